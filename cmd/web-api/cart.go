@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"interview/pkg/cart"
+	"interview/pkg/log"
+)
+
+const cartUsage = `usage: web-api [-config file] cart <command>
+
+commands:
+  reconcile      repair the totals of open carts that don't match their items
+  expire         expire open carts unchanged for longer than cart_lifetime and release their stock`
+
+// runCartCommand handles the "cart reconcile" and "cart expire" commands.
+func runCartCommand(ctx context.Context, repo cart.Repository, inventory cart.Inventory, lifetime time.Duration, args []string, out io.Writer, logger log.Logger) error {
+	if len(args) != 1 {
+		return errors.New(cartUsage)
+	}
+	switch args[0] {
+	case "reconcile":
+		repaired, err := cart.Reconcile(ctx, repo, logger)
+		for _, r := range repaired {
+			fmt.Fprintf(out, "cart %d (session %s): total %s -> %s\n", r.CartID, r.SessionID, r.OldTotal, r.NewTotal)
+		}
+		if err == nil {
+			fmt.Fprintf(out, "%d carts repaired\n", len(repaired))
+		}
+		return err
+	case "expire":
+		expired, err := cart.ExpireCarts(ctx, repo, inventory, time.Now().Add(-lifetime), logger)
+		for _, id := range expired {
+			fmt.Fprintf(out, "cart %d expired\n", id)
+		}
+		if err == nil {
+			fmt.Fprintf(out, "%d carts expired\n", len(expired))
+		}
+		return err
+	}
+	return errors.New(cartUsage)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"interview/pkg/cart"
+	"interview/pkg/entity"
+	"interview/pkg/money"
+	"interview/pkg/promotion"
+)
+
+const couponUsage = `usage: web-api [-config file] coupon <command>
+
+commands:
+  list           list the coupons
+  add [flags]    add a coupon; it takes -code and one of -percent, -amount or -buy and -get:
+    -code SPRING10       the code customers enter
+    -percent 10          take 10 percent off
+    -amount 5.00         take an amount off
+    -buy 2 -get 1        make 1 unit free for every 2 bought
+    -product shoe        limit the coupon to the items of a product
+    -min 50.00           require a cart subtotal of at least this amount
+    -currency USD        the currency of -amount and -min
+    -from 2024-03-01     the first day the coupon is valid
+    -until 2024-04-01    the day the coupon stops being valid
+    -max-uses 100        the number of orders the coupon can be used for`
+
+// runCouponCommand handles the "coupon list" and "coupon add" commands.
+func runCouponCommand(ctx context.Context, promotions promotion.Service, products cart.ProductRepository, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(couponUsage)
+	}
+	switch args[0] {
+	case "list":
+		coupons, err := promotions.GetCoupons(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tKIND\tPRODUCT\tMIN SUBTOTAL\tVALID\tUSES\tACTIVE")
+		for _, c := range coupons {
+			minSubtotal := "*"
+			if !c.MinSubtotal.IsZero() {
+				minSubtotal = c.MinSubtotal.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s - %s\t%d/%d\t%t\n", c.Code, c.Kind, c.ProductID, minSubtotal,
+				formatDay(c.ValidFrom), formatDay(c.ValidUntil), c.Uses, c.MaxUses, c.Active)
+		}
+		return w.Flush()
+	case "add":
+		coupon, err := parseCoupon(ctx, products, args[1:])
+		if err != nil {
+			return err
+		}
+		if err := promotions.CreateCoupon(ctx, &coupon); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "added coupon %s\n", coupon.Code)
+		return nil
+	}
+	return errors.New(couponUsage)
+}
+
+// parseCoupon builds a coupon from the flags of "coupon add".
+func parseCoupon(ctx context.Context, products cart.ProductRepository, args []string) (entity.Coupon, error) {
+	flags := flag.NewFlagSet("coupon add", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	code := flags.String("code", "", "")
+	percent := flags.Int("percent", 0, "")
+	amount := flags.String("amount", "", "")
+	buy := flags.Int("buy", 0, "")
+	get := flags.Int("get", 0, "")
+	product := flags.String("product", "", "")
+	minSubtotal := flags.String("min", "", "")
+	currency := flags.String("currency", money.DefaultCurrency, "")
+	from := flags.String("from", "", "")
+	until := flags.String("until", "", "")
+	maxUses := flags.Int("max-uses", 0, "")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return entity.Coupon{}, errors.New(couponUsage)
+	}
+
+	coupon := entity.Coupon{Code: *code, MaxUses: *maxUses, Active: true}
+	switch {
+	case *percent != 0 && *amount == "" && *buy == 0:
+		coupon.Kind = entity.CouponPercentage
+		coupon.Percent = *percent
+	case *amount != "" && *percent == 0 && *buy == 0:
+		coupon.Kind = entity.CouponFixed
+		parsed, err := money.Parse(*amount, *currency)
+		if err != nil {
+			return entity.Coupon{}, err
+		}
+		coupon.Amount = parsed
+	case *buy != 0 && *percent == 0 && *amount == "":
+		coupon.Kind = entity.CouponBuyXGetY
+		coupon.BuyQuantity = *buy
+		coupon.FreeQuantity = *get
+	default:
+		return entity.Coupon{}, errors.New("give one of -percent, -amount or -buy and -get")
+	}
+	if *minSubtotal != "" {
+		parsed, err := money.Parse(*minSubtotal, *currency)
+		if err != nil {
+			return entity.Coupon{}, err
+		}
+		coupon.MinSubtotal = parsed
+	}
+	if *product != "" {
+		productEntities, err := products.QueryProduct(ctx, map[string]interface{}{"name": *product}, "id asc", 1, 0)
+		if err != nil {
+			return entity.Coupon{}, err
+		}
+		if len(productEntities) == 0 {
+			return entity.Coupon{}, fmt.Errorf("unknown product %q", *product)
+		}
+		coupon.ProductID = productEntities[0].ID
+	}
+	var err error
+	if coupon.ValidFrom, err = parseDay(*from); err != nil {
+		return entity.Coupon{}, err
+	}
+	if coupon.ValidUntil, err = parseDay(*until); err != nil {
+		return entity.Coupon{}, err
+	}
+	return coupon, nil
+}
+
+// parseDay parses a date such as 2024-03-01 as the start of that day in UTC. An empty string gives nil.
+func parseDay(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	day, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q, use the form 2024-03-01", s)
+	}
+	return &day, nil
+}
+
+func formatDay(t *time.Time) string {
+	if t == nil {
+		return "*"
+	}
+	return t.UTC().Format(time.DateOnly)
+}
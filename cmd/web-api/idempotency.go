@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"interview/pkg/idempotency"
+)
+
+const idempotencyUsage = `usage: web-api [-config file] idempotency <command>
+
+commands:
+  purge          delete the idempotency keys older than idempotency_window and the responses stored with them`
+
+// runIdempotencyCommand handles the "idempotency purge" command.
+func runIdempotencyCommand(ctx context.Context, idempotencyService idempotency.Service, args []string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "purge" {
+		return errors.New(idempotencyUsage)
+	}
+	purged, err := idempotencyService.Purge(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d idempotency keys purged\n", purged)
+	return nil
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"flag"
-	"fmt"
+	"interview/pkg/cart"
 	"interview/pkg/db"
-	"net/http"
+	"interview/pkg/db/migrations"
+	"interview/pkg/health"
+	"interview/pkg/idempotency"
+	"interview/pkg/inventory"
+	"interview/pkg/promotion"
+	"interview/pkg/ratelimit"
+	"interview/pkg/session"
+	"interview/pkg/shipping"
+	"interview/pkg/tax"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 
 	"interview/internal/config"
 	"interview/internal/router"
+	"interview/internal/tracing"
 	"interview/internal/utils"
 	"interview/pkg/log"
 )
@@ -25,19 +43,70 @@ func main() {
 
 	// create root logger tagged with server version
 	logger := log.New().With(nil, "version", Version)
+	exitCode := 0
+	defer func() {
+		// flush the logger last, after everything else has been closed
+		_ = logger.Sync()
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// migration files can be created without configuration or database
+	if flag.Arg(0) == "migrate" && flag.Arg(1) == "create" {
+		if err := createMigration(flag.Args()[2:], os.Stdout); err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	}
 
 	// load application configurations
 	cfg, err := config.Load(*flagConfig, logger)
 	if err != nil {
 		logger.Errorf("failed to load application configuration: %s", err)
-		os.Exit(-1)
+		exitCode = 1
+		return
+	}
+
+	// replace the bootstrap logger with the configured one, which also keeps secrets out of the logs
+	configuredLogger, logLevel, err := log.NewWithConfig(log.Config{
+		Level:      cfg.LogLevel,
+		Encoding:   cfg.LogEncoding,
+		OutputPath: cfg.LogOutput,
+		Sampling:   cfg.LogSampling,
+		Secrets:    cfg.Secrets(),
+	})
+	if err != nil {
+		logger.Errorf("failed to create the logger: %s", err)
+		exitCode = 1
+		return
+	}
+	_ = logger.Sync()
+	logger = configuredLogger.With(nil, "version", Version)
+
+	// Set up tracing; pending spans are flushed after the database has been closed
+	shutdownTracing, err := tracing.Setup(cfg, Version)
+	if err != nil {
+		logger.Error(err)
+		exitCode = 1
+		return
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error(err)
+		}
+	}()
 
 	// Open the connection to the database
-	dbConnection, err := utils.GetDBConnection(cfg.DSN)
+	dbConnection, err := utils.GetDBConnection(cfg.DBDriver, cfg.DSN)
 	if err != nil {
 		logger.Error(err)
-		os.Exit(-1)
+		exitCode = 1
+		return
 	}
 	defer func() {
 		err := utils.CloseDBConnection(dbConnection)
@@ -45,25 +114,189 @@ func main() {
 			logger.Error(err)
 		}
 	}()
+	if err := db.RegisterTracing(dbConnection); err != nil {
+		logger.Error(err)
+		exitCode = 1
+		return
+	}
 	dbctx := db.New(dbConnection, logger)
 
-	// Migrate the database
-	err = dbctx.MigrateDatabase()
+	migrator := db.NewMigrator(dbctx, migrations.All())
+	switch flag.Arg(0) {
+	case "":
+	case "migrate":
+		if err := runMigrateCommand(context.Background(), migrator, flag.Args()[1:], os.Stdout); err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	case "cart":
+		inventoryService := inventory.NewService(inventory.NewRepository(dbctx, logger), logger)
+		lifetime := time.Duration(cfg.CartLifetime) * time.Second
+		err := runCartCommand(context.Background(), cart.NewRepository(dbctx, logger), inventoryService, lifetime, flag.Args()[1:], os.Stdout, logger)
+		if err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	case "coupon":
+		promotionService := promotion.NewService(promotion.NewRepository(dbctx, logger), logger)
+		err := runCouponCommand(context.Background(), promotionService, cart.NewProductRepository(dbctx, logger), flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	case "tax":
+		taxSettings := tax.Settings{Region: cfg.TaxRegion, Inclusive: cfg.TaxInclusivePrices, Rounding: tax.Rounding(cfg.TaxRounding)}
+		taxService := tax.NewService(tax.NewRepository(dbctx, logger), taxSettings, logger)
+		err := runTaxCommand(context.Background(), taxService, cart.NewProductRepository(dbctx, logger), flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	case "shipping":
+		shippingService := shipping.NewService(shipping.NewRepository(dbctx, logger), logger)
+		err := runShippingCommand(context.Background(), shippingService, cart.NewProductRepository(dbctx, logger), flag.Args()[1:], os.Stdout)
+		if err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	case "idempotency":
+		window := time.Duration(cfg.IdempotencyWindow) * time.Second
+		idempotencyService := idempotency.NewService(idempotency.NewRepository(dbctx, logger), window, logger)
+		if err := runIdempotencyCommand(context.Background(), idempotencyService, flag.Args()[1:], os.Stdout); err != nil {
+			logger.Error(err)
+			exitCode = 1
+			return
+		}
+		return
+	default:
+		logger.Errorf("unknown command %q\n%s\n\n%s\n\n%s\n\n%s\n\n%s\n\n%s", flag.Arg(0), migrateUsage, cartUsage,
+			couponUsage, taxUsage, shippingUsage, idempotencyUsage)
+		exitCode = 1
+		return
+	}
+
+	// Check that the database schema is up to date
+	err = prepareSchema(context.Background(), cfg, migrator, logger)
 	if err != nil {
 		logger.Error(err)
-		os.Exit(-1)
+		exitCode = 1
+		return
+	}
+
+	// Create the session store
+	sessionStore, closeSessionStore := newSessionStore(cfg)
+	defer func() {
+		err := closeSessionStore()
+		if err != nil {
+			logger.Error(err)
+		}
+	}()
+
+	// Without a configured secret the session cookies are signed with a key that only this process knows
+	if cfg.SessionSecret == "" {
+		cfg.SessionSecret = newSessionSecret()
+		logger.Warn("session_secret is not set, so sessions are signed with a random key and do not survive a restart")
 	}
 
-	ginEngine := gin.Default()
+	// Create the rate limiter
+	limiter, closeLimiter := newRateLimiter(cfg)
+	defer func() {
+		err := closeLimiter()
+		if err != nil {
+			logger.Error(err)
+		}
+	}()
+
+	ginEngine := gin.New()
+	if err := ginEngine.SetTrustedProxies(trustedProxies(cfg)); err != nil {
+		logger.Error(err)
+		exitCode = 1
+		return
+	}
+	readiness := &health.Readiness{}
 	routes := router.New(ginEngine)
-	routes.RegisterHandlers(logger, dbctx)
+	routes.RegisterHandlers(cfg, logger, logLevel, dbctx, sessionStore, limiter, readiness, newHealthChecks(cfg, dbctx, sessionStore, limiter, migrator))
 
-	address := fmt.Sprintf(":%v", cfg.ServerPort)
-	srv := &http.Server{
-		Addr:    address,
-		Handler: ginEngine,
+	// Serve until SIGINT or SIGTERM, then drain in-flight requests before the
+	// deferred functions close the session store and the database in that order
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := newServer(cfg, ginEngine)
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Error(err)
+		exitCode = 1
+		return
+	}
+	if err := serve(ctx, srv, listener, readiness, cfg, logger); err != nil {
+		logger.Error(err)
+		exitCode = 1
+	}
+}
+
+// newSessionSecret returns a random key for signing session cookies.
+func newSessionSecret() string {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(secret)
+}
+
+// newSessionStore creates the session store selected in the configuration and a function releasing its resources.
+func newSessionStore(cfg *config.Config) (session.Store, func() error) {
+	if cfg.SessionStore != "redis" {
+		return session.NewMemoryStore(), func() error { return nil }
 	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	return session.NewRedisStore(client), client.Close
+}
+
+// newRateLimiter creates the rate limiter selected in the configuration and a function releasing its resources.
+func newRateLimiter(cfg *config.Config) (ratelimit.Limiter, func() error) {
+	if cfg.RateLimitStore != "redis" {
+		return ratelimit.NewMemoryLimiter(), func() error { return nil }
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	return ratelimit.NewRedisLimiter(client), client.Close
+}
+
+// trustedProxies returns the proxies listed in the configuration, or nil if there are none.
+func trustedProxies(cfg *config.Config) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(cfg.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
 
-	logger.Infof("server %v is running at %v", Version, address)
-	srv.ListenAndServe()
+// newHealthChecks returns the readiness checks of the dependencies created in main.
+func newHealthChecks(cfg *config.Config, dbctx *db.DB, sessionStore session.Store, limiter ratelimit.Limiter, migrator *db.Migrator) *health.Registry {
+	timeout := time.Duration(cfg.HealthCheckTimeout) * time.Second
+	checks := health.NewRegistry()
+	checks.Register("database", timeout, dbctx.Ping)
+	checks.Register("session_store", timeout, sessionStore.Ping)
+	checks.Register("rate_limiter", timeout, limiter.Ping)
+	checks.Register("migrations", timeout, migrator.Check)
+	return checks
 }
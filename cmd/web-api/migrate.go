@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"interview/internal/config"
+	"interview/internal/utils"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/log"
+)
+
+const migrateUsage = `usage: web-api [-config file] migrate <command>
+
+commands:
+  up [n]         apply all pending migrations, or the next n
+  down [n]       revert the last applied migration, or the last n
+  status         list migrations and whether they have been applied
+  create <name>  create a new migration file in pkg/db/migrations`
+
+// createMigration handles "migrate create <name>", which needs neither configuration nor database.
+func createMigration(args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	dir := filepath.Join(utils.GetRootDir(), "pkg", "db", "migrations")
+	path, err := migrations.Create(dir, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "created %s\n", path)
+	return nil
+}
+
+// runMigrateCommand handles the "migrate up|down|status" commands.
+func runMigrateCommand(ctx context.Context, migrator *db.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations %q", args[1])
+		}
+		steps = n
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx, steps)
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied %04d %s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "database is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Fprintf(out, "reverted %04d %s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+	return errors.New(migrateUsage)
+}
+
+// prepareSchema applies pending migrations if configured to, and refuses to continue with pending
+// migrations if the configuration requires an up-to-date schema.
+func prepareSchema(ctx context.Context, cfg *config.Config, migrator *db.Migrator, logger log.Logger) error {
+	if cfg.AutoMigrate {
+		_, err := migrator.Up(ctx, 0)
+		return err
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if cfg.RequireMigrated {
+		return fmt.Errorf("the database schema has %d pending migrations; run \"web-api migrate up\" first", len(pending))
+	}
+	logger.Infof("the database schema has %d pending migrations", len(pending))
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"interview/internal/config"
+	"interview/pkg/health"
+	"interview/pkg/log"
+)
+
+// newServer returns an HTTP server for the handler with the timeouts from the configuration.
+func newServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%v", cfg.ServerPort),
+		Handler:      handler,
+		ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(cfg.IdleTimeout) * time.Second,
+	}
+}
+
+// serve runs the server on the listener until ctx is done and then shuts it down gracefully: the server reports not ready,
+// keeps serving for the shutdown delay so that load balancers stop sending traffic, stops accepting connections
+// and gives in-flight requests the shutdown timeout to complete. Requests still running after the timeout are cut off.
+func serve(ctx context.Context, srv *http.Server, listener net.Listener, readiness *health.Readiness, cfg *config.Config, logger log.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+	readiness.SetReady(true)
+	logger.Infof("server %v is running at %v", Version, listener.Addr())
+
+	select {
+	case err := <-serveErr:
+		readiness.SetReady(false)
+		return err
+	case <-ctx.Done():
+	}
+
+	readiness.SetReady(false)
+	if delay := time.Duration(cfg.ShutdownDelay) * time.Second; delay > 0 {
+		logger.Infof("server is not ready, shutting down in %v", delay)
+		time.Sleep(delay)
+	}
+	logger.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		_ = srv.Close()
+		return fmt.Errorf("requests did not complete before the shutdown timeout: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("server stopped")
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"interview/internal/config"
+	"interview/pkg/health"
+	"interview/pkg/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	logger, _ := log.NewForTest()
+	cfg := &config.Config{ServerPort: 0, ShutdownTimeout: 5}
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := newServer(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	readiness := &health.Readiness{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, srv, listener, readiness, cfg, logger)
+	}()
+	require.Eventually(t, readiness.Ready, time.Second, 10*time.Millisecond)
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+		assert.Nil(t, err)
+		response <- res
+	}()
+	<-started
+
+	cancel()
+	assert.Eventually(t, func() bool { return !readiness.Ready() }, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("server stopped before the in-flight request completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-response
+	require.NotNil(t, res)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Nil(t, <-served)
+}
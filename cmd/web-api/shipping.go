@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"interview/pkg/cart"
+	"interview/pkg/entity"
+	"interview/pkg/money"
+	"interview/pkg/shipping"
+)
+
+const shippingUsage = `usage: web-api [-config file] shipping <command>
+
+commands:
+  list                                list the shipping zones and rates and the weights and sizes of the products
+  zone <name> <countries>             add a zone of comma separated country codes, or * for all other countries,
+                                      e.g. "shipping zone eu DE,FR,IT"
+  rate [flags]                        add a rate to a zone; it is flat unless -max-weight or -free-over is given:
+    -zone eu                          the zone of the rate
+    -method parcel                    the method customers choose; weight tiers share it
+    -name "Parcel post"               the name shown to customers
+    -price 4.90                       the price of shipping
+    -max-weight 2000                  make the rate a tier for parcels up to this weight in grams
+    -free-over 50.00                  waive the price for carts of at least this amount
+    -currency USD                     the currency of -price and -free-over
+  product <product> [flags]           set the shipping weight and size of a product:
+    -weight 800                       the weight in grams
+    -size 300x200x120                 the length, width and height in millimetres`
+
+// runShippingCommand handles the "shipping list", "shipping zone", "shipping rate" and "shipping product" commands.
+func runShippingCommand(ctx context.Context, shippingService shipping.Service, products cart.ProductRepository, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(shippingUsage)
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		zones, err := shippingService.GetZones(ctx)
+		if err != nil {
+			return err
+		}
+		rates, err := shippingService.GetRates(ctx)
+		if err != nil {
+			return err
+		}
+		zoneNames := map[uint]string{}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ZONE\tCOUNTRIES")
+		for _, z := range zones {
+			zoneNames[z.ID] = z.Name
+			fmt.Fprintf(w, "%s\t%s\n", z.Name, z.Countries)
+		}
+		fmt.Fprintln(w, "\nZONE\tMETHOD\tNAME\tKIND\tPRICE\tMAX WEIGHT\tFREE OVER")
+		for _, r := range rates {
+			maxWeight, freeOver := "*", "*"
+			if r.Kind == entity.ShippingWeight {
+				maxWeight = fmt.Sprintf("%dg", r.MaxWeight)
+			}
+			if r.Kind == entity.ShippingFreeOver {
+				freeOver = r.Threshold.String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", zoneNames[r.ZoneID], r.Method, r.Name, r.Kind, r.Price,
+				maxWeight, freeOver)
+		}
+		fmt.Fprintln(w, "\nPRODUCT\tWEIGHT\tSIZE")
+		productEntities, err := products.QueryProduct(ctx, map[string]interface{}{}, "name asc", -1, -1)
+		if err != nil {
+			return err
+		}
+		for _, p := range productEntities {
+			fmt.Fprintf(w, "%s\t%dg\t%dx%dx%dmm\n", p.Name, p.Weight, p.Length, p.Width, p.Height)
+		}
+		return w.Flush()
+	case args[0] == "zone" && len(args) == 3:
+		zone := entity.ShippingZone{Name: args[1], Countries: args[2]}
+		if err := shippingService.CreateZone(ctx, &zone); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "added zone %s for %s\n", zone.Name, zone.Countries)
+		return nil
+	case args[0] == "rate":
+		rate, err := parseShippingRate(ctx, shippingService, args[1:])
+		if err != nil {
+			return err
+		}
+		if err := shippingService.CreateRate(ctx, &rate); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "added %s rate %s\n", rate.Kind, rate.Method)
+		return nil
+	case args[0] == "product" && len(args) >= 2:
+		return setProductSize(ctx, products, args[1], args[2:], out)
+	}
+	return errors.New(shippingUsage)
+}
+
+// parseShippingRate builds a rate from the flags of "shipping rate".
+func parseShippingRate(ctx context.Context, shippingService shipping.Service, args []string) (entity.ShippingRate, error) {
+	flags := flag.NewFlagSet("shipping rate", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	zoneName := flags.String("zone", "", "")
+	method := flags.String("method", "", "")
+	name := flags.String("name", "", "")
+	price := flags.String("price", "", "")
+	maxWeight := flags.Int("max-weight", 0, "")
+	freeOver := flags.String("free-over", "", "")
+	currency := flags.String("currency", money.DefaultCurrency, "")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || *price == "" {
+		return entity.ShippingRate{}, errors.New(shippingUsage)
+	}
+
+	rate := entity.ShippingRate{Method: *method, Name: *name, Kind: entity.ShippingFlat}
+	parsed, err := money.Parse(*price, *currency)
+	if err != nil {
+		return entity.ShippingRate{}, err
+	}
+	rate.Price = parsed
+	switch {
+	case *maxWeight != 0 && *freeOver != "":
+		return entity.ShippingRate{}, errors.New("give at most one of -max-weight and -free-over")
+	case *maxWeight != 0:
+		rate.Kind = entity.ShippingWeight
+		rate.MaxWeight = *maxWeight
+	case *freeOver != "":
+		rate.Kind = entity.ShippingFreeOver
+		if rate.Threshold, err = money.Parse(*freeOver, *currency); err != nil {
+			return entity.ShippingRate{}, err
+		}
+	}
+	zones, err := shippingService.GetZones(ctx)
+	if err != nil {
+		return entity.ShippingRate{}, err
+	}
+	for _, zone := range zones {
+		if zone.Name == *zoneName {
+			rate.ZoneID = zone.ID
+			return rate, nil
+		}
+	}
+	return entity.ShippingRate{}, fmt.Errorf("unknown zone %q", *zoneName)
+}
+
+// setProductSize sets the weight and size of a product from the flags of "shipping product".
+func setProductSize(ctx context.Context, products cart.ProductRepository, name string, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("shipping product", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	weight := flags.Int("weight", -1, "")
+	size := flags.String("size", "", "")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errors.New(shippingUsage)
+	}
+	productEntities, err := products.QueryProduct(ctx, map[string]interface{}{"name": name}, "id asc", 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(productEntities) == 0 {
+		return fmt.Errorf("unknown product %q", name)
+	}
+	product := productEntities[0]
+	if *weight >= 0 {
+		product.Weight = *weight
+	}
+	if *size != "" {
+		var length, width, height int
+		n, err := fmt.Sscanf(*size, "%dx%dx%d", &length, &width, &height)
+		if err != nil || n != 3 || length < 0 || width < 0 || height < 0 {
+			return fmt.Errorf("invalid size %q, use the form 300x200x120", *size)
+		}
+		product.Length, product.Width, product.Height = length, width, height
+	}
+	if err := products.UpdateProduct(ctx, &product); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s weighs %dg and measures %dx%dx%dmm\n", product.Name, product.Weight, product.Length,
+		product.Width, product.Height)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"interview/pkg/cart"
+	"interview/pkg/entity"
+	"interview/pkg/tax"
+)
+
+const taxUsage = `usage: web-api [-config file] tax <command>
+
+commands:
+  list                                     list the tax rates and the tax categories of the products
+  set <region> <category> <rate> [name]    set the rate in percent of a tax category in a region,
+                                           e.g. "tax set de standard 19 VAT"
+  category <product> <category>            put a product in a tax category`
+
+// runTaxCommand handles the "tax list", "tax set" and "tax category" commands.
+func runTaxCommand(ctx context.Context, taxes tax.Service, products cart.ProductRepository, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(taxUsage)
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		rates, err := taxes.GetRates(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REGION\tCATEGORY\tRATE\tNAME")
+		for _, r := range rates {
+			fmt.Fprintf(w, "%s\t%s\t%s%%\t%s\n", r.Region, r.Category, tax.FormatRate(r.Rate), r.Name)
+		}
+		fmt.Fprintln(w, "\nPRODUCT\tCATEGORY")
+		productEntities, err := products.QueryProduct(ctx, map[string]interface{}{}, "name asc", -1, -1)
+		if err != nil {
+			return err
+		}
+		for _, p := range productEntities {
+			fmt.Fprintf(w, "%s\t%s\n", p.Name, p.TaxCategory)
+		}
+		return w.Flush()
+	case args[0] == "set" && len(args) >= 4:
+		rate, err := tax.ParseRate(args[3])
+		if err != nil {
+			return err
+		}
+		taxRate := entity.TaxRate{Region: args[1], Category: args[2], Rate: rate, Name: strings.Join(args[4:], " ")}
+		if err := taxes.SetRate(ctx, &taxRate); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "set the %s rate of %s to %s%%\n", taxRate.Category, taxRate.Region, tax.FormatRate(taxRate.Rate))
+		return nil
+	case args[0] == "category" && len(args) == 3:
+		productEntities, err := products.QueryProduct(ctx, map[string]interface{}{"name": args[1]}, "id asc", 1, 0)
+		if err != nil {
+			return err
+		}
+		if len(productEntities) == 0 {
+			return fmt.Errorf("unknown product %q", args[1])
+		}
+		product := productEntities[0]
+		product.TaxCategory = tax.NormalizeName(args[2])
+		if product.TaxCategory == "" {
+			product.TaxCategory = entity.DefaultTaxCategory
+		}
+		if err := products.UpdateProduct(ctx, &product); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "put %s in tax category %s\n", product.Name, product.TaxCategory)
+		return nil
+	}
+	return errors.New(taxUsage)
+}
@@ -4,6 +4,9 @@ import (
 	"interview/internal/utils"
 	"interview/pkg/log"
 	"path"
+	"reflect"
+	"regexp"
+	"strings"
 
 	"os"
 
@@ -13,29 +16,244 @@ import (
 )
 
 const (
-	defaultServerPort = 8088
+	defaultServerPort      = 8088
+	defaultDBDriver        = "mysql"
+	defaultSessionStore    = "memory"
+	defaultSessionLifetime = 3600
+	defaultSessionCookie   = "ice_session_id"
+	defaultSessionSameSite = "lax"
+	defaultRateLimitStore  = "memory"
+	defaultCartLifetime    = 86400
+	defaultIdempotencyTTL  = 86400
+	defaultTaxRounding     = "line"
+	defaultReadTimeout     = 15
+	defaultWriteTimeout    = 15
+	defaultIdleTimeout     = 60
+	defaultShutdownTimeout = 30
+	defaultHealthTimeout   = 2
+	defaultTraceExporter   = "none"
+	defaultTraceRatio      = 1.0
+	defaultLogLevel        = "info"
+	defaultLogEncoding     = "json"
+	defaultLogOutput       = "stderr"
 )
 
+// defaultRateLimits keep clients from filling carts with items and from guessing passwords.
+var defaultRateLimits = []RateLimit{
+	{Route: "POST /cart/add", Key: "session", Burst: 20, PerMinute: 30},
+	{Route: "POST /cart/add", Key: "ip", Burst: 60, PerMinute: 120},
+	{Route: "POST /api/v1/cart/items", Key: "session", Burst: 20, PerMinute: 30},
+	{Route: "POST /api/v1/cart/items", Key: "ip", Burst: 60, PerMinute: 120},
+	{Route: "POST /account/login", Key: "ip", Burst: 10, PerMinute: 5},
+	{Route: "POST /api/v1/account/login", Key: "ip", Burst: 10, PerMinute: 5},
+}
+
 // Config represents an application configuration.
 type Config struct {
 	// the server port. Defaults to 8080
 	ServerPort int `yaml:"server_port" env:"SERVER_PORT"`
+	// the number of seconds allowed for reading a request. Zero means no limit. Defaults to 15
+	ReadTimeout int `yaml:"read_timeout" env:"READ_TIMEOUT"`
+	// the number of seconds allowed for writing a response. Zero means no limit. Defaults to 15
+	WriteTimeout int `yaml:"write_timeout" env:"WRITE_TIMEOUT"`
+	// the number of seconds an idle keep-alive connection is kept open. Defaults to 60
+	IdleTimeout int `yaml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	// the number of seconds in-flight requests are given to complete when the server shuts down. Defaults to 30
+	ShutdownTimeout int `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// the number of seconds the server keeps serving while reporting not ready before it shuts down,
+	// so that load balancers can stop sending traffic. Defaults to 0
+	ShutdownDelay int `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	// the number of seconds each dependency check of the readiness probe may take. Defaults to 2
+	HealthCheckTimeout int `yaml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	// where trace spans are exported: "none", "stdout" or "file". Defaults to none
+	TraceExporter string `yaml:"trace_exporter" env:"TRACE_EXPORTER"`
+	// the file spans are appended to as JSON. required when the trace exporter is file
+	TraceFile string `yaml:"trace_file" env:"TRACE_FILE"`
+	// the fraction of traces started by this server that are recorded, between 0 and 1. Defaults to 1
+	TraceSampleRatio float64 `yaml:"trace_sample_ratio" env:"TRACE_SAMPLE_RATIO"`
+	// the lowest level that is logged: "debug", "info", "warn" or "error". Defaults to info
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"`
+	// the format of log entries: "json" or "console". Defaults to json
+	LogEncoding string `yaml:"log_encoding" env:"LOG_ENCODING"`
+	// "stdout", "stderr" or the file log entries are appended to. Defaults to stderr
+	LogOutput string `yaml:"log_output" env:"LOG_OUTPUT"`
+	// whether repeated log messages are sampled after the first 100 per second. Defaults to true
+	LogSampling bool `yaml:"log_sampling" env:"LOG_SAMPLING"`
+	// whether the log level can be read and changed at runtime through /log/level
+	LogLevelEndpoint bool `yaml:"log_level_endpoint" env:"LOG_LEVEL_ENDPOINT"`
+	// the token, of at least 32 characters, operators send as "Authorization: Bearer <token>" to reach /log/level.
+	// required when the log level endpoint is enabled
+	AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN,secret"`
+	// the database driver: "mysql", "postgres" or "sqlite". Defaults to mysql
+	DBDriver string `yaml:"db_driver" env:"DB_DRIVER"`
 	// the data source name (DSN) for connecting to the database. required.
 	DSN string `yaml:"dsn" env:"DSN,secret"`
+	// whether pending database migrations are applied when the server starts
+	AutoMigrate bool `yaml:"auto_migrate" env:"AUTO_MIGRATE"`
+	// whether the server refuses to start while database migrations are pending
+	RequireMigrated bool `yaml:"require_migrated" env:"REQUIRE_MIGRATED"`
+	// where sessions are stored: "memory" or "redis". Defaults to memory
+	SessionStore string `yaml:"session_store" env:"SESSION_STORE"`
+	// the number of seconds a session and its cookie are kept after its last request. Defaults to 3600
+	SessionLifetime int `yaml:"session_lifetime" env:"SESSION_LIFETIME"`
+	// the name of the session cookie. Defaults to ice_session_id
+	SessionCookieName string `yaml:"session_cookie_name" env:"SESSION_COOKIE_NAME"`
+	// the domain of the session cookie. Defaults to none, which limits the cookie to the host of the request
+	SessionCookieDomain string `yaml:"session_cookie_domain" env:"SESSION_COOKIE_DOMAIN"`
+	// whether the session cookie is only sent over HTTPS
+	SessionCookieSecure bool `yaml:"session_cookie_secure" env:"SESSION_COOKIE_SECURE"`
+	// the SameSite mode of the session cookie: "lax", "strict" or "none", which requires a secure cookie. Defaults to lax
+	SessionCookieSameSite string `yaml:"session_cookie_same_site" env:"SESSION_COOKIE_SAME_SITE"`
+	// the key, of at least 32 characters, the session IDs in cookies are signed with. required when the session
+	// store is redis; without it a random key is used, so sessions do not survive a restart
+	SessionSecret string `yaml:"session_secret" env:"SESSION_SECRET,secret"`
+	// the number of seconds an open cart is kept after its last change before "cart expire" expires it
+	// and releases its stock. Defaults to 86400
+	CartLifetime int `yaml:"cart_lifetime" env:"CART_LIFETIME"`
+	// the number of seconds the response to a request with an idempotency key is sent again to requests with the same
+	// key. Defaults to 86400
+	IdempotencyWindow int `yaml:"idempotency_window" env:"IDEMPOTENCY_WINDOW"`
+	// the tax region of carts for which the customer has not chosen one
+	TaxRegion string `yaml:"tax_region" env:"TAX_REGION"`
+	// whether product prices include tax
+	TaxInclusivePrices bool `yaml:"tax_inclusive_prices" env:"TAX_INCLUSIVE_PRICES"`
+	// where tax is rounded: "line" rounds the tax of every cart item, "total" the tax of every tax line. Defaults to line
+	TaxRounding string `yaml:"tax_rounding" env:"TAX_ROUNDING"`
+	// where the rate limits are tracked: "memory" or "redis". Defaults to memory
+	RateLimitStore string `yaml:"rate_limit_store" env:"RATE_LIMIT_STORE"`
+	// the limits of the requests of every client to routes. Defaults to limits on adding items and logging in
+	RateLimits []RateLimit `yaml:"rate_limits" env:"-"`
+	// comma separated addresses or CIDR ranges of the proxies whose X-Forwarded-For header tells the IP address of
+	// the client. Defaults to none, so that clients cannot choose their address
+	TrustedProxies string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// the address of the redis server. required when the session store or the rate limit store is redis
+	RedisAddr string `yaml:"redis_addr" env:"REDIS_ADDR"`
+	// the password of the redis server
+	RedisPassword string `yaml:"redis_password" env:"REDIS_PASSWORD,secret"`
+	// the redis database number
+	RedisDB int `yaml:"redis_db" env:"REDIS_DB"`
 }
 
+// cookieNameRegex matches the names a cookie can have without quoting.
+var cookieNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // Validate validates the application configuration.
 func (c Config) Validate() error {
+	var redisAddrRules []validation.Rule
+	if c.SessionStore == "redis" || c.RateLimitStore == "redis" {
+		redisAddrRules = append(redisAddrRules, validation.Required)
+	}
+	var sessionSecretRules []validation.Rule
+	if c.SessionStore == "redis" {
+		sessionSecretRules = append(sessionSecretRules, validation.Required)
+	}
+	var sessionCookieSecureRules []validation.Rule
+	if c.SessionCookieSameSite == "none" {
+		sessionCookieSecureRules = append(sessionCookieSecureRules, validation.Required.Error("must be true when the SameSite mode is none"))
+	}
+	var adminTokenRules []validation.Rule
+	if c.LogLevelEndpoint {
+		adminTokenRules = append(adminTokenRules, validation.Required)
+	}
+	var traceFileRules []validation.Rule
+	if c.TraceExporter == "file" {
+		traceFileRules = append(traceFileRules, validation.Required)
+	}
 	return validation.ValidateStruct(&c,
+		validation.Field(&c.ReadTimeout, validation.Min(0)),
+		validation.Field(&c.WriteTimeout, validation.Min(0)),
+		validation.Field(&c.IdleTimeout, validation.Min(0)),
+		validation.Field(&c.ShutdownTimeout, validation.Min(1)),
+		validation.Field(&c.ShutdownDelay, validation.Min(0)),
+		validation.Field(&c.HealthCheckTimeout, validation.Min(1)),
+		validation.Field(&c.LogLevel, validation.In("debug", "info", "warn", "error")),
+		validation.Field(&c.LogEncoding, validation.In("json", "console")),
+		validation.Field(&c.LogOutput, validation.Required),
+		validation.Field(&c.DBDriver, validation.In("mysql", "postgres", "sqlite")),
 		validation.Field(&c.DSN, validation.Required),
+		validation.Field(&c.SessionStore, validation.In("memory", "redis")),
+		validation.Field(&c.SessionLifetime, validation.Min(1)),
+		validation.Field(&c.SessionCookieName, validation.Required, validation.Match(cookieNameRegex)),
+		validation.Field(&c.SessionCookieSameSite, validation.In("lax", "strict", "none")),
+		validation.Field(&c.SessionCookieSecure, sessionCookieSecureRules...),
+		validation.Field(&c.SessionSecret, append(sessionSecretRules, validation.Length(32, 0))...),
+		validation.Field(&c.AdminToken, append(adminTokenRules, validation.Length(32, 0))...),
+		validation.Field(&c.CartLifetime, validation.Min(1)),
+		validation.Field(&c.IdempotencyWindow, validation.Min(1)),
+		validation.Field(&c.TaxRounding, validation.In("line", "total")),
+		validation.Field(&c.RateLimitStore, validation.In("memory", "redis")),
+		validation.Field(&c.RateLimits),
+		validation.Field(&c.RedisAddr, redisAddrRules...),
+		validation.Field(&c.TraceExporter, validation.In("none", "stdout", "file")),
+		validation.Field(&c.TraceFile, traceFileRules...),
+		validation.Field(&c.TraceSampleRatio, validation.Min(0.0), validation.Max(1.0)),
 	)
 }
 
+// RateLimit limits the requests of every client to a route with a token bucket.
+type RateLimit struct {
+	// the method and gin route, e.g. "POST /cart/add"
+	Route string `yaml:"route"`
+	// what identifies a client: "ip" or "session"
+	Key string `yaml:"key"`
+	// the number of requests a client can make at once
+	Burst int `yaml:"burst"`
+	// the number of requests a client can make per minute once the burst is used up
+	PerMinute int `yaml:"per_minute"`
+}
+
+// rateLimitRouteRegex matches a method and a route.
+var rateLimitRouteRegex = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE) /\S*$`)
+
+// Validate validates the rate limit.
+func (r RateLimit) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Route, validation.Required, validation.Match(rateLimitRouteRegex)),
+		validation.Field(&r.Key, validation.Required, validation.In("ip", "session")),
+		validation.Field(&r.Burst, validation.Required, validation.Min(1)),
+		validation.Field(&r.PerMinute, validation.Required, validation.Min(1)),
+	)
+}
+
+// Secrets returns the non-empty values of the fields marked as secret, which must not appear in logs.
+func (c Config) Secrets() []string {
+	var secrets []string
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		tag := v.Type().Field(i).Tag.Get("env")
+		if strings.HasSuffix(tag, ",secret") && v.Field(i).Kind() == reflect.String && v.Field(i).String() != "" {
+			secrets = append(secrets, v.Field(i).String())
+		}
+	}
+	return secrets
+}
+
 // Load returns an application configuration which is populated from the given configuration file and environment variables.
 func Load(file string, logger log.Logger) (*Config, error) {
 	// default config
 	c := Config{
-		ServerPort: defaultServerPort,
+		ServerPort:            defaultServerPort,
+		ReadTimeout:           defaultReadTimeout,
+		WriteTimeout:          defaultWriteTimeout,
+		IdleTimeout:           defaultIdleTimeout,
+		ShutdownTimeout:       defaultShutdownTimeout,
+		HealthCheckTimeout:    defaultHealthTimeout,
+		TraceExporter:         defaultTraceExporter,
+		TraceSampleRatio:      defaultTraceRatio,
+		LogLevel:              defaultLogLevel,
+		LogEncoding:           defaultLogEncoding,
+		LogOutput:             defaultLogOutput,
+		LogSampling:           true,
+		DBDriver:              defaultDBDriver,
+		SessionStore:          defaultSessionStore,
+		SessionLifetime:       defaultSessionLifetime,
+		SessionCookieName:     defaultSessionCookie,
+		SessionCookieSameSite: defaultSessionSameSite,
+		CartLifetime:          defaultCartLifetime,
+		IdempotencyWindow:     defaultIdempotencyTTL,
+		RateLimitStore:        defaultRateLimitStore,
+		RateLimits:            append([]RateLimit(nil), defaultRateLimits...),
+		TaxRounding:           defaultTaxRounding,
 	}
 
 	// load from YAML config file
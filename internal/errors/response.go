@@ -0,0 +1,128 @@
+// Package errors provides the error envelope returned by the JSON API.
+package errors
+
+import "net/http"
+
+// ErrorResponse is the structured error returned to API clients.
+type ErrorResponse struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// envelope wraps an ErrorResponse so every API error has the shape {"error": {...}}.
+type envelope struct {
+	Error ErrorResponse `json:"error"`
+}
+
+// Error implements the error interface.
+func (e ErrorResponse) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status code of the error.
+func (e ErrorResponse) StatusCode() int {
+	return e.Status
+}
+
+// Envelope returns the JSON body to send for the error.
+func (e ErrorResponse) Envelope() interface{} {
+	return envelope{e}
+}
+
+// BadRequest creates a new error response representing a bad request (HTTP 400).
+func BadRequest(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "Your request is in a bad format."
+	}
+	return ErrorResponse{
+		Status:  http.StatusBadRequest,
+		Code:    "bad_request",
+		Message: msg,
+	}
+}
+
+// Unauthorized creates a new error response representing an authentication failure (HTTP 401).
+func Unauthorized(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "You are not authenticated to perform the requested action."
+	}
+	return ErrorResponse{
+		Status:  http.StatusUnauthorized,
+		Code:    "unauthorized",
+		Message: msg,
+	}
+}
+
+// Forbidden creates a new error response representing a request that is not allowed (HTTP 403).
+func Forbidden(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "You are not allowed to perform the requested action."
+	}
+	return ErrorResponse{
+		Status:  http.StatusForbidden,
+		Code:    "forbidden",
+		Message: msg,
+	}
+}
+
+// NotFound creates a new error response representing a resource-not-found error (HTTP 404).
+func NotFound(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The requested resource was not found."
+	}
+	return ErrorResponse{
+		Status:  http.StatusNotFound,
+		Code:    "not_found",
+		Message: msg,
+	}
+}
+
+// Conflict creates a new error response representing a conflict with the resource's current state (HTTP 409).
+func Conflict(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The request conflicts with the current state of the resource."
+	}
+	return ErrorResponse{
+		Status:  http.StatusConflict,
+		Code:    "conflict",
+		Message: msg,
+	}
+}
+
+// UnprocessableEntity creates a new error response representing a request that is well-formed but cannot be
+// processed (HTTP 422).
+func UnprocessableEntity(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The request cannot be processed."
+	}
+	return ErrorResponse{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    "unprocessable_entity",
+		Message: msg,
+	}
+}
+
+// TooManyRequests creates a new error response representing a client that made too many requests (HTTP 429).
+func TooManyRequests(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "You have made too many requests, please try again later."
+	}
+	return ErrorResponse{
+		Status:  http.StatusTooManyRequests,
+		Code:    "too_many_requests",
+		Message: msg,
+	}
+}
+
+// InternalServerError creates a new error response representing an internal server error (HTTP 500).
+func InternalServerError(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "We encountered an error while processing your request."
+	}
+	return ErrorResponse{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal_error",
+		Message: msg,
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"interview/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminTokenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	const token = "0123456789abcdef0123456789abcdef"
+	engine := gin.New()
+	engine.PUT("/log/level", AdminTokenMiddleware(token, logger), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/log/level", strings.NewReader(`{"level":"debug"}`))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		engine.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, "Bearer", res.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, res.Body.String(), `"unauthorized"`)
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(token).Code)
+	assert.Equal(t, http.StatusOK, serve("Bearer "+token).Code)
+
+	// without a token every request is rejected
+	engine = gin.New()
+	engine.PUT("/log/level", AdminTokenMiddleware("", logger), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer ").Code)
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/log"
+	"interview/pkg/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRFErrorMessage tells why a state-changing request was rejected by CSRFMiddleware.
+const CSRFErrorMessage = "the CSRF token is missing or wrong, reload the page and try again"
+
+// CSRFMiddleware rejects state-changing requests, i.e. those with a method other than GET, HEAD or OPTIONS, which
+// do not carry the CSRF token of their session in the session.CSRFField form field or the session.CSRFHeader
+// header. Pages put the token into their forms, and API clients read it from the session.CSRFHeader header, which
+// is set on every response. Another site can make the browser of a user send requests with the session cookie,
+// but it cannot read the token, so it cannot change the cart of the user.
+//
+// It must run after SessionMiddleware. Rejected API requests, under /api/, get a JSON error and others plain text.
+func CSRFMiddleware(logger log.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess := session.FromContext(c.Request.Context())
+		token := sess.CSRFToken()
+		// the header is set just before the response status is written, so that it carries the new token of a
+		// renewed session
+		setHeader := func() {
+			c.Header(session.CSRFHeader, sess.CSRFToken())
+		}
+		writer := &beforeWriteWriter{ResponseWriter: c.Writer, before: setHeader}
+		c.Writer = writer
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			if !writer.called && !c.Writer.Written() {
+				setHeader()
+			}
+		}()
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		sent := c.GetHeader(session.CSRFHeader)
+		if sent == "" {
+			sent = c.PostForm(session.CSRFField)
+		}
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1 {
+			c.Next()
+			return
+		}
+		logger.With(c.Request.Context()).Warnf("rejected %s %s without a valid CSRF token", c.Request.Method, c.Request.URL.Path)
+		abortWithError(c, apierrors.Forbidden(CSRFErrorMessage))
+	}
+}
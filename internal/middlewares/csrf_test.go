@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	token := stored.CSRFToken()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	engine := gin.New()
+	engine.Use(SessionMiddleware(store, testCookie, logger), CSRFMiddleware(logger))
+	engine.GET("/cart/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	engine.POST("/cart/add", func(c *gin.Context) {
+		c.String(http.StatusOK, c.PostForm("product"))
+	})
+	engine.POST("/api/v1/cart/items", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	serve := func(method, path, contentType, body string, sentToken string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(stored.ID)))
+		if sentToken != "" {
+			req.Header.Set(session.CSRFHeader, sentToken)
+		}
+		engine.ServeHTTP(res, req)
+		return res
+	}
+
+	res := serve("GET", "/cart/", "", "", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, token, res.Header().Get(session.CSRFHeader))
+
+	form := url.Values{"product": {"shoe"}, session.CSRFField: {token}}
+	res = serve("POST", "/cart/add", "application/x-www-form-urlencoded", form.Encode(), "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "shoe", res.Body.String())
+
+	form.Set(session.CSRFField, "wrong")
+	res = serve("POST", "/cart/add", "application/x-www-form-urlencoded", form.Encode(), "")
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.Equal(t, CSRFErrorMessage, res.Body.String())
+	form.Del(session.CSRFField)
+	res = serve("POST", "/cart/add", "application/x-www-form-urlencoded", form.Encode(), "")
+	assert.Equal(t, http.StatusForbidden, res.Code)
+
+	res = serve("POST", "/api/v1/cart/items", "application/json", `{"product":"shoe"}`, token)
+	assert.Equal(t, http.StatusCreated, res.Code)
+	res = serve("POST", "/api/v1/cart/items", "text/plain", `{"product":"shoe"}`, "")
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.JSONEq(t, `{"error":{"status":403,"code":"forbidden","message":"`+CSRFErrorMessage+`"}}`, res.Body.String())
+}
+
+func TestCSRFMiddleware_NewSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	engine := gin.New()
+	engine.Use(SessionMiddleware(store, testCookie, logger), CSRFMiddleware(logger))
+	engine.POST("/cart/add", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// a new session has a token that the request cannot know yet
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cart/add", nil)
+	engine.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	token := res.Header().Get(session.CSRFHeader)
+	assert.NotEmpty(t, token)
+
+	// the token is kept with the session
+	id, _ := testCookie.verify(res.Result().Cookies()[0].Value)
+	sess, err := store.Get(context.Background(), id)
+	assert.Nil(t, err)
+	assert.Equal(t, token, sess.CSRF)
+}
+
+func TestCSRFMiddleware_RenewedSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	token := stored.CSRFToken()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	engine := gin.New()
+	engine.Use(SessionMiddleware(store, testCookie, logger), CSRFMiddleware(logger))
+	engine.POST("/api/v1/login", func(c *gin.Context) {
+		session.FromContext(c.Request.Context()).Renew()
+		c.Status(http.StatusOK)
+	})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/login", nil)
+	req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(stored.ID)))
+	req.Header.Set(session.CSRFHeader, token)
+	engine.ServeHTTP(res, req)
+
+	// the renewed session gets a new token, which is sent with the response
+	assert.Equal(t, http.StatusOK, res.Code)
+	renewed := res.Header().Get(session.CSRFHeader)
+	assert.NotEmpty(t, renewed)
+	assert.NotEqual(t, token, renewed)
+	id, _ := testCookie.verify(res.Result().Cookies()[0].Value)
+	sess, err := store.Get(context.Background(), id)
+	assert.Nil(t, err)
+	assert.Equal(t, renewed, sess.CSRF)
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/idempotency"
+	"interview/pkg/log"
+	"interview/pkg/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotentReplayedHeader is set on responses that were sent before, to a request with the same idempotency key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// recordingWriter keeps a copy of the body written to the response.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes state-changing requests that carry an idempotency key, in the idempotency.KeyHeader
+// header or the idempotency.KeyField form field, take effect once. The response to the first request with a key is
+// stored, and a request that repeats it with the same key gets the same response, with the
+// IdempotentReplayedHeader header, instead of being handled again. A key reused for a request with another method,
+// URL or body is rejected with 422 Unprocessable Entity, and a repeated request that arrives while the first one is
+// still being handled with 409 Conflict. Keys belong to the session that sent them. Responses with a server error
+// status are not stored, so that the requests can be retried with the same key.
+//
+// It must run after SessionMiddleware and before db.DB.TransactionHandler, so that the key is stored outside of the
+// transaction of the request, and so that a transaction that fails to commit is seen as the 500 response it turns
+// into before the response is stored.
+func IdempotencyMiddleware(service idempotency.Service, logger log.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		key := c.GetHeader(idempotency.KeyHeader)
+		if key == "" {
+			key = c.PostForm(idempotency.KeyField)
+		}
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			abortWithError(c, apierrors.BadRequest(fmt.Sprintf("the idempotency key must be at most %d characters long", idempotency.MaxKeyLength)))
+			return
+		}
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			abortWithError(c, apierrors.BadRequest("the request body cannot be read"))
+			return
+		}
+
+		ctx := c.Request.Context()
+		// the session gets a new ID on login and logout, so the scope is taken before the request is handled
+		scope := session.FromContext(ctx).ID
+		earlier, err := service.Begin(ctx, scope, key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.KeyReusedError):
+			abortWithError(c, apierrors.UnprocessableEntity(err.Error()))
+			return
+		case errors.Is(err, idempotency.InProgressError):
+			abortWithError(c, apierrors.Conflict(err.Error()))
+			return
+		case err != nil:
+			abortWithError(c, apierrors.InternalServerError(""))
+			return
+		case earlier != nil:
+			logger.With(ctx).Infof("replayed the response to %s %s with idempotency key %q", c.Request.Method, c.Request.URL.Path, key)
+			replay(c, earlier)
+			return
+		}
+
+		stored := false
+		defer func() {
+			// also runs when the handler panics, so that the key is not left in progress
+			if !stored {
+				_ = service.Abandon(ctx, scope, key)
+			}
+		}()
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		// client errors are recorded in c.Errors as well, and are stored like any other response
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+		res := idempotency.Response{
+			Status:      c.Writer.Status(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Location:    c.Writer.Header().Get("Location"),
+			Body:        w.body.Bytes(),
+		}
+		stored = service.Finish(ctx, scope, key, res) == nil
+	}
+}
+
+// requestFingerprint returns the idempotency.Fingerprint of the request. Forms are fingerprinted by their parsed
+// values, as their body may already have been read by an earlier middleware; other bodies are read and put back.
+func requestFingerprint(c *gin.Context) (string, error) {
+	switch c.ContentType() {
+	case gin.MIMEPOSTForm, gin.MIMEMultipartPOSTForm:
+		c.PostForm(idempotency.KeyField)
+		return idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), []byte(c.Request.PostForm.Encode())), nil
+	}
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(c.Request.Body); err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body), nil
+}
+
+// replay sends the stored response to an earlier request again.
+func replay(c *gin.Context, res *idempotency.Response) {
+	c.Header(IdempotentReplayedHeader, "true")
+	if res.Location != "" {
+		c.Header("Location", res.Location)
+	}
+	if res.ContentType != "" {
+		c.Header("Content-Type", res.ContentType)
+	}
+	c.Abort()
+	c.Status(res.Status)
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(res.Body)
+}
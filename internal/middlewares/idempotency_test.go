@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/idempotency"
+	"interview/pkg/log"
+	"interview/pkg/session"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "idempotency_keys")
+
+	store := session.NewMemoryStore()
+	stored := session.New()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	engine := gin.New()
+	engine.Use(SessionMiddleware(store, testCookie, logger),
+		IdempotencyMiddleware(idempotency.NewService(idempotency.NewRepository(dbc, logger), time.Hour, logger), logger))
+	added, checkouts := 0, 0
+	engine.POST("/api/v1/cart/items", func(c *gin.Context) {
+		added++
+		c.JSON(http.StatusCreated, gin.H{"added": added})
+	})
+	engine.POST("/cart/add", func(c *gin.Context) {
+		added++
+		c.Redirect(http.StatusFound, "/cart/")
+	})
+	engine.POST("/api/v1/cart/checkout", func(c *gin.Context) {
+		checkouts++
+		if checkouts == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"order": checkouts})
+	})
+	rejected := 0
+	engine.POST("/api/v1/cart/coupon", func(c *gin.Context) {
+		rejected++
+		_ = c.Error(apierrors.UnprocessableEntity("the coupon has expired"))
+		c.JSON(http.StatusUnprocessableEntity, apierrors.UnprocessableEntity("the coupon has expired").Envelope())
+	})
+	engine.POST("/api/v1/cart/items/shoe", func(c *gin.Context) {
+		rejected++
+		_ = c.Error(apierrors.Conflict("the cart was changed"))
+		c.JSON(http.StatusConflict, apierrors.Conflict("the cart was changed").Envelope())
+	})
+	orders := 0
+	engine.POST("/api/v1/orders", dbc.TransactionHandler(), func(c *gin.Context) {
+		orders++
+		if orders == 1 {
+			// ends the transaction, so that it fails to commit
+			_ = dbc.With(c.Request.Context()).Rollback()
+		}
+		c.JSON(http.StatusCreated, gin.H{"order": orders})
+	})
+	serve := func(path, contentType, body, key string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(stored.ID)))
+		if key != "" {
+			req.Header.Set(idempotency.KeyHeader, key)
+		}
+		engine.ServeHTTP(res, req)
+		return res
+	}
+
+	// a repeated API request gets the first response
+	res := serve("/api/v1/cart/items", "application/json", `{"product":"shoe"}`, "key-1")
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"added":1}`, res.Body.String())
+	assert.Empty(t, res.Header().Get(IdempotentReplayedHeader))
+	res = serve("/api/v1/cart/items", "application/json", `{"product":"shoe"}`, "key-1")
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"added":1}`, res.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, "true", res.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, added)
+
+	// a key used for another request is rejected
+	res = serve("/api/v1/cart/items", "application/json", `{"product":"purse"}`, "key-1")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Contains(t, res.Body.String(), `"unprocessable_entity"`)
+	assert.Equal(t, 1, added)
+
+	// requests without a key are handled every time
+	serve("/api/v1/cart/items", "application/json", `{"product":"shoe"}`, "")
+	serve("/api/v1/cart/items", "application/json", `{"product":"shoe"}`, "")
+	assert.Equal(t, 3, added)
+
+	// a form submitted twice is handled once
+	form := url.Values{"product": {"shoe"}, idempotency.KeyField: {"form-key"}}
+	res = serve("/cart/add", "application/x-www-form-urlencoded", form.Encode(), "")
+	assert.Equal(t, http.StatusFound, res.Code)
+	res = serve("/cart/add", "application/x-www-form-urlencoded", form.Encode(), "")
+	assert.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "/cart/", res.Header().Get("Location"))
+	assert.Equal(t, "true", res.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 4, added)
+
+	// a request that failed with a server error can be retried with its key
+	res = serve("/api/v1/cart/checkout", "application/json", "", "key-2")
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	res = serve("/api/v1/cart/checkout", "application/json", "", "key-2")
+	assert.Equal(t, http.StatusCreated, res.Code)
+	res = serve("/api/v1/cart/checkout", "application/json", "", "key-2")
+	assert.Equal(t, `{"order":2}`, res.Body.String())
+	assert.Equal(t, 2, checkouts)
+
+	// client errors are stored and replayed
+	res = serve("/api/v1/cart/coupon", "application/json", `{"code":"OLD"}`, "key-3")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	res = serve("/api/v1/cart/coupon", "application/json", `{"code":"OLD"}`, "key-3")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	assert.Contains(t, res.Body.String(), "the coupon has expired")
+	assert.Equal(t, "true", res.Header().Get(IdempotentReplayedHeader))
+	res = serve("/api/v1/cart/items/shoe", "application/json", "", "key-4")
+	assert.Equal(t, http.StatusConflict, res.Code)
+	res = serve("/api/v1/cart/items/shoe", "application/json", "", "key-4")
+	assert.Equal(t, http.StatusConflict, res.Code)
+	assert.Contains(t, res.Body.String(), "the cart was changed")
+	assert.Equal(t, "true", res.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, rejected)
+
+	// a request whose transaction fails to commit is not stored
+	res = serve("/api/v1/orders", "application/json", "", "key-5")
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	res = serve("/api/v1/orders", "application/json", "", "key-5")
+	assert.Equal(t, http.StatusCreated, res.Code)
+	assert.Equal(t, `{"order":2}`, res.Body.String())
+	assert.Empty(t, res.Header().Get(IdempotentReplayedHeader))
+
+	res = serve("/api/v1/cart/items", "application/json", `{"product":"shoe"}`, strings.Repeat("k", idempotency.MaxKeyLength+1))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, 4, added)
+}
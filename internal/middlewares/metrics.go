@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute labels requests that did not match any route, so that arbitrary paths cannot create new series.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware counts the handled requests and observes their latency per method, gin route and status.
+// The metrics are registered with the given registerer.
+func MetricsMiddleware(reg prometheus.Registerer) gin.HandlerFunc {
+	labels := []string{"method", "route", "status"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled.",
+	}, labels)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, labels)
+	reg.MustRegister(requests, duration)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		values := []string{c.Request.Method, route, strconv.Itoa(c.Writer.Status())}
+		requests.WithLabelValues(values...).Inc()
+		duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+	engine := gin.New()
+	engine.Use(MetricsMiddleware(reg))
+	engine.GET("/items/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	count, err := testutil.GatherAndCount(reg, "http_requests_total")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP http_requests_total Number of HTTP requests handled.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/items/:id",status="204"} 2
+http_requests_total{method="GET",route="unmatched",status="404"} 1
+`), "http_requests_total"))
+	count, err = testutil.GatherAndCount(reg, "http_request_duration_seconds")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+}
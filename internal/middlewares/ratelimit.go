@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"math"
+	"strconv"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/log"
+	"interview/pkg/ratelimit"
+	"interview/pkg/session"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RateLimitErrorMessage tells why a request was rejected by RateLimitMiddleware.
+const RateLimitErrorMessage = "too many requests, please try again later"
+
+// Rate limit keys tell what identifies the client a rate limit applies to.
+const (
+	RateLimitByIP      = "ip"
+	RateLimitBySession = "session"
+)
+
+// RateLimitRule limits the requests of every client to a route.
+type RateLimitRule struct {
+	Method string
+	// Route is the gin route, e.g. /cart/items/:id.
+	Route string
+	// Key is RateLimitByIP or RateLimitBySession.
+	Key   string
+	Limit ratelimit.Limit
+}
+
+// RateLimitMiddleware rejects the requests of clients that exceed a rate limit of the route with 429 Too Many
+// Requests and a Retry-After header. A route can have a limit per IP address and a limit per session, which are both
+// enforced. A session limit only holds back clients that keep their session cookie: the requests of new sessions,
+// e.g. of clients that drop the cookie, share the session limit of their IP address instead. Rejected API requests,
+// under /api/, get a JSON error and others plain text. Throttled requests are counted per method, route and key with
+// the given registerer. If the limiter fails, requests are let through.
+//
+// It must run after SessionMiddleware.
+func RateLimitMiddleware(limiter ratelimit.Limiter, rules []RateLimitRule, reg prometheus.Registerer, logger log.Logger) gin.HandlerFunc {
+	throttled := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_throttled_total",
+		Help: "Number of HTTP requests rejected by a rate limit.",
+	}, []string{"method", "route", "key"})
+	reg.MustRegister(throttled)
+	byRoute := map[string][]RateLimitRule{}
+	for _, rule := range rules {
+		byRoute[rule.Method+" "+rule.Route] = append(byRoute[rule.Method+" "+rule.Route], rule)
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		for _, rule := range byRoute[c.Request.Method+" "+c.FullPath()] {
+			client := c.ClientIP()
+			if rule.Key == RateLimitBySession && !session.IsNew(ctx) {
+				client = session.FromContext(ctx).ID
+			}
+			result, err := limiter.Allow(ctx, rule.Key+":"+rule.Method+" "+rule.Route+":"+client, rule.Limit)
+			if err != nil {
+				logger.With(ctx).Errorf("error checking rate limit: %v", err)
+				continue
+			}
+			if result.Allowed {
+				continue
+			}
+			throttled.WithLabelValues(rule.Method, rule.Route, rule.Key).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			abortWithError(c, apierrors.TooManyRequests(RateLimitErrorMessage))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,148 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"interview/pkg/log"
+	"interview/pkg/ratelimit"
+	"interview/pkg/session"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("limiter down")
+}
+
+func (failingLimiter) Ping(ctx context.Context) error {
+	return errors.New("limiter down")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	first, second := session.New(), session.New()
+	_ = store.Save(context.Background(), first, time.Hour)
+	_ = store.Save(context.Background(), second, time.Hour)
+	reg := prometheus.NewRegistry()
+	rules := []RateLimitRule{
+		{Method: "POST", Route: "/api/v1/cart/items", Key: RateLimitBySession, Limit: ratelimit.Limit{Burst: 2, PerMinute: 1}},
+		{Method: "POST", Route: "/api/v1/cart/items", Key: RateLimitByIP, Limit: ratelimit.Limit{Burst: 3, PerMinute: 1}},
+		{Method: "POST", Route: "/cart/add", Key: RateLimitByIP, Limit: ratelimit.Limit{Burst: 1, PerMinute: 1}},
+	}
+	engine := gin.New()
+	engine.Use(SessionMiddleware(store, testCookie, logger), RateLimitMiddleware(ratelimit.NewMemoryLimiter(), rules, reg, logger))
+	engine.POST("/api/v1/cart/items", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	engine.POST("/cart/add", func(c *gin.Context) {
+		c.Status(http.StatusFound)
+	})
+	engine.GET("/cart/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	serve := func(method, path string, s *session.Session) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(s.ID)))
+		engine.ServeHTTP(res, req)
+		return res
+	}
+
+	// the first session uses up its own limit
+	assert.Equal(t, http.StatusCreated, serve("POST", "/api/v1/cart/items", first).Code)
+	assert.Equal(t, http.StatusCreated, serve("POST", "/api/v1/cart/items", first).Code)
+	res := serve("POST", "/api/v1/cart/items", first)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "60", res.Header().Get("Retry-After"))
+	assert.Contains(t, res.Body.String(), `"too_many_requests"`)
+	assert.Contains(t, res.Body.String(), RateLimitErrorMessage)
+
+	// the second session has its own bucket, but shares the one of the IP address
+	assert.Equal(t, http.StatusCreated, serve("POST", "/api/v1/cart/items", second).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("POST", "/api/v1/cart/items", second).Code)
+
+	// pages get plain text, and routes without limits are not throttled
+	assert.Equal(t, http.StatusFound, serve("POST", "/cart/add", first).Code)
+	res = serve("POST", "/cart/add", first)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, RateLimitErrorMessage, res.Body.String())
+	assert.Equal(t, http.StatusOK, serve("GET", "/cart/", first).Code)
+
+	assert.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP http_requests_throttled_total Number of HTTP requests rejected by a rate limit.
+# TYPE http_requests_throttled_total counter
+http_requests_throttled_total{key="ip",method="POST",route="/api/v1/cart/items"} 1
+http_requests_throttled_total{key="ip",method="POST",route="/cart/add"} 1
+http_requests_throttled_total{key="session",method="POST",route="/api/v1/cart/items"} 1
+`), "http_requests_throttled_total"))
+}
+
+func TestRateLimitMiddleware_NewSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	rules := []RateLimitRule{{Method: "POST", Route: "/cart/add", Key: RateLimitBySession, Limit: ratelimit.Limit{Burst: 2, PerMinute: 1}}}
+	engine := gin.New()
+	engine.Use(SessionMiddleware(store, testCookie, logger),
+		RateLimitMiddleware(ratelimit.NewMemoryLimiter(), rules, prometheus.NewRegistry(), logger))
+	engine.POST("/cart/add", func(c *gin.Context) {
+		c.Status(http.StatusFound)
+	})
+	serve := func(remoteAddr string, s *session.Session) int {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cart/add", nil)
+		req.RemoteAddr = remoteAddr
+		if s != nil {
+			req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(s.ID)))
+		}
+		engine.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	// a client that drops the cookie gets a new session every time, but not a new limit
+	assert.Equal(t, http.StatusFound, serve("192.0.2.1:1234", nil))
+	assert.Equal(t, http.StatusFound, serve("192.0.2.1:1234", nil))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.1:1234", nil))
+
+	// the limit of other addresses and of stored sessions is their own
+	assert.Equal(t, http.StatusFound, serve("192.0.2.2:1234", nil))
+	assert.Equal(t, http.StatusFound, serve("192.0.2.1:1234", stored))
+	assert.Equal(t, http.StatusFound, serve("192.0.2.1:1234", stored))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.1:1234", stored))
+}
+
+func TestRateLimitMiddleware_LimiterDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	rules := []RateLimitRule{{Method: "POST", Route: "/cart/add", Key: RateLimitByIP, Limit: ratelimit.Limit{Burst: 1, PerMinute: 1}}}
+	engine := gin.New()
+	engine.Use(SessionMiddleware(session.NewMemoryStore(), testCookie, logger),
+		RateLimitMiddleware(failingLimiter{}, rules, prometheus.NewRegistry(), logger))
+	engine.POST("/cart/add", func(c *gin.Context) {
+		c.Status(http.StatusFound)
+	})
+
+	for i := 0; i < 3; i++ {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cart/add", nil)
+		engine.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusFound, res.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"strings"
+	"time"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware records the request ID and correlation ID of the request in its context, so that
+// every logger decorated with the context via log.Logger.With carries them. A request ID is generated when
+// the client did not send a valid one. The request ID is echoed in the X-Request-ID response header.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := log.WithRequest(c.Request.Context(), c.Request)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(requestIDHeader, log.RequestID(ctx))
+		c.Next()
+	}
+}
+
+// AccessLogMiddleware logs every handled request as a structured entry, at ERROR level for server errors.
+// It should run after RequestIDMiddleware so that the entries carry the request ID.
+func AccessLogMiddleware(logger log.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		l := logger.With(c.Request.Context(),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+			"user_agent", c.Request.UserAgent(),
+		)
+		if status >= 500 {
+			l.Errorf("%s %s %d", c.Request.Method, c.Request.URL.Path, status)
+			return
+		}
+		l.Infof("%s %s %d", c.Request.Method, c.Request.URL.Path, status)
+	}
+}
+
+// RecoveryMiddleware turns a panic in a handler into a 500 response and logs it with the request context.
+func RecoveryMiddleware(logger log.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logger.With(c.Request.Context()).Errorf("panic while handling request: %v", recovered)
+				c.AbortWithStatus(500)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// abortWithError rejects the request with the error, as JSON for API requests, under /api/, and plain text for
+// others.
+func abortWithError(c *gin.Context, res apierrors.ErrorResponse) {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.AbortWithStatusJSON(res.StatusCode(), res.Envelope())
+		return
+	}
+	c.Abort()
+	c.String(res.StatusCode(), res.Message)
+}
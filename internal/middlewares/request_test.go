@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"interview/pkg/log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRequestMiddlewares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, entries := log.NewForTest()
+	engine := gin.New()
+	engine.Use(RequestIDMiddleware(), AccessLogMiddleware(logger), RecoveryMiddleware(logger))
+	engine.GET("/items/:id", func(c *gin.Context) {
+		logger.With(c.Request.Context()).Info("handling")
+		c.Status(http.StatusNoContent)
+	})
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	req.Header.Set("X-Correlation-ID", "123")
+	engine.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "abc", res.Header().Get("X-Request-ID"))
+
+	logged := entries.TakeAll()
+	assert.Equal(t, 2, len(logged))
+	for _, entry := range logged {
+		assert.Equal(t, "abc", entry.ContextMap()["request_id"])
+		assert.Equal(t, "123", entry.ContextMap()["correlation_id"])
+	}
+	access := logged[1].ContextMap()
+	assert.Equal(t, "GET /items/1 204", logged[1].Message)
+	assert.Equal(t, "/items/:id", access["route"])
+	assert.Equal(t, int64(204), access["status"])
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/panic", nil)
+	engine.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	generated := res.Header().Get("X-Request-ID")
+	assert.Len(t, generated, 36)
+	logged = entries.TakeAll()
+	assert.Equal(t, 2, len(logged))
+	assert.Equal(t, zapcore.ErrorLevel, logged[0].Level)
+	assert.Equal(t, generated, logged[0].ContextMap()["request_id"])
+	assert.Equal(t, zapcore.ErrorLevel, logged[1].Level)
+	assert.Equal(t, int64(500), logged[1].ContextMap()["status"])
+}
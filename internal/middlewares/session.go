@@ -1,28 +1,155 @@
 package middlewares
 
 import (
-	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"interview/pkg/log"
+	"interview/pkg/session"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
-const cookieName = "ice_session_id"
+// SessionCookie configures the cookie that carries the session ID.
+type SessionCookie struct {
+	Name string
+	// Domain is empty for a cookie that is only sent to the host that set it.
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+	// Lifetime is how long a session is kept after its last request. The cookie expires with it.
+	Lifetime time.Duration
+	// Secret is the key the session ID in the cookie is signed with.
+	Secret []byte
+}
+
+// sign returns the cookie value for the session ID: the ID and its HMAC.
+func (sc SessionCookie) sign(id string) string {
+	mac := hmac.New(sha256.New, sc.Secret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
 
-func SessionMiddleware(logger log.Logger) gin.HandlerFunc {
+// verify returns the session ID in the cookie value, or false if the value was not signed with the secret.
+func (sc SessionCookie) verify(value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", false
+	}
+	id := value[:i]
+	return id, hmac.Equal([]byte(sc.sign(id)), []byte(value))
+}
+
+// SessionMiddleware loads the session referenced by the session cookie from the store and makes it
+// available to handlers via session.FromContext. The cookie carries the session ID signed with the secret, so that
+// forged IDs are rejected without a lookup. Unknown, expired or forged session IDs are replaced with a new session,
+// so clients cannot choose their own session ID. The session is saved back to the store after the request has been
+// handled, and the cookie is sent again, which extends the expiry of both.
+//
+// A handler may renew the session, e.g. when a user logs in. The cookie then receives the new ID and
+// the session stored under the old ID is deleted. A session that another request renewed or deleted while this one
+// was handled is not saved back, so that its old ID cannot be brought back to life.
+func SessionMiddleware(store session.Store, sc SessionCookie, logger log.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var sessionId string
-		cookie, err := c.Request.Cookie(cookieName)
-		if err != nil {
-			sessionId = uuid.New().String()
-			c.SetCookie(cookieName, sessionId, 3600, "/", "localhost", false, true)
+		ctx := c.Request.Context()
+		var sess *session.Session
+		storedID := ""
+		cookie, err := c.Request.Cookie(sc.Name)
+		if err == nil {
+			if id, ok := sc.verify(cookie.Value); ok {
+				sess, err = store.Get(ctx, id)
+				if err != nil && !errors.Is(err, session.NotFoundError) {
+					logger.With(ctx).Errorf("error loading session: %v", err)
+					c.AbortWithStatus(500)
+					return
+				}
+			}
+		}
+		if sess == nil {
+			sess = session.New()
+			c.Request = c.Request.WithContext(session.WithNewSession(ctx, sess))
 		} else {
-			sessionId = cookie.Value
+			storedID = sess.ID
+			c.Request = c.Request.WithContext(session.WithSession(ctx, sess))
 		}
-		ctx := c.Request.Context()
-		ctx = context.WithValue(ctx, "SessionId", sessionId)
-		c.Request = c.Request.WithContext(ctx)
+
+		// the cookie is set just before the response status is written, so that it carries the ID of a renewed session
+		setCookie := func() {
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     sc.Name,
+				Value:    sc.sign(sess.ID),
+				Path:     "/",
+				Domain:   sc.Domain,
+				MaxAge:   int(sc.Lifetime.Seconds()),
+				Secure:   sc.Secure,
+				HttpOnly: true,
+				SameSite: sc.SameSite,
+			})
+		}
+		writer := &beforeWriteWriter{ResponseWriter: c.Writer, before: setCookie}
+		c.Writer = writer
+
 		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		if !writer.called && !c.Writer.Written() {
+			setCookie()
+		}
+		if storedID != "" && storedID != sess.ID {
+			if err := store.Delete(ctx, storedID); err != nil {
+				logger.With(ctx).Errorf("error deleting renewed session: %v", err)
+			}
+		}
+		if storedID != "" && storedID == sess.ID {
+			// a concurrent request may have renewed or deleted the session meanwhile, which must not be undone
+			err = store.Update(ctx, sess, sc.Lifetime)
+			if errors.Is(err, session.NotFoundError) {
+				logger.With(ctx).Infof("the session was renewed or deleted while the request was handled, its changes are dropped")
+				return
+			}
+		} else {
+			err = store.Save(ctx, sess, sc.Lifetime)
+		}
+		if err != nil {
+			logger.With(ctx).Errorf("error saving session: %v", err)
+		}
+	}
+}
+
+// beforeWriteWriter calls before once, when the response status or body is first written.
+type beforeWriteWriter struct {
+	gin.ResponseWriter
+	before func()
+	called bool
+}
+
+func (w *beforeWriteWriter) callBefore() {
+	if !w.called {
+		w.called = true
+		w.before()
 	}
 }
+
+func (w *beforeWriteWriter) WriteHeader(code int) {
+	w.callBefore()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *beforeWriteWriter) WriteHeaderNow() {
+	w.callBefore()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *beforeWriteWriter) Write(data []byte) (int, error) {
+	w.callBefore()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *beforeWriteWriter) WriteString(s string) (int, error) {
+	w.callBefore()
+	return w.ResponseWriter.WriteString(s)
+}
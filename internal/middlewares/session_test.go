@@ -1,11 +1,14 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
 	"interview/pkg/log"
+	"interview/pkg/session"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -13,29 +16,180 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+var testCookie = SessionCookie{
+	Name:     "ice_session_id",
+	Lifetime: time.Hour,
+	Secret:   []byte("0123456789abcdef0123456789abcdef"),
+}
+
 func TestSettingNewSessionToRequests(t *testing.T) {
 	res := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(res)
 	c.Request, _ = http.NewRequest("GET", "/", nil)
 	logger, _ := log.NewForTest()
-	handler := SessionMiddleware(logger)
+	store := session.NewMemoryStore()
+	handler := SessionMiddleware(store, testCookie, logger)
 	handler(c)
 	ctx := c.Request.Context()
-	session := ctx.Value("SessionId")
-	assert.NotNil(t, session)
+	sess := session.FromContext(ctx)
+	assert.NotNil(t, sess)
+	assert.Contains(t, res.Header().Get("Set-Cookie"), sess.ID)
+	_, err := store.Get(context.Background(), sess.ID)
+	assert.Nil(t, err)
 }
 
 func TestGettingSessionFromRequestCookie(t *testing.T) {
+	res := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(res)
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	stored.CartID = 5
+	_ = store.Save(context.Background(), stored, time.Hour)
+	c.Request.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(stored.ID)))
+	handler := SessionMiddleware(store, testCookie, logger)
+	handler(c)
+	ctx := c.Request.Context()
+	sess := session.FromContext(ctx)
+	assert.NotNil(t, sess)
+	assert.Equal(t, stored.ID, sess.ID)
+	assert.Equal(t, uint(5), sess.CartID)
+}
+
+func TestRejectingUnknownSessionFromRequestCookie(t *testing.T) {
 	res := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(res)
 	c.Request, _ = http.NewRequest("GET", "/", nil)
 	sessionId := uuid.New().String()
-	c.Request.Header.Add("Cookie", fmt.Sprintf("%s=%s", cookieName, sessionId))
+	c.Request.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(sessionId)))
 	logger, _ := log.NewForTest()
-	handler := SessionMiddleware(logger)
+	handler := SessionMiddleware(session.NewMemoryStore(), testCookie, logger)
 	handler(c)
 	ctx := c.Request.Context()
-	session := ctx.Value("SessionId")
-	assert.NotNil(t, session)
-	assert.Equal(t, sessionId, session)
+	sess := session.FromContext(ctx)
+	assert.NotNil(t, sess)
+	assert.NotEqual(t, sessionId, sess.ID)
+}
+
+func TestSavingSessionChanges(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	_, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.Use(SessionMiddleware(store, testCookie, logger))
+	engine.GET("/", func(c *gin.Context) {
+		session.FromContext(c.Request.Context()).AddFlash("saved")
+	})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	engine.ServeHTTP(res, req)
+
+	id, _ := testCookie.verify(res.Result().Cookies()[0].Value)
+	sess, err := store.Get(context.Background(), id)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"saved"}, sess.Flashes)
+}
+
+func TestRenewingSession(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	_, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.Use(SessionMiddleware(store, testCookie, logger))
+	engine.POST("/login", func(c *gin.Context) {
+		sess := session.FromContext(c.Request.Context())
+		sess.UserID = 7
+		sess.Renew()
+		c.Redirect(302, "/")
+	})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/login", nil)
+	req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(stored.ID)))
+	engine.ServeHTTP(res, req)
+
+	cookies := res.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	id, ok := testCookie.verify(cookies[0].Value)
+	assert.True(t, ok)
+	assert.NotEqual(t, stored.ID, id)
+	sess, err := store.Get(context.Background(), id)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(7), sess.UserID)
+	_, err = store.Get(context.Background(), stored.ID)
+	assert.ErrorIs(t, err, session.NotFoundError)
+}
+
+func TestNotSavingSessionDeletedMeanwhile(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	_, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.Use(SessionMiddleware(store, testCookie, logger))
+	engine.POST("/cart/add", func(c *gin.Context) {
+		// a concurrent request logs in, which renews the session and deletes it under its old ID
+		_ = store.Delete(context.Background(), stored.ID)
+		session.FromContext(c.Request.Context()).AddFlash("added")
+		c.Redirect(302, "/")
+	})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/cart/add", nil)
+	req.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, testCookie.sign(stored.ID)))
+	engine.ServeHTTP(res, req)
+
+	assert.Equal(t, 302, res.Code)
+	_, err := store.Get(context.Background(), stored.ID)
+	assert.ErrorIs(t, err, session.NotFoundError)
+}
+
+func TestRejectingForgedSessionCookie(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	_ = store.Save(context.Background(), stored, time.Hour)
+	other := testCookie
+	other.Secret = []byte("another secret of at least 32 bytes")
+	for _, value := range []string{stored.ID, other.sign(stored.ID), stored.ID + ".", testCookie.sign(stored.ID) + "x"} {
+		res := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(res)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Add("Cookie", fmt.Sprintf("%s=%s", testCookie.Name, value))
+		SessionMiddleware(store, testCookie, logger)(c)
+		assert.NotEqual(t, stored.ID, session.FromContext(c.Request.Context()).ID, value)
+	}
+}
+
+func TestRefreshingSessionCookie(t *testing.T) {
+	logger, _ := log.NewForTest()
+	store := session.NewMemoryStore()
+	stored := session.New()
+	_ = store.Save(context.Background(), stored, time.Minute)
+	sc := SessionCookie{
+		Name:     "sid",
+		Domain:   "shop.example.com",
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		Lifetime: 2 * time.Hour,
+		Secret:   testCookie.Secret,
+	}
+	_, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.Use(SessionMiddleware(store, sc, logger))
+	engine.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("Cookie", fmt.Sprintf("%s=%s", sc.Name, sc.sign(stored.ID)))
+	engine.ServeHTTP(res, req)
+
+	// the cookie of an existing session is sent again with the full lifetime
+	cookies := res.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, sc.sign(stored.ID), cookies[0].Value)
+	assert.Equal(t, "shop.example.com", cookies[0].Domain)
+	assert.Equal(t, 7200, cookies[0].MaxAge)
+	assert.True(t, cookies[0].Secure)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
 }
@@ -1,15 +1,46 @@
 package router
 
 import (
-	"interview/pkg/db"
+	"net/http"
+	"strings"
+	"time"
 
+	"interview/internal/config"
 	"interview/internal/middlewares"
+	"interview/internal/tracing"
 	"interview/pkg/cart"
+	"interview/pkg/db"
+	"interview/pkg/health"
+	"interview/pkg/idempotency"
+	"interview/pkg/inventory"
 	"interview/pkg/log"
+	"interview/pkg/order"
+	"interview/pkg/promotion"
+	"interview/pkg/ratelimit"
+	"interview/pkg/session"
+	"interview/pkg/shipping"
+	"interview/pkg/tax"
+	"interview/pkg/user"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+const (
+	MetricsPath  = "/metrics"
+	LogLevelPath = "/log/level"
 )
 
+// sameSiteModes maps the SameSite modes of the configuration to those of the session cookie.
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
 type routes struct {
 	router *gin.Engine
 }
@@ -20,10 +51,74 @@ func New(router *gin.Engine) *routes {
 	}
 }
 
-func (r *routes) RegisterHandlers(logger log.Logger, db *db.DB) {
-	r.router.Use(middlewares.SessionMiddleware(logger))
+func (r *routes) RegisterHandlers(cfg *config.Config, logger log.Logger, logLevel http.Handler, db *db.DB, sessionStore session.Store, limiter ratelimit.Limiter, readiness *health.Readiness, checks *health.Registry) {
+	metrics := prometheus.NewRegistry()
+	metrics.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if sqlDB, err := db.DB().DB(); err == nil {
+		metrics.MustRegister(collectors.NewDBStatsCollector(sqlDB, cfg.DBDriver))
+	}
+	r.router.Use(middlewares.RequestIDMiddleware())
+	r.router.Use(otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != health.LivePath && req.URL.Path != health.ReadyPath && req.URL.Path != MetricsPath
+	})))
+	r.router.Use(middlewares.AccessLogMiddleware(logger))
+	r.router.Use(middlewares.MetricsMiddleware(metrics))
+	r.router.Use(middlewares.RecoveryMiddleware(logger))
+
+	// probes, metrics and the log level are registered before the other middlewares so that they neither create sessions nor open transactions
+	health.RegisterHandlers(r.router, readiness, checks)
+	r.router.GET(MetricsPath, gin.WrapH(promhttp.HandlerFor(metrics, promhttp.HandlerOpts{})))
+	if cfg.LogLevelEndpoint {
+		admin := r.router.Group(LogLevelPath, middlewares.AdminTokenMiddleware(cfg.AdminToken, logger))
+		admin.GET("", gin.WrapH(logLevel))
+		admin.PUT("", gin.WrapH(logLevel))
+	}
+
+	sessionCookie := middlewares.SessionCookie{
+		Name:     cfg.SessionCookieName,
+		Domain:   cfg.SessionCookieDomain,
+		Secure:   cfg.SessionCookieSecure,
+		SameSite: sameSiteModes[cfg.SessionCookieSameSite],
+		Lifetime: time.Duration(cfg.SessionLifetime) * time.Second,
+		Secret:   []byte(cfg.SessionSecret),
+	}
+	r.router.Use(middlewares.SessionMiddleware(sessionStore, sessionCookie, logger))
+	r.router.Use(middlewares.RateLimitMiddleware(limiter, rateLimitRules(cfg.RateLimits), metrics, logger))
+	r.router.Use(middlewares.CSRFMiddleware(logger))
+	idempotencyService := idempotency.NewService(idempotency.NewRepository(db, logger), time.Duration(cfg.IdempotencyWindow)*time.Second, logger)
+	r.router.Use(middlewares.IdempotencyMiddleware(idempotencyService, logger))
 	r.router.Use(db.TransactionHandler())
 	cartRepo := cart.NewRepository(db, logger)
-	cartService := cart.NewService(cartRepo, logger)
+	productRepo := cart.NewProductRepository(db, logger)
+	orderRepo := order.NewRepository(db, logger)
+	inventoryService := inventory.NewService(inventory.NewRepository(db, logger), logger)
+	promotionService := promotion.NewService(promotion.NewRepository(db, logger), logger)
+	taxSettings := tax.Settings{Region: cfg.TaxRegion, Inclusive: cfg.TaxInclusivePrices, Rounding: tax.Rounding(cfg.TaxRounding)}
+	taxService := tax.NewService(tax.NewRepository(db, logger), taxSettings, logger)
+	shippingService := shipping.NewService(shipping.NewRepository(db, logger), logger)
+	cartService := cart.NewTracedService(cart.NewService(cartRepo, productRepo, orderRepo, inventoryService, promotionService, taxService, shippingService, logger))
+	cartService = cart.NewInstrumentedService(cartService, cartRepo, metrics, logger)
 	cart.RegisterHandlers(r.router.Group(cart.CartPath), cartService, logger)
+	cart.RegisterAPIHandlers(r.router.Group(cart.APIPath), cartService, logger)
+	userService := user.NewService(user.NewRepository(db, logger), cartService, logger)
+	user.RegisterHandlers(r.router.Group(user.AccountPath), userService, logger)
+	user.RegisterAPIHandlers(r.router.Group(user.APIPath), userService, logger)
+}
+
+// rateLimitRules turns the rate limits of the configuration into the rules of the rate limit middleware.
+func rateLimitRules(limits []config.RateLimit) []middlewares.RateLimitRule {
+	rules := make([]middlewares.RateLimitRule, 0, len(limits))
+	for _, limit := range limits {
+		method, route, _ := strings.Cut(limit.Route, " ")
+		rules = append(rules, middlewares.RateLimitRule{
+			Method: method,
+			Route:  route,
+			Key:    limit.Key,
+			Limit:  ratelimit.Limit{Burst: limit.Burst, PerMinute: limit.PerMinute},
+		})
+	}
+	return rules
 }
@@ -1,13 +1,20 @@
 package utils
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"interview/pkg/session"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -29,11 +36,16 @@ func GetTemplatesDir() string {
 	return filepath.Join(rootDir, "static", "templates")
 }
 
-func RenderTemplate(pageData interface{}, templateName string) (string, error) {
+// RenderTemplate renders the named template with the page data. Forms in templates add the CSRF token of the
+// session of the context with {{ csrfField }}, and a new idempotency key with {{ idempotencyField }}.
+func RenderTemplate(ctx context.Context, pageData interface{}, templateName string) (string, error) {
 	// Read and parse the HTML template file
 	templatesDir := GetTemplatesDir()
 	templatePath := filepath.Join(templatesDir, templateName)
-	tmpl, err := template.ParseFiles(templatePath)
+	tmpl, err := template.New(templateName).Funcs(template.FuncMap{
+		"csrfField":        csrfField(ctx),
+		"idempotencyField": idempotencyField,
+	}).ParseFiles(templatePath)
 	if err != nil {
 		return "", fmt.Errorf("Error parsing template: %v ", err)
 	}
@@ -52,8 +64,44 @@ func RenderTemplate(pageData interface{}, templateName string) (string, error) {
 	return resultString, nil
 }
 
-func GetDBConnection(dsn string) (*gorm.DB, error) {
-	return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// csrfField returns a template function that renders the hidden form field with the CSRF token of the session.
+func csrfField(ctx context.Context) func() template.HTML {
+	return func() template.HTML {
+		sess := session.FromContext(ctx)
+		if sess == nil {
+			return ""
+		}
+		return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s" />`, session.CSRFField,
+			template.HTMLEscapeString(sess.CSRFToken())))
+	}
+}
+
+// idempotencyField renders the hidden form field with a new idempotency key, so that submitting the form twice
+// takes effect once. The name of the field is idempotency.KeyField, which cannot be imported here because the
+// package depends on this one.
+func idempotencyField() template.HTML {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="idempotency_key" value="%s" />`,
+		base64.RawURLEncoding.EncodeToString(key)))
+}
+
+// GetDBConnection opens a connection to the database using the given driver ("mysql", "postgres" or "sqlite").
+func GetDBConnection(driver string, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case "mysql":
+		dialector = mysql.Open(dsn)
+	case "postgres":
+		dialector = postgres.Open(dsn)
+	case "sqlite":
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	return gorm.Open(dialector, &gorm.Config{})
 }
 
 func CloseDBConnection(db *gorm.DB) error {
@@ -0,0 +1,538 @@
+package cart
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/entity"
+	"interview/pkg/inventory"
+	"interview/pkg/log"
+	"interview/pkg/money"
+	"interview/pkg/order"
+	"interview/pkg/promotion"
+	"interview/pkg/shipping"
+	"interview/pkg/tax"
+
+	"github.com/gin-gonic/gin"
+)
+
+const APIPath = "/api/v1/cart"
+
+func RegisterAPIHandlers(r *gin.RouterGroup, service Service, logger log.Logger) {
+	res := apiResource{service, logger}
+
+	r.GET("", res.getCart())
+	r.POST("/items", res.addItem())
+	r.PATCH("/items/:id", res.updateItem())
+	r.DELETE("/items/:id", res.deleteItem())
+	r.POST("/coupon", res.applyCoupon())
+	r.DELETE("/coupon", res.removeCoupon())
+	r.PUT("/region", res.setRegion())
+	r.PUT("/shipping", res.setShipping())
+	r.POST("/checkout", res.checkout())
+	r.GET("/orders", res.getOrders())
+	r.GET("/orders/:id", res.getOrder())
+}
+
+type apiResource struct {
+	service Service
+	logger  log.Logger
+}
+
+type cartResponse struct {
+	ID        uint               `json:"id"`
+	Status    entity.Status      `json:"status"`
+	Subtotal  money.Money        `json:"subtotal"`
+	Discounts []discountResponse `json:"discounts"`
+	Region    string             `json:"region"`
+	// PricesIncludeTax tells whether the subtotal and discounts include the tax.
+	PricesIncludeTax bool               `json:"prices_include_tax"`
+	Net              money.Money        `json:"net"`
+	Tax              money.Money        `json:"tax"`
+	TaxLines         []taxLineResponse  `json:"tax_lines"`
+	Total            money.Money        `json:"total"`
+	Coupon           *couponResponse    `json:"coupon,omitempty"`
+	Shipping         *shippingResponse  `json:"shipping,omitempty"`
+	Items            []cartItemResponse `json:"items"`
+}
+
+type shippingResponse struct {
+	Country string `json:"country"`
+	Method  string `json:"method,omitempty"`
+	// Price is the price of the chosen method, zero if no method is chosen.
+	Price   money.Money             `json:"price"`
+	Options []shippingQuoteResponse `json:"options"`
+	// Error tells why the cart cannot be shipped to the country or with the method.
+	Error string `json:"error,omitempty"`
+}
+
+type shippingQuoteResponse struct {
+	Method string `json:"method"`
+	Name   string `json:"name"`
+	// Weight is the billed weight of the parcel in grams.
+	Weight int         `json:"weight"`
+	Price  money.Money `json:"price"`
+	Free   bool        `json:"free"`
+}
+
+type taxLineResponse struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	// Rate is in percent, e.g. "8.25".
+	Rate    string      `json:"rate"`
+	Taxable money.Money `json:"taxable"`
+	Tax     money.Money `json:"tax"`
+}
+
+type discountResponse struct {
+	Code        string      `json:"code"`
+	Description string      `json:"description"`
+	Amount      money.Money `json:"amount"`
+}
+
+type couponResponse struct {
+	Code string `json:"code"`
+	// Error tells why the coupon gives no discount on the cart.
+	Error string `json:"error,omitempty"`
+}
+
+type cartItemResponse struct {
+	ID        uint        `json:"id"`
+	ProductID uint        `json:"product_id"`
+	Product   string      `json:"product"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"`
+}
+
+type addItemRequest struct {
+	Product  string `json:"product"  binding:"required"`
+	Quantity int    `json:"quantity"`
+}
+
+type updateItemRequest struct {
+	Quantity *int `json:"quantity" binding:"required"`
+}
+
+type applyCouponRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type setRegionRequest struct {
+	Region *string `json:"region" binding:"required"`
+}
+
+type setShippingRequest struct {
+	Country *string `json:"country" binding:"required"`
+	Method  string  `json:"method"`
+}
+
+type orderResponse struct {
+	ID         uint               `json:"id"`
+	CartID     uint               `json:"cart_id"`
+	Status     entity.OrderStatus `json:"status"`
+	CouponCode string             `json:"coupon_code,omitempty"`
+	Discount   money.Money        `json:"discount"`
+	Region     string             `json:"region"`
+	Net        money.Money        `json:"net"`
+	Tax        money.Money        `json:"tax"`
+	// PricesIncludeTax tells whether the prices of the lines and the discount include the tax.
+	PricesIncludeTax bool              `json:"prices_include_tax"`
+	TaxLines         []taxLineResponse `json:"tax_lines"`
+	// ShippingCountry and ShippingMethod are empty if the order is not shipped.
+	ShippingCountry string              `json:"shipping_country,omitempty"`
+	ShippingMethod  string              `json:"shipping_method,omitempty"`
+	Shipping        money.Money         `json:"shipping"`
+	Total           money.Money         `json:"total"`
+	CreatedAt       time.Time           `json:"created_at"`
+	Lines           []orderLineResponse `json:"lines"`
+}
+
+type orderSummaryResponse struct {
+	ID        uint               `json:"id"`
+	Status    entity.OrderStatus `json:"status"`
+	Total     money.Money        `json:"total"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type orderPageResponse struct {
+	Orders  []orderSummaryResponse `json:"orders"`
+	Page    int                    `json:"page"`
+	PerPage int                    `json:"per_page"`
+	Total   int64                  `json:"total"`
+}
+
+type orderLineResponse struct {
+	ProductID uint        `json:"product_id"`
+	Product   string      `json:"product"`
+	Quantity  int         `json:"quantity"`
+	Price     money.Money `json:"price"`
+}
+
+func newOrderResponse(placed order.Order) orderResponse {
+	lines := make([]orderLineResponse, 0, len(placed.Lines))
+	for _, line := range placed.Lines {
+		lines = append(lines, orderLineResponse{
+			ProductID: line.ProductID,
+			Product:   line.ProductName,
+			Quantity:  line.Quantity,
+			Price:     line.Price,
+		})
+	}
+	taxLines := make([]taxLineResponse, 0, len(placed.TaxLines))
+	for _, line := range placed.TaxLines {
+		taxLines = append(taxLines, newTaxLineResponse(tax.TaxLine{
+			Name:     line.Name,
+			Category: line.Category,
+			Rate:     line.Rate,
+			Taxable:  line.Taxable,
+			Tax:      line.Tax,
+		}))
+	}
+	return orderResponse{
+		ID:               placed.ID,
+		CartID:           placed.CartID,
+		Status:           placed.Status,
+		CouponCode:       placed.CouponCode,
+		Discount:         placed.Discount,
+		Region:           placed.Region,
+		Net:              placed.Net,
+		Tax:              placed.Tax,
+		PricesIncludeTax: placed.PricesIncludeTax,
+		TaxLines:         taxLines,
+		ShippingCountry:  placed.ShippingCountry,
+		ShippingMethod:   placed.ShippingMethod,
+		Shipping:         placed.Shipping,
+		Total:            placed.Total,
+		CreatedAt:        placed.CreatedAt,
+		Lines:            lines,
+	}
+}
+
+func newTaxLineResponse(line tax.TaxLine) taxLineResponse {
+	return taxLineResponse{
+		Name:     line.Name,
+		Category: line.Category,
+		Rate:     tax.FormatRate(line.Rate),
+		Taxable:  line.Taxable,
+		Tax:      line.Tax,
+	}
+}
+
+func newOrderPageResponse(page OrderPage) orderPageResponse {
+	orders := make([]orderSummaryResponse, 0, len(page.Orders))
+	for _, o := range page.Orders {
+		orders = append(orders, orderSummaryResponse{
+			ID:        o.ID,
+			Status:    o.Status,
+			Total:     o.Total,
+			CreatedAt: o.CreatedAt,
+		})
+	}
+	return orderPageResponse{
+		Orders:  orders,
+		Page:    page.Page,
+		PerPage: page.PerPage,
+		Total:   page.Total,
+	}
+}
+
+func newCartResponse(cart Cart) cartResponse {
+	items := make([]cartItemResponse, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, cartItemResponse{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Product:   item.ProductName,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+	discounts := make([]discountResponse, 0, len(cart.Discounts))
+	for _, discount := range cart.Discounts {
+		discounts = append(discounts, discountResponse{
+			Code:        discount.Code,
+			Description: discount.Description,
+			Amount:      discount.Amount,
+		})
+	}
+	taxLines := make([]taxLineResponse, 0, len(cart.Taxes.Lines))
+	for _, line := range cart.Taxes.Lines {
+		taxLines = append(taxLines, newTaxLineResponse(line))
+	}
+	res := cartResponse{
+		ID:               cart.ID,
+		Status:           cart.Status,
+		Subtotal:         cart.Subtotal(),
+		Discounts:        discounts,
+		Region:           cart.Taxes.Region,
+		PricesIncludeTax: cart.Taxes.Inclusive,
+		Net:              cart.Taxes.Net,
+		Tax:              cart.Taxes.Tax,
+		TaxLines:         taxLines,
+		Total:            cart.GrandTotal(),
+		Items:            items,
+	}
+	if cart.CouponCode != "" {
+		res.Coupon = &couponResponse{Code: cart.CouponCode}
+		if cart.CouponError != nil {
+			res.Coupon.Error = cart.CouponError.Error()
+		}
+	}
+	if cart.ShippingCountry != "" {
+		res.Shipping = newShippingResponse(cart)
+	}
+	return res
+}
+
+func newShippingResponse(cart Cart) *shippingResponse {
+	options := make([]shippingQuoteResponse, 0, len(cart.ShippingQuotes))
+	for _, quote := range cart.ShippingQuotes {
+		options = append(options, shippingQuoteResponse{
+			Method: quote.Method,
+			Name:   quote.Name,
+			Weight: quote.Weight,
+			Price:  quote.Price,
+			Free:   quote.Free,
+		})
+	}
+	res := &shippingResponse{
+		Country: cart.ShippingCountry,
+		Method:  cart.ShippingMethod,
+		Price:   cart.Shipping(),
+		Options: options,
+	}
+	if cart.ShippingError != nil {
+		res.Error = cart.ShippingError.Error()
+	}
+	return res
+}
+
+func (r *apiResource) getCart() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cart, err := r.service.GetCart(c.Request.Context())
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) addItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var req addItemRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with a product and a quantity"))
+			return
+		}
+		if err := r.service.AddItemToCart(ctx, req.Product, req.Quantity); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		cart, err := r.service.GetCart(ctx)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) updateItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		cartItemID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			r.respondError(c, apierrors.BadRequest("cart item id must be a number"))
+			return
+		}
+		var req updateItemRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with a quantity"))
+			return
+		}
+		if err := r.service.UpdateCartItemQuantity(ctx, uint(cartItemID), *req.Quantity); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		cart, err := r.service.GetCart(ctx)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) deleteItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cartItemID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			r.respondError(c, apierrors.BadRequest("cart item id must be a number"))
+			return
+		}
+		if err := r.service.DeleteCartItem(c.Request.Context(), uint(cartItemID)); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (r *apiResource) applyCoupon() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var req applyCouponRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with a code"))
+			return
+		}
+		if err := r.service.ApplyCoupon(ctx, req.Code); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		cart, err := r.service.GetCart(ctx)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) removeCoupon() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if err := r.service.RemoveCoupon(ctx); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		cart, err := r.service.GetCart(ctx)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) setRegion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var req setRegionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with a region"))
+			return
+		}
+		if err := r.service.SetRegion(ctx, *req.Region); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		cart, err := r.service.GetCart(ctx)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) setShipping() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var req setShippingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with a country"))
+			return
+		}
+		if err := r.service.SetShipping(ctx, *req.Country, req.Method); err != nil {
+			r.respondError(c, err)
+			return
+		}
+		cart, err := r.service.GetCart(ctx)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newCartResponse(cart))
+	}
+}
+
+func (r *apiResource) checkout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		placed, err := r.service.Checkout(c.Request.Context())
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, newOrderResponse(placed))
+	}
+}
+
+func (r *apiResource) getOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, err := parseOrderQuery(c)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		page, err := r.service.GetOrders(c.Request.Context(), query)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newOrderPageResponse(page))
+	}
+}
+
+func (r *apiResource) getOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orderID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			r.respondError(c, apierrors.BadRequest("order id must be a number"))
+			return
+		}
+		placed, err := r.service.GetOrder(c.Request.Context(), uint(orderID))
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newOrderResponse(placed))
+	}
+}
+
+// respondError translates a service error into an API error response.
+// The error is also recorded on the gin context so that the request transaction is rolled back.
+func (r *apiResource) respondError(c *gin.Context, err error) {
+	var res apierrors.ErrorResponse
+	switch {
+	case errors.As(err, &res):
+	case errors.Is(err, InvalidProductError), errors.Is(err, InvalidQuantityError),
+		errors.Is(err, NegativeQuantityError), errors.Is(err, QuantityLimitError),
+		errors.Is(err, InvalidPageError), errors.Is(err, InvalidDateError),
+		errors.Is(err, PageOutOfRangeError), errors.Is(err, InvalidOrderStatusError), errors.Is(err, InvalidPeriodError),
+		errors.Is(err, promotion.CouponNotFoundError), errors.Is(err, promotion.CouponNotStartedError),
+		errors.Is(err, promotion.CouponExpiredError), errors.Is(err, promotion.MinimumNotMetError),
+		errors.Is(err, promotion.NotApplicableError), errors.Is(err, tax.UnknownRegionError),
+		errors.Is(err, shipping.UnknownDestinationError), errors.Is(err, shipping.NotShippableError),
+		errors.Is(err, shipping.UnknownMethodError):
+		res = apierrors.BadRequest(err.Error())
+	case errors.Is(err, CartNotFoundError), errors.Is(err, CartItemNotFoundError), errors.Is(err, OrderNotFoundError):
+		res = apierrors.NotFound(err.Error())
+	case errors.Is(err, EmptyCartError), errors.Is(err, inventory.OutOfStockError),
+		errors.Is(err, promotion.CouponUsedUpError), errors.Is(err, ShippingMethodRequiredError),
+		errors.Is(err, CurrencyMismatchError):
+		res = apierrors.Conflict(err.Error())
+	case errors.Is(err, InternalError):
+		res = apierrors.InternalServerError("")
+	default:
+		r.logger.With(c.Request.Context()).Errorf("unexpected error in cart API: %v", err)
+		res = apierrors.InternalServerError("")
+	}
+	_ = c.Error(err)
+	c.AbortWithStatusJSON(res.StatusCode(), res.Envelope())
+}
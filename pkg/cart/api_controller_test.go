@@ -0,0 +1,318 @@
+package cart
+
+import (
+	"encoding/json"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"interview/pkg/tax"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPITestEngine(repo *mockCartRepo, productRepo *mockProductRepo, id string) *gin.Engine {
+	logger, _ := log.NewForTest()
+	return newServiceTestEngine(NewService(repo, productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger), id)
+}
+
+// newServiceTestEngine serves the API of the given service to the session with the given ID.
+func newServiceTestEngine(service Service, id string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		ctx := session.WithSession(c.Request.Context(), &session.Session{ID: id})
+		c.Request = c.Request.WithContext(ctx)
+	})
+	RegisterAPIHandlers(engine.Group(APIPath), service, logger)
+	return engine
+}
+
+func serveAPI(engine *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPI_GetCart(t *testing.T) {
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	engine := newAPITestEngine(&repo, &productRepo, sessionID)
+
+	w := serveAPI(engine, "GET", APIPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res cartResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, uint(1), res.ID)
+	assert.Equal(t, usd(50000), res.Total)
+	assert.Equal(t, 2, len(res.Items))
+
+	engine = newAPITestEngine(&repo, &productRepo, "unknown")
+	w = serveAPI(engine, "GET", APIPath, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":{"status":404,"code":"not_found","message":"cart not found"}}`, w.Body.String())
+}
+
+func TestAPI_AddItem(t *testing.T) {
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	engine := newAPITestEngine(&repo, &productRepo, sessionID)
+
+	w := serveAPI(engine, "POST", APIPath+"/items", `{"product":"watch","quantity":2}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var res cartResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, usd(110000), res.Total)
+	assert.Equal(t, 3, len(res.Items))
+
+	w = serveAPI(engine, "POST", APIPath+"/items", `{"product":"hat","quantity":2}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "POST", APIPath+"/items", `{"product":"watch","quantity":-1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "POST", APIPath+"/items", `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPI_AddItem_OutOfStock(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	stock := mockInventory{available: map[uint]int{4: 1}}
+	engine := newServiceTestEngine(NewService(&repo, &productRepo, &mockOrderRepo{}, &stock, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger), sessionID)
+
+	w := serveAPI(engine, "POST", APIPath+"/items", `{"product":"watch","quantity":2}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":{"status":409,"code":"conflict","message":"not enough stock of watch"}}`, w.Body.String())
+}
+
+func TestAPI_Coupon(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	promotions := mockPromotions{coupons: []entity.Coupon{
+		{Code: "SHOES", Kind: entity.CouponBuyXGetY, BuyQuantity: 2, FreeQuantity: 1, ProductID: 1, Active: true},
+	}}
+	engine := newServiceTestEngine(NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &promotions, &mockTaxes{}, &mockShipping{}, logger), sessionID)
+
+	w := serveAPI(engine, "POST", APIPath+"/coupon", `{"code":"HATS"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"code":"bad_request","message":"unknown coupon code"}}`, w.Body.String())
+
+	w = serveAPI(engine, "POST", APIPath+"/coupon", `{"code":"shoes"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res cartResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, usd(50000), res.Subtotal)
+	assert.Equal(t, []discountResponse{{Code: "SHOES", Description: "buy 2 get 1 free shoe", Amount: usd(10000)}}, res.Discounts)
+	assert.Equal(t, usd(40000), res.Total)
+	assert.Equal(t, &couponResponse{Code: "SHOES"}, res.Coupon)
+
+	w = serveAPI(engine, "DELETE", APIPath+"/coupon", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = cartResponse{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Empty(t, res.Discounts)
+	assert.Equal(t, usd(50000), res.Total)
+	assert.Nil(t, res.Coupon)
+}
+
+func TestAPI_Region(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	taxes := mockTaxes{
+		rates: []entity.TaxRate{
+			{Region: "ny", Category: "standard", Rate: 825, Name: "Sales tax"},
+			{Region: "de", Category: "standard", Rate: 1900, Name: "VAT"},
+		},
+		settings: tax.Settings{Region: "ny", Rounding: tax.RoundPerLine},
+	}
+	engine := newServiceTestEngine(NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &taxes, &mockShipping{}, logger), sessionID)
+
+	w := serveAPI(engine, "GET", APIPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res cartResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "ny", res.Region)
+	assert.Equal(t, usd(50000), res.Net)
+	assert.Equal(t, usd(4125), res.Tax)
+	assert.Equal(t, usd(54125), res.Total)
+	assert.Equal(t, []taxLineResponse{{Name: "Sales tax", Category: "standard", Rate: "8.25", Taxable: usd(50000), Tax: usd(4125)}}, res.TaxLines)
+
+	w = serveAPI(engine, "PUT", APIPath+"/region", `{"region":"fr"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"code":"bad_request","message":"unknown tax region"}}`, w.Body.String())
+	w = serveAPI(engine, "PUT", APIPath+"/region", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "PUT", APIPath+"/region", `{"region":"de"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = cartResponse{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "de", res.Region)
+	assert.Equal(t, usd(9500), res.Tax)
+	assert.Equal(t, usd(59500), res.Total)
+
+	w = serveAPI(engine, "PUT", APIPath+"/region", `{"region":""}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = cartResponse{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "ny", res.Region)
+}
+
+func TestAPI_Shipping(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	productRepo.products[0].Weight = 500
+	shippingRates := getMockedShipping()
+	engine := newServiceTestEngine(NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &shippingRates, logger), sessionID)
+
+	w := serveAPI(engine, "POST", APIPath+"/checkout", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":{"status":409,"code":"conflict","message":"choose a shipping method"}}`, w.Body.String())
+
+	w = serveAPI(engine, "PUT", APIPath+"/shipping", `{"country":"us"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"code":"bad_request","message":"we do not ship to this country"}}`, w.Body.String())
+	w = serveAPI(engine, "PUT", APIPath+"/shipping", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "PUT", APIPath+"/shipping", `{"country":"de","method":"parcel"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res cartResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, &shippingResponse{
+		Country: "DE",
+		Method:  "parcel",
+		Price:   usd(900),
+		Options: []shippingQuoteResponse{
+			{Method: "parcel", Name: "Parcel", Weight: 1500, Price: usd(900)},
+			{Method: "standard", Name: "Standard", Weight: 1500, Price: usd(1200)},
+		},
+	}, res.Shipping)
+	assert.Equal(t, usd(50900), res.Total)
+
+	w = serveAPI(engine, "POST", APIPath+"/checkout", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var placed orderResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &placed))
+	assert.Equal(t, "DE", placed.ShippingCountry)
+	assert.Equal(t, "parcel", placed.ShippingMethod)
+	assert.Equal(t, usd(900), placed.Shipping)
+	assert.Equal(t, usd(50900), placed.Total)
+}
+
+func TestAPI_DeleteItem(t *testing.T) {
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	engine := newAPITestEngine(&repo, &productRepo, sessionID)
+
+	w := serveAPI(engine, "DELETE", APIPath+"/items/abc", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "DELETE", APIPath+"/items/3", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveAPI(engine, "DELETE", APIPath+"/items/1", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestAPI_Checkout(t *testing.T) {
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	engine := newAPITestEngine(&repo, &productRepo, sessionID)
+
+	w := serveAPI(engine, "POST", APIPath+"/checkout", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var res orderResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, usd(50000), res.Total)
+	assert.Equal(t, 2, len(res.Lines))
+
+	w = serveAPI(engine, "POST", APIPath+"/checkout", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	repo.items = nil
+	repo.cards[1].SessionID = "empty"
+	engine = newAPITestEngine(&repo, &productRepo, "empty")
+	w = serveAPI(engine, "POST", APIPath+"/checkout", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAPI_UpdateItem(t *testing.T) {
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	engine := newAPITestEngine(&repo, &productRepo, sessionID)
+
+	w := serveAPI(engine, "PATCH", APIPath+"/items/1", `{"quantity":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res cartResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, usd(30000), res.Total)
+	assert.Equal(t, 1, res.Items[0].Quantity)
+
+	w = serveAPI(engine, "PATCH", APIPath+"/items/1", `{"quantity":-1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "PATCH", APIPath+"/items/1", `{"quantity":100}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "PATCH", APIPath+"/items/1", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAPI(engine, "PATCH", APIPath+"/items/3", `{"quantity":2}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serveAPI(engine, "PATCH", APIPath+"/items/1", `{"quantity":0}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, usd(20000), res.Total)
+	assert.Equal(t, 1, len(res.Items))
+}
+
+func TestAPI_Orders(t *testing.T) {
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	engine := newAPITestEngine(&repo, &productRepo, sessionID)
+	w := serveAPI(engine, "POST", APIPath+"/checkout", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = serveAPI(engine, "GET", APIPath+"/orders?status=placed&per_page=10", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page orderPageResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, 10, page.PerPage)
+	assert.Equal(t, uint(1), page.Orders[0].ID)
+	assert.Equal(t, usd(50000), page.Orders[0].Total)
+
+	w = serveAPI(engine, "GET", APIPath+"/orders?to=2000-01-01", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, int64(0), page.Total)
+	assert.Equal(t, []orderSummaryResponse{}, page.Orders)
+
+	w = serveAPI(engine, "GET", APIPath+"/orders/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res orderResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 2, len(res.Lines))
+
+	for _, query := range []string{"page=first", "page=0", "page=10001", "page=9223372036854775807", "per_page=101",
+		"per_page=-1", "from=yesterday", "status=lost", "from=2024-03-02&to=2024-03-01"} {
+		w = serveAPI(engine, "GET", APIPath+"/orders?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	w = serveAPI(engine, "GET", APIPath+"/orders/2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
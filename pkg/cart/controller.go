@@ -3,9 +3,11 @@ package cart
 import (
 	"errors"
 	"fmt"
-	"interview/internal/utils"
+	"interview/pkg/entity"
 	"interview/pkg/log"
+	"interview/pkg/session"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -16,7 +18,15 @@ func RegisterHandlers(r *gin.RouterGroup, service Service, logger log.Logger) {
 
 	r.GET("/", res.showAddItemForm())
 	r.POST("/add", res.addItem())
-	r.GET("/remove", res.deleteItem())
+	r.POST("/update", res.updateItem())
+	r.POST("/remove", res.deleteItem())
+	r.POST("/coupon", res.applyCoupon())
+	r.POST("/coupon/remove", res.removeCoupon())
+	r.POST("/region", res.setRegion())
+	r.POST("/shipping", res.setShipping())
+	r.POST("/checkout", res.checkout())
+	r.GET("/orders", res.showOrders())
+	r.GET("/orders/:id", res.showOrder())
 }
 
 type resource struct {
@@ -27,14 +37,20 @@ type resource struct {
 func (r *resource) showAddItemForm() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
+		sess := session.FromContext(ctx)
 		data := map[string]interface{}{
-			"Error":     c.Query("error"),
+			"Errors":    sess.PopFlashes(),
+			"LoggedIn":  sess.UserID != 0,
 			"CartItems": r.service.GetCartItems(ctx),
-			"Products":  r.service.GetProducts(),
+			"Products":  r.service.GetProducts(ctx),
+			"Regions":   r.service.GetRegions(ctx),
 		}
-		html, err := utils.RenderTemplate(data, "add_item_form.html")
+		if cart, err := r.service.GetCart(ctx); err == nil {
+			data["Cart"] = cart
+		}
+		html, err := renderTemplate(ctx, data, "add_item_form.html")
 		if err != nil {
-			r.logger.Errorf("Failed to render cart template: %s", err)
+			r.logger.With(c.Request.Context()).Errorf("Failed to render cart template: %s", err)
 			c.AbortWithStatus(500)
 			return
 		}
@@ -53,17 +69,50 @@ func (r *resource) addItem() gin.HandlerFunc {
 		ctx := c.Request.Context()
 		addItemForm, err := r.getCartItemForm(c)
 		if err != nil {
-			c.Redirect(302, CartPath+"?error="+err.Error())
+			r.redirectWithError(c, err)
 			return
 		}
 		quantity, err := strconv.ParseInt(addItemForm.Quantity, 10, 0)
 		if err != nil {
-			c.Redirect(302, CartPath+"?error="+errors.New("quantity must be a number").Error())
+			r.redirectWithError(c, errors.New("quantity must be a number"))
 			return
 		}
 		err = r.service.AddItemToCart(ctx, addItemForm.Product, int(quantity))
 		if err != nil {
-			c.Redirect(302, CartPath+"?error="+err.Error())
+			r.redirectWithError(c, err)
+			return
+		}
+		c.Redirect(302, CartPath)
+	}
+}
+
+type updateItemForm struct {
+	CartItemID string `form:"cart_item_id" binding:"required"`
+	Quantity   string `form:"quantity"     binding:"required"`
+}
+
+func (r *resource) updateItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		form := &updateItemForm{}
+		if err := binding.FormPost.Bind(c.Request, form); err != nil {
+			r.logger.With(c.Request.Context()).Errorf("Error in binding processing update form data: %s", err)
+			r.redirectWithError(c, err)
+			return
+		}
+		cartItemID, err := strconv.Atoi(form.CartItemID)
+		if err != nil {
+			r.redirectWithError(c, errors.New("cart item id must be a number"))
+			return
+		}
+		quantity, err := strconv.ParseInt(form.Quantity, 10, 0)
+		if err != nil {
+			r.redirectWithError(c, errors.New("quantity must be a number"))
+			return
+		}
+		err = r.service.UpdateCartItemQuantity(ctx, uint(cartItemID), int(quantity))
+		if err != nil {
+			r.redirectWithError(c, err)
 			return
 		}
 		c.Redirect(302, CartPath)
@@ -73,21 +122,228 @@ func (r *resource) addItem() gin.HandlerFunc {
 func (r *resource) deleteItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		cartItemIDString := c.Query("cart_item_id")
+		cartItemIDString := c.PostForm("cart_item_id")
 		cartItemID, err := strconv.Atoi(cartItemIDString)
 		if err != nil {
-			c.Redirect(302, CartPath+"?error="+errors.New("cart item id must be a number").Error())
+			r.redirectWithError(c, errors.New("cart item id must be a number"))
 			return
 		}
 		err = r.service.DeleteCartItem(ctx, uint(cartItemID))
 		if err != nil {
-			c.Redirect(302, CartPath+"?error="+err.Error())
+			r.redirectWithError(c, err)
+			return
+		}
+		c.Redirect(302, CartPath)
+	}
+}
+
+type couponForm struct {
+	Code string `form:"code" binding:"required"`
+}
+
+func (r *resource) applyCoupon() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var form couponForm
+		if err := c.ShouldBind(&form); err != nil {
+			r.redirectWithError(c, errors.New("enter a coupon code"))
+			return
+		}
+		if err := r.service.ApplyCoupon(c.Request.Context(), form.Code); err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		c.Redirect(302, CartPath)
+	}
+}
+
+func (r *resource) removeCoupon() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := r.service.RemoveCoupon(c.Request.Context()); err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		c.Redirect(302, CartPath)
+	}
+}
+
+type regionForm struct {
+	Region string `form:"region"`
+}
+
+func (r *resource) setRegion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var form regionForm
+		if err := c.ShouldBind(&form); err != nil {
+			r.redirectWithError(c, errors.New("choose a region"))
+			return
+		}
+		if err := r.service.SetRegion(c.Request.Context(), form.Region); err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		c.Redirect(302, CartPath)
+	}
+}
+
+type shippingForm struct {
+	Country string `form:"country"`
+	Method  string `form:"method"`
+}
+
+func (r *resource) setShipping() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var form shippingForm
+		if err := c.ShouldBind(&form); err != nil {
+			r.redirectWithError(c, errors.New("enter a country"))
+			return
+		}
+		if err := r.service.SetShipping(c.Request.Context(), form.Country, form.Method); err != nil {
+			r.redirectWithError(c, err)
 			return
 		}
 		c.Redirect(302, CartPath)
 	}
 }
 
+func (r *resource) checkout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		placed, err := r.service.Checkout(ctx)
+		if err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		c.Redirect(302, fmt.Sprintf("%s/orders/%d", CartPath, placed.ID))
+	}
+}
+
+func (r *resource) showOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		orderID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			r.redirectWithError(c, errors.New("order id must be a number"))
+			return
+		}
+		placed, err := r.service.GetOrder(ctx, uint(orderID))
+		if err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		html, err := renderTemplate(ctx, placed, "order_confirmation.html")
+		if err != nil {
+			r.logger.With(c.Request.Context()).Errorf("Failed to render order template: %s", err)
+			c.AbortWithStatus(500)
+			return
+		}
+		c.Header("Content-Type", "text/html")
+		c.String(200, html)
+	}
+}
+
+func (r *resource) showOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		query, err := parseOrderQuery(c)
+		if err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		page, err := r.service.GetOrders(ctx, query)
+		if err != nil {
+			r.redirectWithError(c, err)
+			return
+		}
+		data := map[string]interface{}{
+			"Page":     page,
+			"Status":   string(query.Status),
+			"Statuses": entity.OrderStatuses,
+			"From":     c.Query("from"),
+			"To":       c.Query("to"),
+		}
+		if page.HasPrevious() {
+			data["PreviousURL"] = pageURL(c, page.Page-1)
+		}
+		if page.HasNext() {
+			data["NextURL"] = pageURL(c, page.Page+1)
+		}
+		html, err := renderTemplate(ctx, data, "order_history.html")
+		if err != nil {
+			r.logger.With(c.Request.Context()).Errorf("Failed to render order history template: %s", err)
+			c.AbortWithStatus(500)
+			return
+		}
+		c.Header("Content-Type", "text/html")
+		c.String(200, html)
+	}
+}
+
+// pageURL returns the URL of the request with the page parameter replaced.
+func pageURL(c *gin.Context, page int) string {
+	params := c.Request.URL.Query()
+	params.Set("page", strconv.Itoa(page))
+	return c.Request.URL.Path + "?" + params.Encode()
+}
+
+var InvalidPageError = errors.New("page and per_page must be numbers")
+var InvalidDateError = errors.New("from and to must be dates such as 2024-03-01")
+
+// parseOrderQuery reads an order history query from the page, per_page, status, from and to parameters of the URL.
+// from and to are dates or RFC 3339 times; the orders of the whole day given in to are included.
+func parseOrderQuery(c *gin.Context) (OrderQuery, error) {
+	query := OrderQuery{Status: entity.OrderStatus(c.Query("status"))}
+	var err error
+	if query.Page, err = parsePageParam(c.Query("page")); err != nil {
+		return OrderQuery{}, err
+	}
+	if query.PerPage, err = parsePageParam(c.Query("per_page")); err != nil {
+		return OrderQuery{}, err
+	}
+	if query.From, err = parseOptionalTime(c.Query("from"), false); err != nil {
+		return OrderQuery{}, InvalidDateError
+	}
+	if query.To, err = parseOptionalTime(c.Query("to"), true); err != nil {
+		return OrderQuery{}, InvalidDateError
+	}
+	return query, nil
+}
+
+// parsePageParam parses the page or per_page parameter, which is 0 when it is not given.
+func parsePageParam(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, InvalidPageError
+	}
+	if n < 1 {
+		return 0, PageOutOfRangeError
+	}
+	return n, nil
+}
+
+// parseOptionalTime parses a date or an RFC 3339 time. A date stands for the start of the day in UTC,
+// or the start of the next day if endOfDay is set.
+func parseOptionalTime(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// redirectWithError sends the user back to the cart page, which shows the error as a flash message.
+func (r *resource) redirectWithError(c *gin.Context, err error) {
+	session.FromContext(c.Request.Context()).AddFlash(err.Error())
+	c.Redirect(302, CartPath)
+}
+
 func (r *resource) getCartItemForm(c *gin.Context) (*cartItemForm, error) {
 	if c.Request.Body == nil {
 		return nil, fmt.Errorf("body cannot be nil")
@@ -96,7 +352,7 @@ func (r *resource) getCartItemForm(c *gin.Context) (*cartItemForm, error) {
 	form := &cartItemForm{}
 
 	if err := binding.FormPost.Bind(c.Request, form); err != nil {
-		r.logger.Errorf("Error in binding processing cart form data: %s", err)
+		r.logger.With(c.Request.Context()).Errorf("Error in binding processing cart form data: %s", err)
 		return nil, err
 	}
 
@@ -0,0 +1,107 @@
+package cart
+
+import (
+	"context"
+	"errors"
+
+	"interview/pkg/entity"
+	"interview/pkg/promotion"
+)
+
+// ApplyCoupon applies the coupon with the given code to the open cart, replacing the coupon applied before.
+// A coupon that gives no discount on the cart is refused with the reason, e.g. promotion.CouponExpiredError.
+func (s service) ApplyCoupon(ctx context.Context, code string) error {
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, cartItems, err := s.getCartWithItems(ctx)
+		if err != nil {
+			return err
+		}
+		if len(cartItems) == 0 {
+			return EmptyCartError
+		}
+		discount, err := s.promotions.Evaluate(ctx, code, cartItems)
+		if errors.Is(err, promotion.InternalError) {
+			return InternalError
+		}
+		if err != nil {
+			return err
+		}
+		cartEntity.CouponCode = discount.Code
+		if err := s.repo.UpdateCart(ctx, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error applying coupon: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+// RemoveCoupon removes the coupon from the open cart.
+func (s service) RemoveCoupon(ctx context.Context) error {
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, err := s.getCart(ctx)
+		if errors.Is(err, CartNotFoundError) {
+			return err
+		}
+		if err != nil {
+			s.logger.With(ctx).Errorf("error getting cart: %v", err)
+			return InternalError
+		}
+		if cartEntity.CouponCode == "" {
+			return nil
+		}
+		cartEntity.CouponCode = ""
+		if err := s.repo.UpdateCart(ctx, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error removing coupon: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+// getCartWithItems returns the open cart of the session and all of its items.
+func (s service) getCartWithItems(ctx context.Context) (entity.CartEntity, []entity.CartItem, error) {
+	cartEntity, err := s.getCart(ctx)
+	if errors.Is(err, CartNotFoundError) {
+		return entity.CartEntity{}, nil, err
+	}
+	if err != nil {
+		s.logger.With(ctx).Errorf("error getting cart: %v", err)
+		return entity.CartEntity{}, nil, InternalError
+	}
+	cartItems, err := s.repo.QueryCartItem(ctx, map[string]interface{}{"cart_id": cartEntity.ID}, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying cart items: %v", err)
+		return entity.CartEntity{}, nil, InternalError
+	}
+	return cartEntity, cartItems, nil
+}
+
+// evaluateCoupon sets the discounts of the cart from its coupon. A coupon that no longer applies, e.g. because
+// items were removed, stays on the cart without a discount, and CouponError tells why.
+func (s service) evaluateCoupon(ctx context.Context, cart *Cart) error {
+	if cart.CouponCode == "" {
+		return nil
+	}
+	discount, err := s.promotions.Evaluate(ctx, cart.CouponCode, cart.Items)
+	if errors.Is(err, promotion.InternalError) {
+		return InternalError
+	}
+	if err != nil {
+		cart.CouponError = err
+		return nil
+	}
+	cart.Discounts = []promotion.Discount{discount}
+	return nil
+}
+
+// redeemCoupon counts the use of the coupon by an order.
+func (s service) redeemCoupon(ctx context.Context, code string) error {
+	err := s.promotions.Redeem(ctx, code)
+	if errors.Is(err, promotion.CouponUsedUpError) {
+		return err
+	}
+	if err != nil {
+		return InternalError
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cart
+
+import (
+	"context"
+	"time"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+const expireBatchSize = 100
+
+// ExpireCarts expires the open carts that have not been changed since the cutoff and releases the stock reserved
+// for their items. Each cart is checked and expired in its own transaction. The IDs of the expired carts are
+// returned in ID order.
+func ExpireCarts(ctx context.Context, repo Repository, inventory Inventory, cutoff time.Time, logger log.Logger) ([]uint, error) {
+	var expired []uint
+	for offset := 0; ; {
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{"status": entity.CartOpen}, "id asc", expireBatchSize, offset)
+		if err != nil {
+			return expired, err
+		}
+		// expired carts are no longer open, so the next batch starts after the carts that were kept
+		offset += len(carts)
+		for _, cartEntity := range carts {
+			if !cartEntity.UpdatedAt.Before(cutoff) {
+				continue
+			}
+			done := false
+			err := repo.Transactional(ctx, func(ctx context.Context) error {
+				// the cart may have changed since the batch was read
+				current, err := repo.QueryCart(ctx, map[string]interface{}{"id": cartEntity.ID, "status": entity.CartOpen}, "", 1, 0)
+				if err != nil || len(current) == 0 || !current[0].UpdatedAt.Before(cutoff) {
+					return err
+				}
+				cartItems, err := repo.QueryCartItem(ctx, map[string]interface{}{"cart_id": cartEntity.ID}, "id asc", -1, -1)
+				if err != nil {
+					return err
+				}
+				for _, cartItem := range cartItems {
+					if err := inventory.Release(ctx, cartItem.ProductID, cartItem.Quantity); err != nil {
+						return err
+					}
+				}
+				current[0].Status = entity.CartExpired
+				done = true
+				return repo.UpdateCart(ctx, &current[0])
+			})
+			if err != nil {
+				return expired, err
+			}
+			if done {
+				logger.With(ctx).Infof("expired cart %d of session %s", cartEntity.ID, cartEntity.SessionID)
+				expired = append(expired, cartEntity.ID)
+				offset--
+			}
+		}
+		if len(carts) < expireBatchSize {
+			return expired, nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cart
+
+import (
+	"context"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestExpireCarts(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := NewMemoryRepository()
+	stock := mockInventory{}
+	ctx := context.Background()
+
+	cutoff := time.Now().Add(-time.Hour)
+	stale := gorm.Model{UpdatedAt: cutoff.Add(-time.Minute)}
+	abandoned := entity.CartEntity{Model: stale, SessionID: "a", Status: entity.CartOpen}
+	active := entity.CartEntity{SessionID: "b", Status: entity.CartOpen}
+	closed := entity.CartEntity{Model: stale, SessionID: "c", Status: entity.CartClosed}
+	emptied := entity.CartEntity{Model: stale, SessionID: "d", Status: entity.CartOpen}
+	for _, c := range []*entity.CartEntity{&abandoned, &active, &closed, &emptied} {
+		require.Nil(t, repo.CreateCart(ctx, c))
+	}
+	items := []entity.CartItem{
+		{CartID: abandoned.ID, ProductID: 1, ProductName: "shoe", Quantity: 3, Price: usd(30000)},
+		{CartID: abandoned.ID, ProductID: 2, ProductName: "purse", Quantity: 1, Price: usd(20000)},
+		{CartID: active.ID, ProductID: 1, ProductName: "shoe", Quantity: 2, Price: usd(20000)},
+		{CartID: closed.ID, ProductID: 3, ProductName: "bag", Quantity: 1, Price: usd(30000)},
+	}
+	for i := range items {
+		require.Nil(t, repo.CreateCartItem(ctx, &items[i]))
+	}
+
+	expired, err := ExpireCarts(ctx, repo, &stock, cutoff, logger)
+	require.Nil(t, err)
+	assert.Equal(t, []uint{abandoned.ID, emptied.ID}, expired)
+	assert.Equal(t, map[uint]int{1: -3, 2: -1}, stock.reserved)
+
+	carts, err := repo.QueryCart(ctx, map[string]interface{}{}, "id asc", -1, -1)
+	require.Nil(t, err)
+	assert.Equal(t, entity.CartExpired, carts[0].Status)
+	assert.Equal(t, entity.CartOpen, carts[1].Status)
+	assert.Equal(t, entity.CartClosed, carts[2].Status)
+	assert.Equal(t, entity.CartExpired, carts[3].Status)
+
+	expired, err = ExpireCarts(ctx, repo, &stock, cutoff, logger)
+	require.Nil(t, err)
+	assert.Empty(t, expired)
+}
@@ -0,0 +1,374 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"interview/pkg/entity"
+)
+
+type memoryRepository struct {
+	// mu guards the tables; txMu serializes transactions.
+	mu    *sync.Mutex
+	txMu  *sync.Mutex
+	carts *memoryTable[entity.CartEntity]
+	items *memoryTable[entity.CartItem]
+}
+
+type memoryTxKey struct{}
+
+// NewMemoryRepository returns a Repository that keeps carts in memory.
+// It follows the semantics of the GORM repository: conditions and order use column names,
+// deletes are soft deletes and a failed transaction leaves no changes behind.
+func NewMemoryRepository() Repository {
+	return memoryRepository{
+		mu:    &sync.Mutex{},
+		txMu:  &sync.Mutex{},
+		carts: newMemoryTable[entity.CartEntity](),
+		items: newMemoryTable[entity.CartItem](),
+	}
+}
+
+func (r memoryRepository) QueryCart(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.CartEntity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.carts.query(conditions, order, limit, offset)
+}
+
+func (r memoryRepository) QueryCartItem(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.CartItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items.query(conditions, order, limit, offset)
+}
+
+func (r memoryRepository) CountCart(ctx context.Context, conditions map[string]interface{}) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	carts, err := r.carts.query(conditions, "", -1, -1)
+	return int64(len(carts)), err
+}
+
+func (r memoryRepository) CreateCart(ctx context.Context, cartEntity *entity.CartEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.carts.create(cartEntity)
+}
+
+func (r memoryRepository) CreateCartItem(ctx context.Context, cartItem *entity.CartItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items.create(cartItem)
+}
+
+func (r memoryRepository) UpdateCart(ctx context.Context, cartEntity *entity.CartEntity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.carts.save(cartEntity)
+}
+
+func (r memoryRepository) UpdateCartItem(ctx context.Context, cartItem *entity.CartItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items.save(cartItem)
+}
+
+func (r memoryRepository) UpdateCartStatus(ctx context.Context, id uint, from entity.Status, to entity.Status) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	carts, err := r.carts.query(map[string]interface{}{"id": id, "status": from}, "", 1, 0)
+	if err != nil || len(carts) == 0 {
+		return false, err
+	}
+	carts[0].Status = to
+	return true, r.carts.save(&carts[0])
+}
+
+func (r memoryRepository) DeleteCartById(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.carts.delete(map[string]interface{}{"id": id})
+}
+
+func (r memoryRepository) DeleteCartItemById(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items.delete(map[string]interface{}{"id": id})
+}
+
+func (r memoryRepository) DeleteCart(ctx context.Context, conditions map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.carts.delete(conditions)
+}
+
+func (r memoryRepository) DeleteCartItem(ctx context.Context, conditions map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items.delete(conditions)
+}
+
+// Transactional runs f and restores the tables to their previous state if it returns an error.
+// Nested calls join the outer transaction, and run as a unit that is rolled back on its own failure.
+func (r memoryRepository) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	if ctx.Value(memoryTxKey{}) == nil {
+		r.txMu.Lock()
+		defer r.txMu.Unlock()
+		ctx = context.WithValue(ctx, memoryTxKey{}, true)
+	}
+
+	r.mu.Lock()
+	carts, items := r.carts.snapshot(), r.items.snapshot()
+	r.mu.Unlock()
+
+	if err := f(ctx); err != nil {
+		r.mu.Lock()
+		r.carts.restore(carts)
+		r.items.restore(items)
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// memoryTable stores rows of a GORM model and evaluates queries on them by column name.
+type memoryTable[T any] struct {
+	schema *schema.Schema
+	rows   []T
+	nextID uint
+}
+
+type memoryTableState[T any] struct {
+	rows   []T
+	nextID uint
+}
+
+func newMemoryTable[T any]() *memoryTable[T] {
+	s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(err)
+	}
+	return &memoryTable[T]{schema: s, nextID: 1}
+}
+
+func (t *memoryTable[T]) snapshot() memoryTableState[T] {
+	return memoryTableState[T]{append([]T(nil), t.rows...), t.nextID}
+}
+
+func (t *memoryTable[T]) restore(state memoryTableState[T]) {
+	t.rows = state.rows
+	t.nextID = state.nextID
+}
+
+func (t *memoryTable[T]) query(conditions map[string]interface{}, order string, limit int, offset int) ([]T, error) {
+	var rows []T
+	for _, row := range t.rows {
+		ok, err := t.matches(row, conditions)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+	if err := t.sort(rows, order); err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if offset > len(rows) {
+			offset = len(rows)
+		}
+		rows = rows[offset:]
+	}
+	if limit >= 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+func (t *memoryTable[T]) create(row *T) error {
+	rv := reflect.ValueOf(row).Elem()
+	id := t.id(rv)
+	if id == 0 {
+		id = t.nextID
+		t.setField(rv, "id", id)
+	} else if t.indexOf(id) >= 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	if id >= t.nextID {
+		t.nextID = id + 1
+	}
+	now := time.Now()
+	if t.field(rv, "created_at").Interface().(time.Time).IsZero() {
+		t.setField(rv, "created_at", now)
+	}
+	if t.field(rv, "updated_at").Interface().(time.Time).IsZero() {
+		t.setField(rv, "updated_at", now)
+	}
+	t.rows = append(t.rows, *row)
+	return nil
+}
+
+func (t *memoryTable[T]) save(row *T) error {
+	rv := reflect.ValueOf(row).Elem()
+	i := t.indexOf(t.id(rv))
+	if i < 0 {
+		return t.create(row)
+	}
+	t.setField(rv, "updated_at", time.Now())
+	t.rows[i] = *row
+	return nil
+}
+
+func (t *memoryTable[T]) delete(conditions map[string]interface{}) error {
+	if len(conditions) == 0 {
+		return gorm.ErrMissingWhereClause
+	}
+	now := time.Now()
+	for i := range t.rows {
+		ok, err := t.matches(t.rows[i], conditions)
+		if err != nil {
+			return err
+		}
+		if ok {
+			t.setField(reflect.ValueOf(&t.rows[i]).Elem(), "deleted_at", gorm.DeletedAt{Time: now, Valid: true})
+		}
+	}
+	return nil
+}
+
+// indexOf returns the position of the row with the given ID that has not been deleted, or -1.
+func (t *memoryTable[T]) indexOf(id uint) int {
+	for i := range t.rows {
+		rv := reflect.ValueOf(&t.rows[i]).Elem()
+		if t.id(rv) == id && !t.deleted(rv) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *memoryTable[T]) matches(row T, conditions map[string]interface{}) (bool, error) {
+	rv := reflect.ValueOf(&row).Elem()
+	if t.deleted(rv) {
+		return false, nil
+	}
+	for column, expected := range conditions {
+		field := t.schema.LookUpField(column)
+		if field == nil {
+			return false, fmt.Errorf("unknown column %q in table %s", column, t.schema.Table)
+		}
+		if !valueMatches(field.ReflectValueOf(context.Background(), rv), reflect.ValueOf(expected)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// valueMatches compares a column value with a condition value; slices are treated as IN lists.
+func valueMatches(actual reflect.Value, expected reflect.Value) bool {
+	if expected.Kind() == reflect.Slice && expected.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < expected.Len(); i++ {
+			if valueMatches(actual, expected.Index(i)) {
+				return true
+			}
+		}
+		return false
+	}
+	if !expected.IsValid() {
+		return actual.IsZero()
+	}
+	if !expected.Type().ConvertibleTo(actual.Type()) {
+		return false
+	}
+	return reflect.DeepEqual(actual.Interface(), expected.Convert(actual.Type()).Interface())
+}
+
+func (t *memoryTable[T]) sort(rows []T, order string) error {
+	type orderBy struct {
+		field *schema.Field
+		desc  bool
+	}
+	var orders []orderBy
+	for _, part := range strings.Split(order, ",") {
+		tokens := strings.Fields(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		field := t.schema.LookUpField(tokens[0])
+		if field == nil {
+			return fmt.Errorf("unknown column %q in table %s", tokens[0], t.schema.Table)
+		}
+		orders = append(orders, orderBy{field, len(tokens) > 1 && strings.EqualFold(tokens[1], "desc")})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		vi, vj := reflect.ValueOf(&rows[i]).Elem(), reflect.ValueOf(&rows[j]).Elem()
+		for _, o := range orders {
+			c := compareValues(o.field.ReflectValueOf(context.Background(), vi), o.field.ReflectValueOf(context.Background(), vj))
+			if c != 0 {
+				return (c < 0) != o.desc
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func compareValues(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float())
+	case reflect.String:
+		return compareOrdered(a.String(), b.String())
+	case reflect.Bool:
+		return compareOrdered(boolToInt(a.Bool()), boolToInt(b.Bool()))
+	}
+	if ta, ok := a.Interface().(time.Time); ok {
+		return ta.Compare(b.Interface().(time.Time))
+	}
+	return 0
+}
+
+func compareOrdered[V int64 | uint64 | float64 | string | int](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (t *memoryTable[T]) field(rv reflect.Value, column string) reflect.Value {
+	return t.schema.LookUpField(column).ReflectValueOf(context.Background(), rv)
+}
+
+func (t *memoryTable[T]) setField(rv reflect.Value, column string, value interface{}) {
+	t.field(rv, column).Set(reflect.ValueOf(value))
+}
+
+func (t *memoryTable[T]) id(rv reflect.Value) uint {
+	return uint(t.field(rv, "id").Uint())
+}
+
+func (t *memoryTable[T]) deleted(rv reflect.Value) bool {
+	return t.field(rv, "deleted_at").Interface().(gorm.DeletedAt).Valid
+}
@@ -0,0 +1,82 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/order"
+)
+
+const (
+	outcomeSuccess       = "success"
+	outcomeRejected      = "rejected"
+	outcomeInternalError = "internal_error"
+)
+
+type instrumentedService struct {
+	Service
+	operations *prometheus.CounterVec
+}
+
+// NewInstrumentedService returns a Service that counts the cart operations of the given service by outcome:
+// success, rejected for errors caused by the request, and internal_error for InternalError.
+// It also reports the number of open carts in the repository whenever the metrics are collected.
+func NewInstrumentedService(service Service, repo Repository, reg prometheus.Registerer, logger log.Logger) Service {
+	operations := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cart_operations_total",
+		Help: "Number of cart operations by operation and outcome.",
+	}, []string{"operation", "outcome"})
+	openCarts := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cart_open_carts",
+		Help: "Number of open carts.",
+	}, func() float64 {
+		count, err := repo.CountCart(context.Background(), map[string]interface{}{"status": entity.CartOpen})
+		if err != nil {
+			logger.Errorf("error counting open carts: %v", err)
+			return math.NaN()
+		}
+		return float64(count)
+	})
+	reg.MustRegister(operations, openCarts)
+	return instrumentedService{service, operations}
+}
+
+func (s instrumentedService) AddItemToCart(ctx context.Context, product string, qty int) error {
+	err := s.Service.AddItemToCart(ctx, product, qty)
+	s.observe("add", err)
+	return err
+}
+
+func (s instrumentedService) UpdateCartItemQuantity(ctx context.Context, cartItemID uint, qty int) error {
+	err := s.Service.UpdateCartItemQuantity(ctx, cartItemID, qty)
+	s.observe("update", err)
+	return err
+}
+
+func (s instrumentedService) DeleteCartItem(ctx context.Context, cartItemID uint) error {
+	err := s.Service.DeleteCartItem(ctx, cartItemID)
+	s.observe("remove", err)
+	return err
+}
+
+func (s instrumentedService) Checkout(ctx context.Context) (order.Order, error) {
+	placed, err := s.Service.Checkout(ctx)
+	s.observe("checkout", err)
+	return placed, err
+}
+
+func (s instrumentedService) observe(operation string, err error) {
+	outcome := outcomeSuccess
+	switch {
+	case errors.Is(err, InternalError):
+		outcome = outcomeInternalError
+	case err != nil:
+		outcome = outcomeRejected
+	}
+	s.operations.WithLabelValues(operation, outcome).Inc()
+}
@@ -0,0 +1,42 @@
+package cart
+
+import (
+	"context"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedService(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	reg := prometheus.NewRegistry()
+	service := NewInstrumentedService(NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger), &repo, reg, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Nil(t, service.AddItemToCart(ctx, "watch", 1))
+	assert.Equal(t, InvalidProductError, service.AddItemToCart(ctx, "hat", 1))
+	assert.Nil(t, service.UpdateCartItemQuantity(ctx, 1, 2))
+	assert.Nil(t, service.DeleteCartItem(ctx, 2))
+	_, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+
+	assert.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP cart_open_carts Number of open carts.
+# TYPE cart_open_carts gauge
+cart_open_carts 1
+# HELP cart_operations_total Number of cart operations by operation and outcome.
+# TYPE cart_operations_total counter
+cart_operations_total{operation="add",outcome="rejected"} 1
+cart_operations_total{operation="add",outcome="success"} 1
+cart_operations_total{operation="checkout",outcome="success"} 1
+cart_operations_total{operation="remove",outcome="success"} 1
+cart_operations_total{operation="update",outcome="success"} 1
+`)))
+}
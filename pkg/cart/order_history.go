@@ -0,0 +1,94 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interview/pkg/entity"
+	"interview/pkg/order"
+	"interview/pkg/session"
+	"time"
+)
+
+const (
+	// DefaultOrdersPerPage is the page size of the order history when none is requested.
+	DefaultOrdersPerPage = 20
+	// MaxOrdersPerPage is the largest page size of the order history.
+	MaxOrdersPerPage = 100
+	// MaxOrderPage is the largest page number of the order history, which keeps the offset of a page in range.
+	MaxOrderPage = 10000
+)
+
+var InvalidOrderStatusError = errors.New("unknown order status")
+var InvalidPeriodError = errors.New("the start of the period must be before its end")
+var PageOutOfRangeError = fmt.Errorf("page must be between 1 and %d and per_page between 1 and %d", MaxOrderPage, MaxOrdersPerPage)
+
+// OrderQuery selects a page of the order history of the session, or of its user when one is logged in.
+type OrderQuery struct {
+	// Status selects the orders with one of entity.OrderStatuses; empty selects all orders.
+	Status entity.OrderStatus
+	// From and To select the orders placed from From until before To. A zero time leaves that side open.
+	From time.Time
+	To   time.Time
+	// Page is the number of the page starting at 1, up to MaxOrderPage, and PerPage its size, up to
+	// MaxOrdersPerPage. They default to the first page of DefaultOrdersPerPage orders when zero.
+	Page    int
+	PerPage int
+}
+
+// OrderPage is a page of the order history, most recent order first.
+type OrderPage struct {
+	Orders  []entity.Order
+	Page    int
+	PerPage int
+	// Total is the number of orders selected by the query on all pages.
+	Total int64
+}
+
+// HasPrevious tells whether there is a page before this one.
+func (p OrderPage) HasPrevious() bool {
+	return p.Page > 1
+}
+
+// HasNext tells whether there is a page after this one.
+func (p OrderPage) HasNext() bool {
+	return int64(p.Page*p.PerPage) < p.Total
+}
+
+// GetOrders returns a page of the orders placed by the user of the current session,
+// or by the session itself when no user is logged in.
+func (s service) GetOrders(ctx context.Context, query OrderQuery) (OrderPage, error) {
+	if query.Status != "" && !query.Status.Valid() {
+		return OrderPage{}, InvalidOrderStatusError
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return OrderPage{}, InvalidPeriodError
+	}
+	// checked before the offset is computed from them, so that it cannot overflow
+	if query.Page < 0 || query.Page > MaxOrderPage || query.PerPage < 0 || query.PerPage > MaxOrdersPerPage {
+		return OrderPage{}, PageOutOfRangeError
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PerPage == 0 {
+		query.PerPage = DefaultOrdersPerPage
+	}
+
+	conditions := ownerConditions(session.FromContext(ctx))
+	if query.Status != "" {
+		conditions["status"] = query.Status
+	}
+	period := order.Period{From: query.From, To: query.To}
+	total, err := s.orderRepo.CountOrderInPeriod(ctx, conditions, period)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error counting orders: %v", err)
+		return OrderPage{}, InternalError
+	}
+	orders, err := s.orderRepo.QueryOrderInPeriod(ctx, conditions, period, "created_at desc, id desc", query.PerPage, (query.Page-1)*query.PerPage)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying orders: %v", err)
+		return OrderPage{}, InternalError
+	}
+	return OrderPage{Orders: orders, Page: query.Page, PerPage: query.PerPage, Total: total}, nil
+}
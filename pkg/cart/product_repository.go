@@ -0,0 +1,55 @@
+package cart
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+type ProductRepository interface {
+	QueryProduct(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Product, error)
+	CreateProduct(ctx context.Context, product *entity.Product) error
+	UpdateProduct(ctx context.Context, product *entity.Product) error
+}
+
+type productRepository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewProductRepository(db *db.DB, logger log.Logger) ProductRepository {
+	return productRepository{db, logger}
+}
+
+func (r productRepository) QueryProduct(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Product, error) {
+	var products []entity.Product
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&products)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return products, nil
+}
+
+func (r productRepository) CreateProduct(ctx context.Context, product *entity.Product) error {
+	db := r.db.With(ctx)
+	result := db.Create(product)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r productRepository) UpdateProduct(ctx context.Context, product *entity.Product) error {
+	db := r.db.With(ctx)
+	result := db.Save(product)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
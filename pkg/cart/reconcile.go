@@ -0,0 +1,62 @@
+package cart
+
+import (
+	"context"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/money"
+)
+
+const reconcileBatchSize = 100
+
+// Reconciliation describes an open cart whose stored total did not match its items and has been repaired.
+type Reconciliation struct {
+	CartID    uint
+	SessionID string
+	OldTotal  money.Money
+	NewTotal  money.Money
+}
+
+// Reconcile scans all open carts and repairs the totals that don't match the sum of their items.
+// Each cart is checked and repaired in its own transaction. The repaired carts are returned in ID order.
+func Reconcile(ctx context.Context, repo Repository, logger log.Logger) ([]Reconciliation, error) {
+	var repaired []Reconciliation
+	for offset := 0; ; offset += reconcileBatchSize {
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{"status": entity.CartOpen}, "id asc", reconcileBatchSize, offset)
+		if err != nil {
+			return repaired, err
+		}
+		for _, cartEntity := range carts {
+			var reconciliation *Reconciliation
+			err := repo.Transactional(ctx, func(ctx context.Context) error {
+				// the cart may have changed since the batch was read
+				current, err := repo.QueryCart(ctx, map[string]interface{}{"id": cartEntity.ID, "status": entity.CartOpen}, "", 1, 0)
+				if err != nil || len(current) == 0 {
+					return err
+				}
+				oldTotal := current[0].Total
+				changed, err := recalculateTotal(ctx, repo, &current[0])
+				if changed {
+					reconciliation = &Reconciliation{
+						CartID:    current[0].ID,
+						SessionID: current[0].SessionID,
+						OldTotal:  oldTotal,
+						NewTotal:  current[0].Total,
+					}
+				}
+				return err
+			})
+			if err != nil {
+				return repaired, err
+			}
+			if reconciliation != nil {
+				logger.With(ctx).Infof("repaired total of cart %d from %s to %s", reconciliation.CartID, reconciliation.OldTotal, reconciliation.NewTotal)
+				repaired = append(repaired, *reconciliation)
+			}
+		}
+		if len(carts) < reconcileBatchSize {
+			return repaired, nil
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package cart
+
+import (
+	"context"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	consistent := entity.CartEntity{SessionID: "a", Status: entity.CartOpen, Total: usd(30000)}
+	drifted := entity.CartEntity{SessionID: "b", Status: entity.CartOpen, Total: usd(50000)}
+	emptied := entity.CartEntity{SessionID: "c", Status: entity.CartOpen, Total: usd(10000)}
+	closed := entity.CartEntity{SessionID: "d", Status: entity.CartClosed, Total: usd(99999)}
+	for _, c := range []*entity.CartEntity{&consistent, &drifted, &emptied, &closed} {
+		require.Nil(t, repo.CreateCart(ctx, c))
+	}
+	items := []entity.CartItem{
+		{CartID: consistent.ID, ProductName: "shoe", Quantity: 3, Price: usd(30000)},
+		{CartID: drifted.ID, ProductName: "purse", Quantity: 1, Price: usd(20000)},
+		{CartID: closed.ID, ProductName: "bag", Quantity: 1, Price: usd(30000)},
+	}
+	for i := range items {
+		require.Nil(t, repo.CreateCartItem(ctx, &items[i]))
+	}
+
+	repaired, err := Reconcile(ctx, repo, logger)
+	require.Nil(t, err)
+	assert.Equal(t, []Reconciliation{
+		{CartID: drifted.ID, SessionID: "b", OldTotal: usd(50000), NewTotal: usd(20000)},
+		{CartID: emptied.ID, SessionID: "c", OldTotal: usd(10000), NewTotal: usd(0)},
+	}, repaired)
+
+	carts, err := repo.QueryCart(ctx, map[string]interface{}{}, "id asc", -1, -1)
+	require.Nil(t, err)
+	assert.Equal(t, usd(30000), carts[0].Total)
+	assert.Equal(t, usd(20000), carts[1].Total)
+	assert.Equal(t, usd(0), carts[2].Total)
+	assert.Equal(t, usd(99999), carts[3].Total)
+
+	repaired, err = Reconcile(ctx, repo, logger)
+	require.Nil(t, err)
+	assert.Empty(t, repaired)
+}
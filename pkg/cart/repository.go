@@ -10,14 +10,19 @@ import (
 type Repository interface {
 	QueryCart(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.CartEntity, error)
 	QueryCartItem(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.CartItem, error)
+	CountCart(ctx context.Context, conditions map[string]interface{}) (int64, error)
 	CreateCart(ctx context.Context, cartEntity *entity.CartEntity) error
 	CreateCartItem(ctx context.Context, cartItem *entity.CartItem) error
 	UpdateCart(ctx context.Context, cartEntity *entity.CartEntity) error
 	UpdateCartItem(ctx context.Context, cartItem *entity.CartItem) error
+	// UpdateCartStatus sets the status of the cart with the given ID to the status to, if it has the status from, and
+	// reports whether it did. Of concurrent callers changing the same status, only one succeeds.
+	UpdateCartStatus(ctx context.Context, id uint, from entity.Status, to entity.Status) (bool, error)
 	DeleteCartById(ctx context.Context, id uint) error
 	DeleteCartItemById(ctx context.Context, id uint) error
 	DeleteCart(ctx context.Context, conditions map[string]interface{}) error
 	DeleteCartItem(ctx context.Context, conditions map[string]interface{}) error
+	Transactional(ctx context.Context, f func(ctx context.Context) error) error
 }
 
 type repository struct {
@@ -57,6 +62,16 @@ func (r repository) QueryCartItem(ctx context.Context, conditions map[string]int
 	return cartItems, nil
 }
 
+func (r repository) CountCart(ctx context.Context, conditions map[string]interface{}) (int64, error) {
+	var count int64
+	db := r.db.With(ctx)
+	result := db.Model(&entity.CartEntity{}).Where(conditions).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
 func (r repository) CreateCart(ctx context.Context, cartEntity *entity.CartEntity) error {
 	db := r.db.With(ctx)
 	result := db.Create(cartEntity)
@@ -93,6 +108,15 @@ func (r repository) UpdateCartItem(ctx context.Context, cartItem *entity.CartIte
 	return nil
 }
 
+func (r repository) UpdateCartStatus(ctx context.Context, id uint, from entity.Status, to entity.Status) (bool, error) {
+	db := r.db.With(ctx)
+	result := db.Model(&entity.CartEntity{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func (r repository) DeleteCartById(ctx context.Context, id uint) error {
 	db := r.db.With(ctx)
 	result := db.Delete(&entity.CartEntity{}, id)
@@ -128,3 +152,7 @@ func (r repository) DeleteCartItem(ctx context.Context, conditions map[string]in
 	}
 	return nil
 }
+
+func (r repository) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	return r.db.Transactional(ctx, f)
+}
@@ -0,0 +1,180 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The repository tests below form a conformance suite that every Repository implementation must pass.
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) Repository {
+		return NewMemoryRepository()
+	})
+}
+
+func TestGormRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) Repository {
+		logger, _ := log.NewForTest()
+		dbc := db.MigratedForTest(t, migrations.All(), "cart_entities", "cart_items")
+		return NewRepository(dbc, logger)
+	})
+}
+
+func testRepository(t *testing.T, newRepo func(t *testing.T) Repository) {
+	t.Run("create and query carts", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		first := entity.CartEntity{SessionID: "a", Status: entity.CartOpen, Total: usd(100)}
+		second := entity.CartEntity{SessionID: "a", Status: entity.CartClosed}
+		third := entity.CartEntity{SessionID: "b", Status: entity.CartOpen}
+		for _, c := range []*entity.CartEntity{&first, &second, &third} {
+			require.Nil(t, repo.CreateCart(ctx, c))
+			assert.NotZero(t, c.ID)
+			assert.False(t, c.CreatedAt.IsZero())
+		}
+		assert.Less(t, first.ID, second.ID)
+
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{"session_id": "a"}, "id desc", 10, 0)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(carts))
+		assert.Equal(t, second.ID, carts[0].ID)
+		assert.Equal(t, first.ID, carts[1].ID)
+		assert.Equal(t, usd(100), carts[1].Total)
+
+		carts, err = repo.QueryCart(ctx, map[string]interface{}{"session_id": "a", "status": entity.CartOpen}, "id desc", 10, 0)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(carts))
+		assert.Equal(t, first.ID, carts[0].ID)
+
+		carts, err = repo.QueryCart(ctx, map[string]interface{}{}, "id asc", 1, 1)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(carts))
+		assert.Equal(t, second.ID, carts[0].ID)
+
+		carts, err = repo.QueryCart(ctx, map[string]interface{}{"id": []uint{first.ID, third.ID}}, "id asc", -1, -1)
+		require.Nil(t, err)
+		assert.Equal(t, 2, len(carts))
+
+		count, err := repo.CountCart(ctx, map[string]interface{}{"status": entity.CartOpen})
+		require.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		cart := entity.CartEntity{SessionID: "a", Status: entity.CartOpen}
+		require.Nil(t, repo.CreateCart(ctx, &cart))
+		cart.Status = entity.CartClosed
+		cart.Total = usd(250)
+		require.Nil(t, repo.UpdateCart(ctx, &cart))
+
+		item := entity.CartItem{CartID: cart.ID, ProductName: "shoe", Quantity: 1, Price: usd(100)}
+		require.Nil(t, repo.CreateCartItem(ctx, &item))
+		item.Quantity = 2
+		require.Nil(t, repo.UpdateCartItem(ctx, &item))
+
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{"id": cart.ID}, "", 1, 0)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(carts))
+		assert.Equal(t, entity.CartClosed, carts[0].Status)
+		assert.Equal(t, usd(250), carts[0].Total)
+
+		items, err := repo.QueryCartItem(ctx, map[string]interface{}{"cart_id": cart.ID, "product_name": "shoe"}, "", 1, 0)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(items))
+		assert.Equal(t, 2, items[0].Quantity)
+	})
+
+	t.Run("update status", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		cart := entity.CartEntity{SessionID: "a", Status: entity.CartOpen, Total: usd(250)}
+		require.Nil(t, repo.CreateCart(ctx, &cart))
+
+		closed, err := repo.UpdateCartStatus(ctx, cart.ID, entity.CartOpen, entity.CartClosed)
+		require.Nil(t, err)
+		assert.True(t, closed)
+		closed, err = repo.UpdateCartStatus(ctx, cart.ID, entity.CartOpen, entity.CartClosed)
+		require.Nil(t, err)
+		assert.False(t, closed)
+
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{"id": cart.ID}, "", 1, 0)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(carts))
+		assert.Equal(t, entity.CartClosed, carts[0].Status)
+		assert.Equal(t, usd(250), carts[0].Total)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		cart := entity.CartEntity{SessionID: "a", Status: entity.CartOpen}
+		require.Nil(t, repo.CreateCart(ctx, &cart))
+		var items []entity.CartItem
+		for _, name := range []string{"shoe", "purse", "bag"} {
+			item := entity.CartItem{CartID: cart.ID, ProductName: name, Quantity: 1}
+			require.Nil(t, repo.CreateCartItem(ctx, &item))
+			items = append(items, item)
+		}
+
+		require.Nil(t, repo.DeleteCartItemById(ctx, items[0].ID))
+		require.Nil(t, repo.DeleteCartItem(ctx, map[string]interface{}{"id": items[1].ID, "cart_id": cart.ID}))
+		remaining, err := repo.QueryCartItem(ctx, map[string]interface{}{"cart_id": cart.ID}, "id asc", -1, -1)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(remaining))
+		assert.Equal(t, items[2].ID, remaining[0].ID)
+
+		assert.NotNil(t, repo.DeleteCartItem(ctx, map[string]interface{}{}))
+
+		require.Nil(t, repo.DeleteCartById(ctx, cart.ID))
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{"session_id": "a"}, "", -1, -1)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(carts))
+	})
+
+	t.Run("transactional", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		failure := errors.New("failure")
+
+		err := repo.Transactional(ctx, func(ctx context.Context) error {
+			return repo.CreateCart(ctx, &entity.CartEntity{SessionID: "committed", Status: entity.CartOpen})
+		})
+		require.Nil(t, err)
+
+		err = repo.Transactional(ctx, func(ctx context.Context) error {
+			require.Nil(t, repo.CreateCart(ctx, &entity.CartEntity{SessionID: "rolled back", Status: entity.CartOpen}))
+			return failure
+		})
+		assert.Equal(t, failure, err)
+
+		err = repo.Transactional(ctx, func(ctx context.Context) error {
+			require.Nil(t, repo.CreateCart(ctx, &entity.CartEntity{SessionID: "outer", Status: entity.CartOpen}))
+			nestedErr := repo.Transactional(ctx, func(ctx context.Context) error {
+				require.Nil(t, repo.CreateCart(ctx, &entity.CartEntity{SessionID: "inner", Status: entity.CartOpen}))
+				return failure
+			})
+			assert.Equal(t, failure, nestedErr)
+			return nil
+		})
+		require.Nil(t, err)
+
+		carts, err := repo.QueryCart(ctx, map[string]interface{}{}, "id asc", -1, -1)
+		require.Nil(t, err)
+		var sessions []string
+		for _, c := range carts {
+			sessions = append(sessions, c.SessionID)
+		}
+		assert.Equal(t, []string{"committed", "outer"}, sessions)
+	})
+}
@@ -3,44 +3,179 @@ package cart
 import (
 	"context"
 	"errors"
+	"fmt"
 	"interview/pkg/entity"
+	"interview/pkg/inventory"
 	"interview/pkg/log"
+	"interview/pkg/money"
+	"interview/pkg/order"
+	"interview/pkg/promotion"
+	"interview/pkg/session"
+	"interview/pkg/shipping"
+	"interview/pkg/tax"
 )
 
 type Service interface {
 	AddItemToCart(ctx context.Context, product string, qty int) error
+	UpdateCartItemQuantity(ctx context.Context, cartItemID uint, qty int) error
 	DeleteCartItem(ctx context.Context, cartItemID uint) error
+	Checkout(ctx context.Context) (order.Order, error)
+	GetOrder(ctx context.Context, orderID uint) (order.Order, error)
+	GetOrders(ctx context.Context, query OrderQuery) (OrderPage, error)
+	GetCart(ctx context.Context) (Cart, error)
 	GetCartItems(ctx context.Context) []map[string]interface{}
-	GetProducts() []string
+	GetProducts(ctx context.Context) []string
+	GetRegions(ctx context.Context) []string
+	MergeCart(ctx context.Context, userID uint) error
+	ApplyCoupon(ctx context.Context, code string) error
+	RemoveCoupon(ctx context.Context) error
+	SetRegion(ctx context.Context, region string) error
+	SetShipping(ctx context.Context, country string, method string) error
 	getCart(ctx context.Context) (entity.CartEntity, error)
 	getOrCreateCart(ctx context.Context) (entity.CartEntity, bool, error)
 }
 
+// Inventory reserves stock for the items in carts. It is implemented by inventory.Service.
+type Inventory interface {
+	Reserve(ctx context.Context, productID uint, quantity int) error
+	Release(ctx context.Context, productID uint, quantity int) error
+	Commit(ctx context.Context, productID uint, quantity int) error
+}
+
+// Promotions evaluates the coupons applied to carts. It is implemented by promotion.Service.
+type Promotions interface {
+	Evaluate(ctx context.Context, code string, items []entity.CartItem) (promotion.Discount, error)
+	Redeem(ctx context.Context, code string) error
+}
+
+// Taxes calculates the tax on carts. It is implemented by tax.Service.
+type Taxes interface {
+	Calculate(ctx context.Context, region string, lines []tax.Line) (tax.Breakdown, error)
+	Regions(ctx context.Context) ([]string, error)
+}
+
+// Shipping quotes the shipping of carts. It is implemented by shipping.Service.
+type Shipping interface {
+	Quotes(ctx context.Context, country string, items []shipping.Item, subtotal money.Money) ([]shipping.ShippingQuote, error)
+	Required(ctx context.Context) (bool, error)
+}
+
 type service struct {
-	repo   Repository
-	logger log.Logger
+	repo        Repository
+	productRepo ProductRepository
+	orderRepo   order.Repository
+	inventory   Inventory
+	promotions  Promotions
+	taxes       Taxes
+	shipping    Shipping
+	logger      log.Logger
+}
+
+// Cart is an open cart together with its items. The Total of the cart entity is the sum of the item prices.
+type Cart struct {
+	entity.CartEntity
+	Items []entity.CartItem
+	// Discounts are the reductions given by the coupon of the cart.
+	Discounts []promotion.Discount
+	// CouponError tells why the coupon of the cart gives no discount; it is nil if it does or there is no coupon.
+	CouponError error
+	// Taxes splits the discounted items into net and tax.
+	Taxes tax.Breakdown
+	// ShippingQuotes are the shipping methods available to the destination of the cart.
+	ShippingQuotes []shipping.ShippingQuote
+	// ShippingQuote is the quote of the shipping method of the cart; it is nil if no method is chosen.
+	ShippingQuote *shipping.ShippingQuote
+	// ShippingError tells why the cart cannot be shipped to its destination or with its shipping method.
+	ShippingError error
+}
+
+// Subtotal returns the sum of the item prices.
+func (c Cart) Subtotal() money.Money {
+	return c.Total
+}
+
+// Discount returns the sum of the discounts.
+func (c Cart) Discount() money.Money {
+	total := money.Money{Currency: c.Total.Currency}
+	for _, discount := range c.Discounts {
+		total = total.Add(discount.Amount)
+	}
+	return total
+}
+
+// Shipping returns the price of the shipping method of the cart, zero if no method is chosen.
+func (c Cart) Shipping() money.Money {
+	if c.ShippingQuote == nil {
+		return money.Money{Currency: c.Total.Currency}
+	}
+	return c.ShippingQuote.Price
+}
+
+// GrandTotal returns the amount due: the subtotal less the discounts, plus the tax if the prices exclude it,
+// plus the shipping.
+func (c Cart) GrandTotal() money.Money {
+	total := c.Total.Sub(c.Discount())
+	if !c.Taxes.Inclusive {
+		total = total.Add(c.Taxes.Tax)
+	}
+	return total.Add(c.Shipping())
 }
 
 var CartNotFoundError = errors.New("cart not found")
+var CartItemNotFoundError = errors.New("cart item not found")
 var InternalError = errors.New("internal error")
+var InvalidProductError = errors.New("invalid item name")
+var InvalidQuantityError = errors.New("quantity must be greater than zero")
+var NegativeQuantityError = errors.New("quantity cannot be negative")
+var QuantityLimitError = fmt.Errorf("quantity cannot be more than %d per item", MaxQuantityPerItem)
+var EmptyCartError = errors.New("cart is empty")
+var OrderNotFoundError = errors.New("order not found")
+var ShippingMethodRequiredError = errors.New("choose a shipping method")
+var CurrencyMismatchError = errors.New("product is priced in a different currency than the cart")
 
-func NewService(repo Repository, logger log.Logger) Service {
-	return service{repo, logger}
+func NewService(repo Repository, productRepo ProductRepository, orderRepo order.Repository, inventory Inventory, promotions Promotions, taxes Taxes, shipping Shipping, logger log.Logger) Service {
+	return service{repo, productRepo, orderRepo, inventory, promotions, taxes, shipping, logger}
 }
 
 const CartPath = "/cart"
 
-var itemPriceMapping = map[string]float64{
-	"shoe":  100,
-	"purse": 200,
-	"bag":   300,
-	"watch": 300,
+// MaxQuantityPerItem is the largest quantity of a single product a cart can hold.
+const MaxQuantityPerItem = 99
+
+func (s service) GetCart(ctx context.Context) (Cart, error) {
+	cartEntity, err := s.getCart(ctx)
+	if errors.Is(err, CartNotFoundError) {
+		return Cart{}, err
+	}
+	if err != nil {
+		s.logger.With(ctx).Errorf("error getting cart: %v", err)
+		return Cart{}, InternalError
+	}
+	conditions := map[string]interface{}{
+		"cart_id": cartEntity.ID,
+	}
+	cartItems, err := s.repo.QueryCartItem(ctx, conditions, "id desc", 100, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying cart items: %v", err)
+		return Cart{}, InternalError
+	}
+	cart := Cart{CartEntity: cartEntity, Items: cartItems}
+	if err := s.evaluateCoupon(ctx, &cart); err != nil {
+		return Cart{}, err
+	}
+	if err := s.calculateTax(ctx, &cart); err != nil {
+		return Cart{}, err
+	}
+	if err := s.estimateShipping(ctx, &cart); err != nil {
+		return Cart{}, err
+	}
+	return cart, nil
 }
 
 func (s service) GetCartItems(ctx context.Context) (items []map[string]interface{}) {
 	cartEntity, err := s.getCart(ctx)
 	if err != nil {
-		s.logger.Errorf("error getting cart: %v", err)
+		s.logger.With(ctx).Errorf("error getting cart: %v", err)
 		return
 	}
 	conditions := map[string]interface{}{
@@ -48,7 +183,7 @@ func (s service) GetCartItems(ctx context.Context) (items []map[string]interface
 	}
 	cartItems, err := s.repo.QueryCartItem(ctx, conditions, "id desc", 100, 0)
 	if err != nil {
-		s.logger.Errorf("error querying cart items: %v", err)
+		s.logger.With(ctx).Errorf("error querying cart items: %v", err)
 		return
 	}
 	for _, cartItem := range cartItems {
@@ -64,109 +199,481 @@ func (s service) GetCartItems(ctx context.Context) (items []map[string]interface
 }
 
 func (s service) AddItemToCart(ctx context.Context, product string, qty int) error {
-	cartEntity, isCartNew, err := s.getOrCreateCart(ctx)
+	if qty <= 0 {
+		return InvalidQuantityError
+	}
+	if qty > MaxQuantityPerItem {
+		return QuantityLimitError
+	}
+	productEntity, err := s.getProduct(ctx, product)
 	if err != nil {
 		return err
 	}
-	item, ok := itemPriceMapping[product]
-	if !ok {
-		return errors.New("invalid item name")
-	}
-	subTotal := item * float64(qty)
-
-	createItem := func() error {
-		cartItemEntity := entity.CartItem{
-			CartID:      cartEntity.ID,
-			ProductName: product,
-			Quantity:    qty,
-			Price:       subTotal,
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, isCartNew, err := s.getOrCreateCart(ctx)
+		if err != nil {
+			return err
 		}
-		return s.repo.CreateCartItem(ctx, &cartItemEntity)
-	}
-
-	err = nil
-	if isCartNew {
-		err = createItem()
-	} else {
-		conditions := map[string]interface{}{
-			"cart_id":      cartEntity.ID,
-			"product_name": product,
+		if !inCurrencyOf(cartEntity, productEntity.Price) {
+			return CurrencyMismatchError
 		}
-		cartItems, err := s.repo.QueryCartItem(ctx, conditions, "id desc", 1, 0)
-		if err != nil {
-			s.logger.Errorf("error querying cart item: %v", err)
-			return InternalError
+		var cartItems []entity.CartItem
+		if !isCartNew {
+			conditions := map[string]interface{}{
+				"cart_id":      cartEntity.ID,
+				"product_name": product,
+			}
+			cartItems, err = s.repo.QueryCartItem(ctx, conditions, "id desc", 1, 0)
+			if err != nil {
+				s.logger.With(ctx).Errorf("error querying cart item: %v", err)
+				return InternalError
+			}
+			if len(cartItems) > 0 && cartItems[0].Quantity+qty > MaxQuantityPerItem {
+				return QuantityLimitError
+			}
 		}
+		if err := s.reserve(ctx, productEntity, qty); err != nil {
+			return err
+		}
+
 		if len(cartItems) == 0 {
-			err = createItem()
+			cartItemEntity := entity.CartItem{
+				CartID:      cartEntity.ID,
+				ProductID:   productEntity.ID,
+				ProductName: product,
+				Quantity:    qty,
+				Price:       linePrice(productEntity, qty),
+			}
+			err = s.repo.CreateCartItem(ctx, &cartItemEntity)
 		} else {
 			cartItemEntity := cartItems[0]
 			cartItemEntity.Quantity += qty
-			cartItemEntity.Price += subTotal
+			cartItemEntity.Price = linePrice(productEntity, cartItemEntity.Quantity)
 			err = s.repo.UpdateCartItem(ctx, &cartItemEntity)
 		}
+		if err != nil {
+			s.logger.With(ctx).Errorf("error adding item to cart: %v", err)
+			return InternalError
+		}
+
+		if _, err := recalculateTotal(ctx, s.repo, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error updating cart total: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+// UpdateCartItemQuantity sets the quantity of an item in the cart. A quantity of zero removes the item.
+func (s service) UpdateCartItemQuantity(ctx context.Context, cartItemID uint, qty int) error {
+	if qty < 0 {
+		return NegativeQuantityError
 	}
-	if err != nil {
-		s.logger.Errorf("error adding item to cart: %v", err)
-		return InternalError
+	if qty > MaxQuantityPerItem {
+		return QuantityLimitError
 	}
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, cartItemEntity, err := s.getCartItem(ctx, cartItemID)
+		if err != nil {
+			return err
+		}
+		if qty == 0 {
+			if err := s.repo.DeleteCartItemById(ctx, cartItemEntity.ID); err != nil {
+				s.logger.With(ctx).Errorf("error deleting cart item: %v", err)
+				return InternalError
+			}
+			if err := s.release(ctx, cartItemEntity.ProductID, cartItemEntity.Quantity); err != nil {
+				return err
+			}
+		} else {
+			productEntities, err := s.productRepo.QueryProduct(ctx, map[string]interface{}{"id": cartItemEntity.ProductID}, "id asc", 1, 0)
+			if err != nil {
+				s.logger.With(ctx).Errorf("error querying product: %v", err)
+				return InternalError
+			}
+			if len(productEntities) == 0 {
+				return InvalidProductError
+			}
+			if !inCurrencyOf(cartEntity, productEntities[0].Price) {
+				return CurrencyMismatchError
+			}
+			if qty > cartItemEntity.Quantity {
+				err = s.reserve(ctx, productEntities[0], qty-cartItemEntity.Quantity)
+			} else {
+				err = s.release(ctx, cartItemEntity.ProductID, cartItemEntity.Quantity-qty)
+			}
+			if err != nil {
+				return err
+			}
+			cartItemEntity.Quantity = qty
+			cartItemEntity.Price = linePrice(productEntities[0], qty)
+			if err := s.repo.UpdateCartItem(ctx, &cartItemEntity); err != nil {
+				s.logger.With(ctx).Errorf("error updating cart item: %v", err)
+				return InternalError
+			}
+		}
+		if _, err := recalculateTotal(ctx, s.repo, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error updating cart total: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
 
-	cartEntity.Total += subTotal
-	err = s.repo.UpdateCart(ctx, &cartEntity)
+func (s service) DeleteCartItem(ctx context.Context, cartItemID uint) error {
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, cartItemEntity, err := s.getCartItem(ctx, cartItemID)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.DeleteCartItemById(ctx, cartItemEntity.ID); err != nil {
+			s.logger.With(ctx).Errorf("error deleting cart item: %v", err)
+			return InternalError
+		}
+		if err := s.release(ctx, cartItemEntity.ProductID, cartItemEntity.Quantity); err != nil {
+			return err
+		}
+		if _, err := recalculateTotal(ctx, s.repo, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error updating cart total: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+// reserve reserves stock for units of the product added to the cart.
+func (s service) reserve(ctx context.Context, product entity.Product, qty int) error {
+	err := s.inventory.Reserve(ctx, product.ID, qty)
+	if errors.Is(err, inventory.OutOfStockError) {
+		return fmt.Errorf("%w of %s", inventory.OutOfStockError, product.Name)
+	}
 	if err != nil {
-		s.logger.Errorf("error updating cart: %v", err)
+		s.logger.With(ctx).Errorf("error reserving stock: %v", err)
 		return InternalError
 	}
+	return nil
+}
 
+// release returns the stock reserved for units of the product removed from the cart.
+func (s service) release(ctx context.Context, productID uint, qty int) error {
+	if err := s.inventory.Release(ctx, productID, qty); err != nil {
+		s.logger.With(ctx).Errorf("error releasing stock: %v", err)
+		return InternalError
+	}
 	return nil
 }
 
-func (s service) DeleteCartItem(ctx context.Context, cartItemID uint) error {
+// getCartItem returns the open cart of the session together with one of its items.
+func (s service) getCartItem(ctx context.Context, cartItemID uint) (entity.CartEntity, entity.CartItem, error) {
 	cartEntity, err := s.getCart(ctx)
+	if errors.Is(err, CartNotFoundError) {
+		return entity.CartEntity{}, entity.CartItem{}, err
+	}
 	if err != nil {
-		s.logger.Errorf("error getting cart: %v", err)
-		return InternalError
+		s.logger.With(ctx).Errorf("error getting cart: %v", err)
+		return entity.CartEntity{}, entity.CartItem{}, InternalError
 	}
+	conditions := map[string]interface{}{
+		"id":      cartItemID,
+		"cart_id": cartEntity.ID,
+	}
+	cartItems, err := s.repo.QueryCartItem(ctx, conditions, "id desc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying cart item: %v", err)
+		return entity.CartEntity{}, entity.CartItem{}, InternalError
+	}
+	if len(cartItems) == 0 {
+		return entity.CartEntity{}, entity.CartItem{}, CartItemNotFoundError
+	}
+	return cartEntity, cartItems[0], nil
+}
 
+// recalculateTotal sets the total of the cart to the sum of its item prices and saves the cart if the total changed.
+// It reports whether the total changed. Every change to the items of a cart is followed by a call to
+// recalculateTotal in the same transaction, so the stored total cannot drift from the items.
+func recalculateTotal(ctx context.Context, repo Repository, cartEntity *entity.CartEntity) (bool, error) {
 	conditions := map[string]interface{}{
-		"ID":      cartItemID,
 		"cart_id": cartEntity.ID,
 	}
-	err = s.repo.DeleteCartItem(ctx, conditions)
+	cartItems, err := repo.QueryCartItem(ctx, conditions, "id asc", -1, -1)
 	if err != nil {
-		s.logger.Errorf("error deleting cart item: %v", err)
-		return InternalError
+		return false, err
+	}
+	total := calculateTotal(cartEntity.Total.Currency, cartItems)
+	if total == cartEntity.Total {
+		return false, nil
 	}
+	cartEntity.Total = total
+	return true, repo.UpdateCart(ctx, cartEntity)
+}
 
-	return nil
+// inCurrencyOf reports whether the price can be added to the cart. A cart takes the currency of its first item
+// and holds no items in any other currency, so that its total can be summed.
+func inCurrencyOf(cartEntity entity.CartEntity, price money.Money) bool {
+	return cartEntity.Total.Currency == "" || cartEntity.Total.Currency == price.Currency
 }
 
-func (s service) GetProducts() []string {
+// linePrice returns the price of an item of the given quantity of the product at its current price.
+func linePrice(product entity.Product, qty int) money.Money {
+	return product.Price.Multiply(int64(qty))
+}
+
+// calculateTotal returns the sum of the item prices. An empty cart keeps the given currency.
+func calculateTotal(currency string, cartItems []entity.CartItem) money.Money {
+	total := money.Money{Currency: currency}
+	for _, cartItem := range cartItems {
+		total = total.Add(cartItem.Price)
+	}
+	return total
+}
+
+// Checkout turns the open cart of the session into an order and closes the cart.
+// The cart items are copied into order lines so that later changes to the catalog do not affect the order.
+func (s service) Checkout(ctx context.Context) (order.Order, error) {
+	var placed order.Order
+	err := s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, err := s.getCart(ctx)
+		if errors.Is(err, CartNotFoundError) {
+			return err
+		}
+		if err != nil {
+			s.logger.With(ctx).Errorf("error getting cart: %v", err)
+			return InternalError
+		}
+		conditions := map[string]interface{}{
+			"cart_id": cartEntity.ID,
+		}
+		cartItems, err := s.repo.QueryCartItem(ctx, conditions, "id asc", -1, -1)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error querying cart items: %v", err)
+			return InternalError
+		}
+		if len(cartItems) == 0 {
+			return EmptyCartError
+		}
+		cart := Cart{CartEntity: cartEntity, Items: cartItems}
+		cart.Total = calculateTotal(cartEntity.Total.Currency, cartItems)
+		if err := s.evaluateCoupon(ctx, &cart); err != nil {
+			return err
+		}
+		if err := s.calculateTax(ctx, &cart); err != nil {
+			return err
+		}
+		if err := s.estimateShipping(ctx, &cart); err != nil {
+			return err
+		}
+		if cart.ShippingError != nil {
+			return cart.ShippingError
+		}
+		if cart.ShippingQuote == nil {
+			required, err := s.shipping.Required(ctx)
+			if err != nil {
+				s.logger.With(ctx).Errorf("error checking shipping: %v", err)
+				return InternalError
+			}
+			if required {
+				return ShippingMethodRequiredError
+			}
+		}
+
+		// closing the cart first makes a concurrent checkout of the same cart find it closed, or wait for this one
+		closed, err := s.repo.UpdateCartStatus(ctx, cartEntity.ID, entity.CartOpen, entity.CartClosed)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error closing cart: %v", err)
+			return InternalError
+		}
+		if !closed {
+			return CartNotFoundError
+		}
+
+		placed.CartID = cartEntity.ID
+		placed.SessionID = session.FromContext(ctx).ID
+		placed.UserID = cartEntity.UserID
+		placed.Status = entity.OrderPlaced
+		placed.Total = cart.GrandTotal()
+		placed.Discount = cart.Discount()
+		placed.Region = cart.Taxes.Region
+		placed.Net = cart.Taxes.Net
+		placed.Tax = cart.Taxes.Tax
+		placed.PricesIncludeTax = cart.Taxes.Inclusive
+		placed.ShippingCountry = cart.ShippingCountry
+		placed.ShippingMethod = cart.ShippingMethod
+		placed.Shipping = cart.Shipping()
+		if len(cart.Discounts) > 0 {
+			placed.CouponCode = cartEntity.CouponCode
+			if err := s.redeemCoupon(ctx, cartEntity.CouponCode); err != nil {
+				return err
+			}
+		}
+		if err := s.orderRepo.CreateOrder(ctx, &placed.Order); err != nil {
+			s.logger.With(ctx).Errorf("error creating order: %v", err)
+			return InternalError
+		}
+		for _, cartItem := range cartItems {
+			orderLine := entity.OrderLine{
+				OrderID:     placed.ID,
+				ProductID:   cartItem.ProductID,
+				ProductName: cartItem.ProductName,
+				Quantity:    cartItem.Quantity,
+				Price:       cartItem.Price,
+			}
+			if err := s.orderRepo.CreateOrderLine(ctx, &orderLine); err != nil {
+				s.logger.With(ctx).Errorf("error creating order line: %v", err)
+				return InternalError
+			}
+			if err := s.inventory.Commit(ctx, cartItem.ProductID, cartItem.Quantity); err != nil {
+				s.logger.With(ctx).Errorf("error committing stock: %v", err)
+				return InternalError
+			}
+			placed.Lines = append(placed.Lines, orderLine)
+		}
+		for _, line := range cart.Taxes.Lines {
+			taxLine := entity.OrderTaxLine{
+				OrderID:  placed.ID,
+				Name:     line.Name,
+				Category: line.Category,
+				Rate:     line.Rate,
+				Taxable:  line.Taxable,
+				Tax:      line.Tax,
+			}
+			if err := s.orderRepo.CreateOrderTaxLine(ctx, &taxLine); err != nil {
+				s.logger.With(ctx).Errorf("error creating order tax line: %v", err)
+				return InternalError
+			}
+			placed.TaxLines = append(placed.TaxLines, taxLine)
+		}
+
+		session.FromContext(ctx).CartID = 0
+		return nil
+	})
+	if err != nil {
+		return order.Order{}, err
+	}
+	return placed, nil
+}
+
+// GetOrder returns the order with the given ID if it was placed by the user of the current session,
+// or by the session itself when no user is logged in.
+func (s service) GetOrder(ctx context.Context, orderID uint) (order.Order, error) {
+	conditions := ownerConditions(session.FromContext(ctx))
+	conditions["id"] = orderID
+	orders, err := s.orderRepo.QueryOrder(ctx, conditions, "id desc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying order: %v", err)
+		return order.Order{}, InternalError
+	}
+	if len(orders) == 0 {
+		return order.Order{}, OrderNotFoundError
+	}
+	conditions = map[string]interface{}{
+		"order_id": orderID,
+	}
+	orderLines, err := s.orderRepo.QueryOrderLine(ctx, conditions, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying order lines: %v", err)
+		return order.Order{}, InternalError
+	}
+	taxLines, err := s.orderRepo.QueryOrderTaxLine(ctx, conditions, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying order tax lines: %v", err)
+		return order.Order{}, InternalError
+	}
+	return order.Order{Order: orders[0], Lines: orderLines, TaxLines: taxLines}, nil
+}
+
+func (s service) GetProducts(ctx context.Context) []string {
 	var products []string
-	for product := range itemPriceMapping {
-		products = append(products, product)
+	conditions := map[string]interface{}{
+		"active": true,
+	}
+	productEntities, err := s.productRepo.QueryProduct(ctx, conditions, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying products: %v", err)
+		return products
+	}
+	for _, productEntity := range productEntities {
+		products = append(products, productEntity.Name)
 	}
 	return products
 }
 
-func (s service) getCart(ctx context.Context) (entity.CartEntity, error) {
-	sessionID := ctx.Value("SessionId").(string)
+func (s service) getProduct(ctx context.Context, name string) (entity.Product, error) {
 	conditions := map[string]interface{}{
-		"status":     entity.CartOpen,
-		"session_id": sessionID,
+		"name":   name,
+		"active": true,
+	}
+	productEntities, err := s.productRepo.QueryProduct(ctx, conditions, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying product: %v", err)
+		return entity.Product{}, InternalError
+	}
+	if len(productEntities) == 0 {
+		return entity.Product{}, InvalidProductError
+	}
+	return productEntities[0], nil
+}
+
+// productsOf returns the products of the items by ID.
+func (s service) productsOf(ctx context.Context, items []entity.CartItem) (map[uint]entity.Product, error) {
+	products := map[uint]entity.Product{}
+	if len(items) == 0 {
+		return products, nil
+	}
+	productIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	productEntities, err := s.productRepo.QueryProduct(ctx, map[string]interface{}{"id": productIDs}, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying products: %v", err)
+		return nil, InternalError
+	}
+	for _, product := range productEntities {
+		products[product.ID] = product
+	}
+	return products, nil
+}
+
+// ownerConditions returns the conditions selecting the carts and orders of the user logged in to the session,
+// or of the session itself when it is anonymous.
+func ownerConditions(sess *session.Session) map[string]interface{} {
+	if sess.UserID != 0 {
+		return map[string]interface{}{"user_id": sess.UserID}
+	}
+	return map[string]interface{}{"session_id": sess.ID, "user_id": uint(0)}
+}
+
+// getCart returns the open cart of the session. The cart the session remembers is looked up by its ID first; if
+// it was closed or merged meanwhile, the open cart is searched among those of the owner and remembered instead.
+func (s service) getCart(ctx context.Context) (entity.CartEntity, error) {
+	sess := session.FromContext(ctx)
+	conditions := ownerConditions(sess)
+	conditions["status"] = entity.CartOpen
+	if sess.CartID != 0 {
+		conditions["id"] = sess.CartID
+		cartEntities, err := s.repo.QueryCart(ctx, conditions, "", 1, 0)
+		if err != nil {
+			return entity.CartEntity{}, err
+		}
+		if len(cartEntities) > 0 {
+			return cartEntities[0], nil
+		}
+		delete(conditions, "id")
 	}
 	cartEntities, err := s.repo.QueryCart(ctx, conditions, "id desc", 1, 0)
 	if err != nil {
 		return entity.CartEntity{}, err
 	}
 	if len(cartEntities) == 0 {
+		sess.CartID = 0
 		return entity.CartEntity{}, CartNotFoundError
 	}
+	sess.CartID = cartEntities[0].ID
 	return cartEntities[0], nil
 }
 
 func (s service) getOrCreateCart(ctx context.Context) (entity.CartEntity, bool, error) {
-	sessionID := ctx.Value("SessionId").(string)
+	sess := session.FromContext(ctx)
 	created := false
 	cartEntity, err := s.getCart(ctx)
 	if err != nil && !errors.Is(err, CartNotFoundError) {
@@ -174,11 +681,144 @@ func (s service) getOrCreateCart(ctx context.Context) (entity.CartEntity, bool,
 	}
 	if errors.Is(err, CartNotFoundError) {
 		cartEntity = entity.CartEntity{
-			SessionID: sessionID,
+			SessionID: sess.ID,
+			UserID:    sess.UserID,
 			Status:    entity.CartOpen,
 		}
-		s.repo.CreateCart(ctx, &cartEntity)
+		if err := s.repo.CreateCart(ctx, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error creating cart: %v", err)
+			return entity.CartEntity{}, false, InternalError
+		}
+		sess.CartID = cartEntity.ID
 		created = true
 	}
 	return cartEntity, created, nil
 }
+
+// MergeCart folds the open cart of the anonymous session into the open cart of the given user, who is logging in.
+// The quantities of products found in both carts are summed, up to MaxQuantityPerItem. If the user has no open cart,
+// the cart of the session becomes theirs.
+func (s service) MergeCart(ctx context.Context, userID uint) error {
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		sess := session.FromContext(ctx)
+		conditions := map[string]interface{}{
+			"status":     entity.CartOpen,
+			"session_id": sess.ID,
+			"user_id":    uint(0),
+		}
+		anonymousCarts, err := s.repo.QueryCart(ctx, conditions, "id desc", 1, 0)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error getting cart: %v", err)
+			return InternalError
+		}
+		conditions = map[string]interface{}{
+			"status":  entity.CartOpen,
+			"user_id": userID,
+		}
+		userCarts, err := s.repo.QueryCart(ctx, conditions, "id desc", 1, 0)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error getting cart: %v", err)
+			return InternalError
+		}
+
+		switch {
+		case len(anonymousCarts) == 0 && len(userCarts) == 0:
+			sess.CartID = 0
+		case len(anonymousCarts) == 0:
+			sess.CartID = userCarts[0].ID
+		case len(userCarts) == 0:
+			cartEntity := anonymousCarts[0]
+			cartEntity.UserID = userID
+			if err := s.repo.UpdateCart(ctx, &cartEntity); err != nil {
+				s.logger.With(ctx).Errorf("error assigning cart to user: %v", err)
+				return InternalError
+			}
+			sess.CartID = cartEntity.ID
+		default:
+			if err := s.mergeCartItems(ctx, anonymousCarts[0], userCarts[0]); err != nil {
+				s.logger.With(ctx).Errorf("error merging carts: %v", err)
+				return InternalError
+			}
+			sess.CartID = userCarts[0].ID
+		}
+		return nil
+	})
+}
+
+// mergeCartItems moves the items of one cart into another and deletes the emptied cart. The cart keeps its coupon,
+// tax region and shipping destination, or takes those of the other cart if it has none.
+// Items priced in another currency than the cart are dropped. The stock reserved for units dropped by the quantity
+// limit or the currency of the cart is released.
+func (s service) mergeCartItems(ctx context.Context, from entity.CartEntity, into entity.CartEntity) error {
+	fromItems, err := s.repo.QueryCartItem(ctx, map[string]interface{}{"cart_id": from.ID}, "id asc", -1, -1)
+	if err != nil {
+		return err
+	}
+	intoItems, err := s.repo.QueryCartItem(ctx, map[string]interface{}{"cart_id": into.ID}, "id asc", -1, -1)
+	if err != nil {
+		return err
+	}
+	byProduct := map[uint]entity.CartItem{}
+	for _, item := range intoItems {
+		byProduct[item.ProductID] = item
+	}
+	for _, item := range fromItems {
+		if !inCurrencyOf(into, item.Price) {
+			if err := s.inventory.Release(ctx, item.ProductID, item.Quantity); err != nil {
+				return err
+			}
+			if err := s.repo.DeleteCartItemById(ctx, item.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		existing, ok := byProduct[item.ProductID]
+		if !ok {
+			item.CartID = into.ID
+			if err := s.repo.UpdateCartItem(ctx, &item); err != nil {
+				return err
+			}
+			continue
+		}
+		productEntities, err := s.productRepo.QueryProduct(ctx, map[string]interface{}{"id": item.ProductID}, "id asc", 1, 0)
+		if err != nil {
+			return err
+		}
+		if len(productEntities) == 0 {
+			return InvalidProductError
+		}
+		merged := min(existing.Quantity+item.Quantity, MaxQuantityPerItem)
+		if err := s.inventory.Release(ctx, item.ProductID, existing.Quantity+item.Quantity-merged); err != nil {
+			return err
+		}
+		existing.Quantity = merged
+		existing.Price = linePrice(productEntities[0], existing.Quantity)
+		if err := s.repo.UpdateCartItem(ctx, &existing); err != nil {
+			return err
+		}
+		if err := s.repo.DeleteCartItemById(ctx, item.ID); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DeleteCartById(ctx, from.ID); err != nil {
+		return err
+	}
+	if (into.CouponCode == "" && from.CouponCode != "") || (into.Region == "" && from.Region != "") ||
+		(into.ShippingCountry == "" && from.ShippingCountry != "") {
+		if into.CouponCode == "" {
+			into.CouponCode = from.CouponCode
+		}
+		if into.Region == "" {
+			into.Region = from.Region
+		}
+		if into.ShippingCountry == "" {
+			into.ShippingCountry = from.ShippingCountry
+			into.ShippingMethod = from.ShippingMethod
+		}
+		if err := s.repo.UpdateCart(ctx, &into); err != nil {
+			return err
+		}
+	}
+	_, err = recalculateTotal(ctx, s.repo, &into)
+	return err
+}
@@ -3,8 +3,19 @@ package cart
 import (
 	"context"
 	"interview/pkg/entity"
+	"interview/pkg/inventory"
 	"interview/pkg/log"
+	"interview/pkg/money"
+	"interview/pkg/order"
+	"interview/pkg/promotion"
+	"interview/pkg/session"
+	"interview/pkg/shipping"
+	"interview/pkg/tax"
+	"math"
+	"slices"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -16,13 +27,13 @@ var expected = []map[string]interface{}{
 	{
 		"ID":       uint(1),
 		"Quantity": 3,
-		"Price":    float64(300),
+		"Price":    usd(30000),
 		"Product":  "shoe",
 	},
 	{
 		"ID":       uint(2),
 		"Quantity": 1,
-		"Price":    float64(200),
+		"Price":    usd(20000),
 		"Product":  "purse",
 	},
 }
@@ -32,20 +43,86 @@ type mockCartRepo struct {
 	items []entity.CartItem
 }
 
+type mockOrderRepo struct {
+	orders   []entity.Order
+	lines    []entity.OrderLine
+	taxLines []entity.OrderTaxLine
+}
+
+type mockProductRepo struct {
+	products []entity.Product
+}
+
+// mockPromotions evaluates its coupons with the rules of the promotion package.
+type mockPromotions struct {
+	coupons []entity.Coupon
+}
+
+// mockTaxes calculates taxes with the rules of the tax package; without rates it charges no tax.
+type mockTaxes struct {
+	rates    []entity.TaxRate
+	settings tax.Settings
+}
+
+// mockShipping quotes shipping with the rules of the shipping package; without zones it ships nowhere.
+type mockShipping struct {
+	zones []entity.ShippingZone
+	rates []entity.ShippingRate
+}
+
+// mockInventory tracks the stock of the products in available; other products are not tracked.
+type mockInventory struct {
+	available map[uint]int
+	reserved  map[uint]int
+	committed map[uint]int
+}
+
+func usd(cents int64) money.Money {
+	return money.New(cents, "USD")
+}
+
+var productPrice = map[string]money.Money{
+	"shoe":  usd(10000),
+	"purse": usd(20000),
+	"bag":   usd(30000),
+	"watch": usd(30000),
+}
+
 func Test_service_GetCartItems(t *testing.T) {
 	logger, _ := log.NewForTest()
 	repo := getMockedRepo()
-	service := NewService(&repo, logger)
-	ctx := context.WithValue(context.Background(), "SessionId", sessionID)
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
 	got := service.GetCartItems(ctx)
 	assert.Equal(t, expected, got)
 }
 
+func Test_service_GetCart_RememberedCart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+
+	// a cart the session no longer owns is not used, and the open cart of the session is remembered instead
+	sess := &session.Session{ID: sessionID, CartID: 2}
+	cart, err := service.GetCart(session.WithSession(context.Background(), sess))
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), cart.ID)
+	assert.Equal(t, uint(1), sess.CartID)
+
+	sess = &session.Session{ID: "unknown", CartID: 1}
+	_, err = service.GetCart(session.WithSession(context.Background(), sess))
+	assert.Equal(t, CartNotFoundError, err)
+	assert.Zero(t, sess.CartID)
+}
+
 func Test_service_AddItemToCart(t *testing.T) {
 	logger, _ := log.NewForTest()
 	repo := getMockedRepo()
-	service := NewService(&repo, logger)
-	ctx := context.WithValue(context.Background(), "SessionId", sessionID)
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
 
 	qty := 2
 	product := "watch"
@@ -55,26 +132,715 @@ func Test_service_AddItemToCart(t *testing.T) {
 	expected := append(expected, map[string]interface{}{
 		"ID":       uint(4),
 		"Quantity": qty,
-		"Price":    itemPriceMapping[product] * float64(qty),
+		"Price":    productPrice[product].Multiply(int64(qty)),
 		"Product":  product,
 	})
 	got := service.GetCartItems(ctx)
 	assert.Equal(t, expected, got)
 
-	assert.Equal(t, float64(1100), repo.cards[0].Total)
+	assert.Equal(t, usd(110000), repo.cards[0].Total)
+	assert.Equal(t, uint(4), repo.items[3].ProductID)
+}
+
+func Test_service_AddItemToCart_InvalidProduct(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	productRepo.products[0].Active = false
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	err := service.AddItemToCart(ctx, "shoe", 1)
+	assert.Equal(t, InvalidProductError, err)
+	err = service.AddItemToCart(ctx, "hat", 1)
+	assert.Equal(t, InvalidProductError, err)
+	assert.Equal(t, 3, len(repo.items))
+}
+
+func Test_service_AddItemToCart_PriceChanged(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	// the whole item is priced at the current price of the product, as when its quantity is updated
+	productRepo.products[0].Price = usd(12000)
+	assert.Nil(t, service.AddItemToCart(ctx, "shoe", 1))
+	assert.Equal(t, 4, repo.items[0].Quantity)
+	assert.Equal(t, usd(48000), repo.items[0].Price)
+	assert.Equal(t, usd(68000), repo.cards[0].Total)
+}
+
+func Test_service_AddItemToCart_CurrencyMismatch(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	productRepo.products[3].Price = money.New(30000, "EUR")
+	stock := mockInventory{}
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &stock, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Equal(t, CurrencyMismatchError, service.AddItemToCart(ctx, "watch", 1))
+	assert.Equal(t, 3, len(repo.items))
+	assert.Empty(t, stock.reserved)
+	assert.Equal(t, usd(50000), repo.cards[0].Total)
+
+	// a new cart takes the currency of its first item
+	otherCtx := session.WithSession(context.Background(), &session.Session{ID: "other"})
+	assert.Nil(t, service.AddItemToCart(otherCtx, "watch", 1))
+	assert.Equal(t, CurrencyMismatchError, service.AddItemToCart(otherCtx, "shoe", 1))
+	cart, err := service.GetCart(otherCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, money.New(30000, "EUR"), cart.Total)
+}
+
+func Test_service_GetProducts(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	productRepo.products[2].Active = false
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+
+	got := service.GetProducts(context.Background())
+	assert.Equal(t, []string{"shoe", "purse", "watch"}, got)
 }
 
 func Test_service_DeleteCartItem(t *testing.T) {
 	logger, _ := log.NewForTest()
 	repo := getMockedRepo()
-	service := NewService(&repo, logger)
-	ctx := context.WithValue(context.Background(), "SessionId", sessionID)
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
 	err := service.DeleteCartItem(ctx, 1)
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(repo.items))
 	expected := expected[1:]
 	got := service.GetCartItems(ctx)
 	assert.Equal(t, expected, got)
+	assert.Equal(t, usd(20000), repo.cards[0].Total)
+
+	assert.Equal(t, CartItemNotFoundError, service.DeleteCartItem(ctx, 3))
+	assert.Equal(t, usd(30000), repo.cards[1].Total)
+}
+
+func Test_service_UpdateCartItemQuantity(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	err := service.UpdateCartItemQuantity(ctx, 1, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, repo.items[0].Quantity)
+	assert.Equal(t, usd(50000), repo.items[0].Price)
+	assert.Equal(t, usd(70000), repo.cards[0].Total)
+
+	err = service.UpdateCartItemQuantity(ctx, 2, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(repo.items))
+	assert.Equal(t, usd(50000), repo.cards[0].Total)
+}
+
+func Test_service_UpdateCartItemQuantity_Invalid(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Equal(t, NegativeQuantityError, service.UpdateCartItemQuantity(ctx, 1, -1))
+	assert.Equal(t, QuantityLimitError, service.UpdateCartItemQuantity(ctx, 1, MaxQuantityPerItem+1))
+	// item 3 belongs to another session's cart
+	assert.Equal(t, CartItemNotFoundError, service.UpdateCartItemQuantity(ctx, 3, 2))
+	assert.Equal(t, 3, repo.items[0].Quantity)
+	assert.Equal(t, 1, repo.items[2].Quantity)
+	assert.Equal(t, usd(50000), repo.cards[0].Total)
+
+	// adding to an existing item may not exceed the limit either
+	assert.Equal(t, QuantityLimitError, service.AddItemToCart(ctx, "shoe", MaxQuantityPerItem-2))
+	assert.Equal(t, 3, repo.items[0].Quantity)
+}
+
+func Test_service_ReserveStock(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	// 4 more shoes and 1 more watch are available; purses are not tracked
+	stock := mockInventory{available: map[uint]int{1: 4, 4: 1}}
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &stock, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Nil(t, service.AddItemToCart(ctx, "shoe", 2))
+	assert.Nil(t, service.UpdateCartItemQuantity(ctx, 1, 7))
+	assert.Equal(t, 0, stock.available[1])
+	err := service.AddItemToCart(ctx, "shoe", 1)
+	assert.ErrorIs(t, err, inventory.OutOfStockError)
+	assert.Equal(t, "not enough stock of shoe", err.Error())
+	assert.ErrorIs(t, service.UpdateCartItemQuantity(ctx, 1, 8), inventory.OutOfStockError)
+	assert.Equal(t, 7, repo.items[0].Quantity)
+	assert.Equal(t, usd(90000), repo.cards[0].Total)
+
+	// a new item is not created without stock
+	assert.ErrorIs(t, service.AddItemToCart(ctx, "watch", 2), inventory.OutOfStockError)
+	assert.Equal(t, 3, len(repo.items))
+	assert.Nil(t, service.AddItemToCart(ctx, "purse", 50))
+
+	// lowering the quantity or removing the item releases the stock
+	assert.Nil(t, service.UpdateCartItemQuantity(ctx, 1, 5))
+	assert.Equal(t, 2, stock.available[1])
+	assert.Nil(t, service.DeleteCartItem(ctx, 1))
+	assert.Equal(t, 7, stock.available[1])
+}
+
+func Test_service_Checkout(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	orderRepo := mockOrderRepo{}
+	stock := mockInventory{}
+	service := NewService(&repo, &productRepo, &orderRepo, &stock, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	sess := &session.Session{ID: sessionID, CartID: 1}
+	ctx := session.WithSession(context.Background(), sess)
+
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[uint]int{1: 3, 2: 1}, stock.committed)
+	assert.Equal(t, uint(0), sess.CartID)
+	assert.Equal(t, uint(1), placed.ID)
+	assert.Equal(t, uint(1), placed.CartID)
+	assert.Equal(t, entity.OrderPlaced, placed.Status)
+	assert.Equal(t, usd(50000), placed.Total)
+	assert.Equal(t, 2, len(placed.Lines))
+	assert.Equal(t, 2, len(orderRepo.lines))
+	assert.Equal(t, entity.CartClosed, repo.cards[0].Status)
+
+	got, err := service.GetOrder(ctx, placed.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, placed, got)
+
+	otherCtx := session.WithSession(context.Background(), &session.Session{ID: "987654321"})
+	_, err = service.GetOrder(otherCtx, placed.ID)
+	assert.Equal(t, OrderNotFoundError, err)
+
+	// the closed cart is no longer used by the session
+	_, err = service.Checkout(ctx)
+	assert.Equal(t, CartNotFoundError, err)
+	assert.Nil(t, service.AddItemToCart(ctx, "shoe", 1))
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.CartOpen, cart.Status)
+	assert.NotEqual(t, uint(1), cart.ID)
+	assert.Equal(t, cart.ID, sess.CartID)
+}
+
+// staleCartRepo returns the carts as they were before they were changed, like a concurrent request that read them
+// earlier.
+type staleCartRepo struct {
+	*mockCartRepo
+	stale []entity.CartEntity
+}
+
+func (r staleCartRepo) QueryCart(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.CartEntity, error) {
+	current := r.mockCartRepo.cards
+	r.mockCartRepo.cards = r.stale
+	defer func() { r.mockCartRepo.cards = current }()
+	return r.mockCartRepo.QueryCart(ctx, conditions, order, limit, offset)
+}
+
+func Test_service_Checkout_Concurrent(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	stale := staleCartRepo{&repo, append([]entity.CartEntity(nil), repo.cards...)}
+	productRepo := getMockedProductRepo()
+	orderRepo := mockOrderRepo{}
+	service := NewService(&repo, &productRepo, &orderRepo, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	concurrent := NewService(stale, &productRepo, &orderRepo, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID, CartID: 1})
+
+	_, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	// the other checkout read the cart while it was still open
+	_, err = concurrent.Checkout(session.WithSession(context.Background(), &session.Session{ID: sessionID, CartID: 1}))
+	assert.Equal(t, CartNotFoundError, err)
+	assert.Equal(t, 1, len(orderRepo.orders))
+}
+
+// failingCartRepo fails to create carts.
+type failingCartRepo struct {
+	*mockCartRepo
+}
+
+func (r failingCartRepo) CreateCart(ctx context.Context, cartEntity *entity.CartEntity) error {
+	return gorm.ErrInvalidDB
+}
+
+func Test_service_AddItemToCart_CreateCartFails(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := mockCartRepo{}
+	productRepo := getMockedProductRepo()
+	service := NewService(failingCartRepo{&repo}, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	sess := &session.Session{ID: sessionID}
+	ctx := session.WithSession(context.Background(), sess)
+
+	assert.Equal(t, InternalError, service.AddItemToCart(ctx, "shoe", 1))
+	assert.Equal(t, 0, len(repo.items))
+	assert.Equal(t, uint(0), sess.CartID)
+}
+
+func Test_service_Checkout_EmptyCart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	repo.items = nil
+	productRepo := getMockedProductRepo()
+	orderRepo := mockOrderRepo{}
+	service := NewService(&repo, &productRepo, &orderRepo, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	_, err := service.Checkout(ctx)
+	assert.Equal(t, EmptyCartError, err)
+	assert.Equal(t, 0, len(orderRepo.orders))
+	assert.Equal(t, entity.CartOpen, repo.cards[0].Status)
+}
+
+func Test_service_ApplyCoupon(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	orderRepo := mockOrderRepo{}
+	promotions := mockPromotions{coupons: []entity.Coupon{
+		{Code: "TENOFF", Kind: entity.CouponPercentage, Percent: 10, MaxUses: 1, Active: true},
+		{Code: "BIGSPENDER", Kind: entity.CouponFixed, Amount: usd(5000), MinSubtotal: usd(100000), Active: true},
+	}}
+	service := NewService(&repo, &productRepo, &orderRepo, &mockInventory{}, &promotions, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Equal(t, promotion.CouponNotFoundError, service.ApplyCoupon(ctx, "NOPE"))
+	assert.ErrorIs(t, service.ApplyCoupon(ctx, "bigspender"), promotion.MinimumNotMetError)
+	assert.Nil(t, service.ApplyCoupon(ctx, " tenoff "))
+	assert.Equal(t, "TENOFF", repo.cards[0].CouponCode)
+
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, usd(50000), cart.Subtotal())
+	assert.Equal(t, []promotion.Discount{{
+		Code:        "TENOFF",
+		Description: "10% off",
+		Amount:      usd(5000),
+		Items:       map[uint]money.Money{1: usd(3000), 2: usd(2000)},
+	}}, cart.Discounts)
+	assert.Equal(t, usd(45000), cart.GrandTotal())
+
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "TENOFF", placed.CouponCode)
+	assert.Equal(t, usd(5000), placed.Discount)
+	assert.Equal(t, usd(45000), placed.Total)
+	assert.Equal(t, 1, promotions.coupons[0].Uses)
+
+	// the coupon has been used up by the order
+	assert.Nil(t, service.AddItemToCart(ctx, "shoe", 1))
+	assert.Equal(t, promotion.CouponUsedUpError, service.ApplyCoupon(ctx, "TENOFF"))
+}
+
+func Test_service_ApplyCoupon_NoLongerApplies(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	orderRepo := mockOrderRepo{}
+	promotions := mockPromotions{coupons: []entity.Coupon{
+		{Code: "PURSE", Kind: entity.CouponFixed, Amount: usd(2500), ProductID: 2, Active: true},
+	}}
+	service := NewService(&repo, &productRepo, &orderRepo, &mockInventory{}, &promotions, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Nil(t, service.ApplyCoupon(ctx, "PURSE"))
+	assert.Nil(t, service.DeleteCartItem(ctx, 2))
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "PURSE", cart.CouponCode)
+	assert.Empty(t, cart.Discounts)
+	assert.Equal(t, promotion.NotApplicableError, cart.CouponError)
+	assert.Equal(t, usd(30000), cart.GrandTotal())
+
+	// the order is placed without the discount, and the coupon is not used
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "", placed.CouponCode)
+	assert.Equal(t, usd(30000), placed.Total)
+	assert.Equal(t, 0, promotions.coupons[0].Uses)
+}
+
+func Test_service_RemoveCoupon(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	repo.cards[0].CouponCode = "TENOFF"
+	productRepo := getMockedProductRepo()
+	promotions := mockPromotions{coupons: []entity.Coupon{
+		{Code: "TENOFF", Kind: entity.CouponPercentage, Percent: 10, Active: true},
+	}}
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &promotions, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	assert.Nil(t, service.RemoveCoupon(ctx))
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "", cart.CouponCode)
+	assert.Empty(t, cart.Discounts)
+	assert.Equal(t, usd(50000), cart.GrandTotal())
+
+	otherCtx := session.WithSession(context.Background(), &session.Session{ID: "unknown"})
+	assert.Equal(t, CartNotFoundError, service.RemoveCoupon(otherCtx))
+}
+
+func Test_service_Taxes(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	repo.cards[0].CouponCode = "TENOFF"
+	productRepo := getMockedProductRepo()
+	productRepo.products[1].TaxCategory = "reduced"
+	orderRepo := mockOrderRepo{}
+	promotions := mockPromotions{coupons: []entity.Coupon{
+		{Code: "TENOFF", Kind: entity.CouponPercentage, Percent: 10, Active: true},
+	}}
+	taxes := mockTaxes{
+		rates: []entity.TaxRate{
+			{Region: "ny", Category: "standard", Rate: 800, Name: "Sales tax"},
+			{Region: "ny", Category: "reduced", Rate: 400, Name: "Sales tax"},
+			{Region: "de", Category: "standard", Rate: 1900, Name: "VAT"},
+		},
+		settings: tax.Settings{Region: "ny", Rounding: tax.RoundPerLine},
+	}
+	service := NewService(&repo, &productRepo, &orderRepo, &mockInventory{}, &promotions, &taxes, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	// the shoes cost 270.00 and the purse 180.00 after the discount
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "ny", cart.Taxes.Region)
+	assert.Equal(t, usd(45000), cart.Taxes.Net)
+	assert.Equal(t, usd(2160+720), cart.Taxes.Tax)
+	assert.Equal(t, usd(47880), cart.GrandTotal())
+	assert.Equal(t, 2, len(cart.Taxes.Lines))
+
+	assert.Equal(t, tax.UnknownRegionError, service.SetRegion(ctx, "fr"))
+	assert.Nil(t, service.SetRegion(ctx, " DE "))
+	assert.Equal(t, "de", repo.cards[0].Region)
+	cart, err = service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, usd(5130), cart.Taxes.Tax)
+	assert.Equal(t, usd(50130), cart.GrandTotal())
+
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "de", placed.Region)
+	assert.Equal(t, usd(45000), placed.Net)
+	assert.Equal(t, usd(5130), placed.Tax)
+	assert.Equal(t, usd(5000), placed.Discount)
+	assert.Equal(t, usd(50130), placed.Total)
+	assert.False(t, placed.PricesIncludeTax)
+
+	// the order keeps the tax it was placed with when the rates change
+	taxes.rates[2].Rate = 2000
+	got, err := service.GetOrder(ctx, placed.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, usd(5130), got.Tax)
+	assert.Equal(t, []entity.OrderTaxLine{{
+		Model:    gorm.Model{ID: 1},
+		OrderID:  placed.ID,
+		Name:     "VAT",
+		Category: "standard",
+		Rate:     1900,
+		Taxable:  usd(27000),
+		Tax:      usd(5130),
+	}}, got.TaxLines)
+
+	otherCtx := session.WithSession(context.Background(), &session.Session{ID: "unknown"})
+	assert.Equal(t, CartNotFoundError, service.SetRegion(otherCtx, "de"))
+}
+
+func Test_service_Taxes_InclusivePrices(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	productRepo.products[1].TaxCategory = "reduced"
+	taxes := mockTaxes{
+		rates: []entity.TaxRate{
+			{Region: "ny", Category: "standard", Rate: 800, Name: "Sales tax"},
+			{Region: "ny", Category: "reduced", Rate: 400, Name: "Sales tax"},
+		},
+		settings: tax.Settings{Region: "ny", Inclusive: true, Rounding: tax.RoundPerLine},
+	}
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &taxes, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, usd(2222+769), cart.Taxes.Tax)
+	assert.Equal(t, usd(50000-2222-769), cart.Taxes.Net)
+	assert.Equal(t, usd(50000), cart.GrandTotal())
+
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.True(t, placed.PricesIncludeTax)
+
+	// the order keeps its tax when prices stop including it
+	taxes.settings.Inclusive = false
+	got, err := service.GetOrder(ctx, placed.ID)
+	assert.Nil(t, err)
+	assert.True(t, got.PricesIncludeTax)
+	assert.Equal(t, usd(2222+769), got.Tax)
+	assert.Equal(t, usd(50000), got.Total)
+	assert.Equal(t, 2, len(got.TaxLines))
+}
+
+func getMockedShipping() mockShipping {
+	return mockShipping{
+		zones: []entity.ShippingZone{{Model: gorm.Model{ID: 1}, Name: "eu", Countries: "DE,FR"}},
+		rates: []entity.ShippingRate{
+			{ZoneID: 1, Method: "parcel", Name: "Parcel", Kind: entity.ShippingWeight, Price: usd(900), MaxWeight: 2000},
+			{ZoneID: 1, Method: "parcel", Name: "Parcel", Kind: entity.ShippingWeight, Price: usd(1500), MaxWeight: 10000},
+			{ZoneID: 1, Method: "standard", Name: "Standard", Kind: entity.ShippingFreeOver, Price: usd(1200), Threshold: usd(60000)},
+		},
+	}
+}
+
+func Test_service_Shipping(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	// three shoes weigh 2.4 kg and the bulky purse counts as 4.8 kg
+	productRepo.products[0].Weight = 800
+	productRepo.products[1].Weight = 500
+	productRepo.products[1].Length, productRepo.products[1].Width, productRepo.products[1].Height = 400, 300, 200
+	shippingRates := getMockedShipping()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &shippingRates, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	cart, err := service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, cart.ShippingQuotes)
+	assert.Equal(t, usd(0), cart.Shipping())
+
+	assert.Equal(t, shipping.UnknownDestinationError, service.SetShipping(ctx, "us", ""))
+	assert.Nil(t, service.SetShipping(ctx, " de ", ""))
+	assert.Equal(t, "DE", repo.cards[0].ShippingCountry)
+	cart, err = service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []shipping.ShippingQuote{
+		{Zone: "eu", Method: "standard", Name: "Standard", Weight: 7200, Price: usd(1200)},
+		{Zone: "eu", Method: "parcel", Name: "Parcel", Weight: 7200, Price: usd(1500)},
+	}, cart.ShippingQuotes)
+	assert.Nil(t, cart.ShippingQuote)
+	assert.Equal(t, usd(50000), cart.GrandTotal())
+
+	assert.Equal(t, shipping.UnknownMethodError, service.SetShipping(ctx, "de", "courier"))
+	assert.Nil(t, service.SetShipping(ctx, "de", "parcel"))
+	cart, err = service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, usd(1500), cart.Shipping())
+	assert.Equal(t, usd(51500), cart.GrandTotal())
+
+	// the method chosen before is kept for another country of the zone
+	assert.Nil(t, service.SetShipping(ctx, "fr", ""))
+	assert.Equal(t, "parcel", repo.cards[0].ShippingMethod)
+
+	// more shoes are too heavy for parcels
+	assert.Nil(t, service.UpdateCartItemQuantity(ctx, 1, 10))
+	cart, err = service.GetCart(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, shipping.UnknownMethodError, cart.ShippingError)
+	assert.Nil(t, cart.ShippingQuote)
+	_, err = service.Checkout(ctx)
+	assert.Equal(t, shipping.UnknownMethodError, err)
+
+	assert.Nil(t, service.UpdateCartItemQuantity(ctx, 1, 3))
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "FR", placed.ShippingCountry)
+	assert.Equal(t, "parcel", placed.ShippingMethod)
+	assert.Equal(t, usd(1500), placed.Shipping)
+	assert.Equal(t, usd(51500), placed.Total)
+
+	otherCtx := session.WithSession(context.Background(), &session.Session{ID: "unknown"})
+	assert.Equal(t, CartNotFoundError, service.SetShipping(otherCtx, "de", ""))
+}
+
+func Test_service_Checkout_ShippingRequired(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	shippingRates := getMockedShipping()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &shippingRates, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	_, err := service.Checkout(ctx)
+	assert.Equal(t, ShippingMethodRequiredError, err)
+
+	// the free shipping threshold is reached
+	assert.Nil(t, service.AddItemToCart(ctx, "shoe", 1))
+	assert.Nil(t, service.SetShipping(ctx, "de", "standard"))
+	placed, err := service.Checkout(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, usd(0), placed.Shipping)
+	assert.Equal(t, usd(60000), placed.Total)
+}
+
+func Test_service_MergeCart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	// cart 2 belongs to user 7, who logs in from the session of cart 1
+	repo.cards[1].UserID = 7
+	repo.items = append(repo.items, entity.CartItem{
+		Model:       gorm.Model{ID: 4},
+		CartID:      2,
+		ProductID:   1,
+		ProductName: "shoe",
+		Quantity:    97,
+		Price:       productPrice["shoe"].Multiply(97),
+	})
+	productRepo := getMockedProductRepo()
+	stock := mockInventory{}
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &stock, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	sess := &session.Session{ID: sessionID, CartID: 1}
+	ctx := session.WithSession(context.Background(), sess)
+
+	assert.Nil(t, service.MergeCart(ctx, 7))
+	assert.Equal(t, uint(2), sess.CartID)
+	// the shoe dropped by the quantity limit is no longer reserved
+	assert.Equal(t, map[uint]int{1: -1}, stock.reserved)
+	assert.Equal(t, 1, len(repo.cards))
+
+	// the cart is found from any session of the user
+	otherCtx := session.WithSession(context.Background(), &session.Session{ID: "other", UserID: 7})
+	cart, err := service.GetCart(otherCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(2), cart.ID)
+	quantities := map[string]int{}
+	for _, item := range cart.Items {
+		quantities[item.ProductName] = item.Quantity
+	}
+	assert.Equal(t, map[string]int{"shoe": MaxQuantityPerItem, "purse": 1, "bag": 1}, quantities)
+	assert.Equal(t, productPrice["shoe"].Multiply(MaxQuantityPerItem).Add(usd(50000)), cart.Total)
+
+	// the anonymous session no longer has a cart
+	_, err = service.GetCart(ctx)
+	assert.Equal(t, CartNotFoundError, err)
+}
+
+func Test_service_MergeCart_CurrencyMismatch(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	repo.cards[1].UserID = 7
+	repo.cards[0].Total = money.New(50000, "EUR")
+	repo.items[0].Price = money.New(30000, "EUR")
+	repo.items[1].Price = money.New(20000, "EUR")
+	productRepo := getMockedProductRepo()
+	stock := mockInventory{}
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &stock, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	sess := &session.Session{ID: sessionID, CartID: 1}
+	ctx := session.WithSession(context.Background(), sess)
+
+	// the items priced in euros are dropped from the cart of the user, which is priced in dollars
+	assert.Nil(t, service.MergeCart(ctx, 7))
+	assert.Equal(t, uint(2), sess.CartID)
+	assert.Equal(t, map[uint]int{1: -3, 2: -1}, stock.reserved)
+	cart, err := service.GetCart(session.WithSession(context.Background(), &session.Session{ID: "other", UserID: 7}))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(cart.Items))
+	assert.Equal(t, usd(30000), cart.Total)
+}
+
+func Test_service_MergeCart_NoUserCart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	service := NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	sess := &session.Session{ID: sessionID}
+	ctx := session.WithSession(context.Background(), sess)
+
+	assert.Nil(t, service.MergeCart(ctx, 7))
+	assert.Equal(t, uint(1), sess.CartID)
+	assert.Equal(t, uint(7), repo.cards[0].UserID)
+	assert.Equal(t, 3, len(repo.items))
+
+	// a session without a cart keeps none
+	ctx = session.WithSession(context.Background(), &session.Session{ID: "other"})
+	assert.Nil(t, service.MergeCart(ctx, 8))
+}
+
+func Test_service_GetOrders(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	orderRepo := mockOrderRepo{}
+	for i := 0; i < 5; i++ {
+		orderRepo.orders = append(orderRepo.orders, entity.Order{
+			Model:     gorm.Model{ID: uint(i + 1), CreatedAt: day.AddDate(0, 0, i)},
+			SessionID: sessionID,
+			Status:    entity.OrderPlaced,
+		})
+	}
+	orderRepo.orders[4].SessionID = "987654321"
+	orderRepo.orders[3].SessionID = "logged in"
+	orderRepo.orders[3].UserID = 7
+	service := NewService(&repo, &productRepo, &orderRepo, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: sessionID})
+
+	page, err := service.GetOrders(ctx, OrderQuery{PerPage: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, []uint{3, 2}, orderIDs(page.Orders))
+	assert.False(t, page.HasPrevious())
+	assert.True(t, page.HasNext())
+
+	page, err = service.GetOrders(ctx, OrderQuery{Page: 2, PerPage: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{1}, orderIDs(page.Orders))
+	assert.True(t, page.HasPrevious())
+	assert.False(t, page.HasNext())
+
+	page, err = service.GetOrders(ctx, OrderQuery{From: day.AddDate(0, 0, 1), To: day.AddDate(0, 0, 2), Status: entity.OrderPlaced})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{2}, orderIDs(page.Orders))
+	assert.Equal(t, DefaultOrdersPerPage, page.PerPage)
+
+	// a logged in user sees the orders of the user, whichever session placed them
+	userCtx := session.WithSession(context.Background(), &session.Session{ID: "other", UserID: 7})
+	page, err = service.GetOrders(userCtx, OrderQuery{PerPage: MaxOrdersPerPage})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint{4}, orderIDs(page.Orders))
+	assert.Equal(t, MaxOrdersPerPage, page.PerPage)
+
+	// every status can be filtered by
+	for _, status := range entity.OrderStatuses {
+		_, err = service.GetOrders(ctx, OrderQuery{Status: status})
+		assert.Nil(t, err, status)
+	}
+	_, err = service.GetOrders(ctx, OrderQuery{Status: "lost"})
+	assert.Equal(t, InvalidOrderStatusError, err)
+	for _, query := range []OrderQuery{
+		{PerPage: MaxOrdersPerPage + 1},
+		{PerPage: -1},
+		{Page: -1},
+		{Page: MaxOrderPage + 1},
+		{Page: math.MaxInt, PerPage: MaxOrdersPerPage},
+	} {
+		_, err = service.GetOrders(ctx, query)
+		assert.Equal(t, PageOutOfRangeError, err, query)
+	}
+	_, err = service.GetOrders(ctx, OrderQuery{From: day, To: day})
+	assert.Equal(t, InvalidPeriodError, err)
+}
+
+func orderIDs(orders []entity.Order) []uint {
+	var ids []uint
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	return ids
 }
 
 func getMockedRepo() mockCartRepo {
@@ -83,36 +849,39 @@ func getMockedRepo() mockCartRepo {
 			Model:     gorm.Model{ID: 1},
 			SessionID: sessionID,
 			Status:    entity.CartOpen,
-			Total:     500,
+			Total:     usd(50000),
 		},
 		{
 			Model:     gorm.Model{ID: 2},
 			SessionID: "987654321",
 			Status:    entity.CartOpen,
-			Total:     300,
+			Total:     usd(30000),
 		},
 	}
 	items := []entity.CartItem{
 		{
 			Model:       gorm.Model{ID: 1},
 			CartID:      1,
+			ProductID:   1,
 			ProductName: "shoe",
 			Quantity:    3,
-			Price:       itemPriceMapping["shoe"] * 3,
+			Price:       productPrice["shoe"].Multiply(3),
 		},
 		{
 			Model:       gorm.Model{ID: 2},
 			CartID:      1,
+			ProductID:   2,
 			ProductName: "purse",
 			Quantity:    1,
-			Price:       itemPriceMapping["purse"],
+			Price:       productPrice["purse"],
 		},
 		{
 			Model:       gorm.Model{ID: 3},
 			CartID:      2,
+			ProductID:   3,
 			ProductName: "bag",
 			Quantity:    1,
-			Price:       itemPriceMapping["bag"],
+			Price:       productPrice["bag"],
 		},
 	}
 	repo := mockCartRepo{
@@ -122,14 +891,84 @@ func getMockedRepo() mockCartRepo {
 	return repo
 }
 
+func getMockedProductRepo() mockProductRepo {
+	var products []entity.Product
+	for i, name := range []string{"shoe", "purse", "bag", "watch"} {
+		products = append(products, entity.Product{
+			Model:  gorm.Model{ID: uint(i + 1)},
+			Name:   name,
+			Price:  productPrice[name],
+			Active: true,
+		})
+	}
+	return mockProductRepo{products: products}
+}
+
+func (m *mockProductRepo) QueryProduct(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Product, error) {
+	var products []entity.Product
+	for _, p := range m.products {
+		matched := true
+		for k, v := range conditions {
+			if k == "id" && containsID(v, p.ID) {
+				continue
+			}
+			if k == "name" && p.Name == v.(string) {
+				continue
+			}
+			if k == "active" && p.Active == v.(bool) {
+				continue
+			}
+			matched = false
+		}
+		if matched {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+// containsID reports whether a condition on an ID, a single ID or a slice of them, holds for the ID.
+func containsID(condition interface{}, id uint) bool {
+	if ids, ok := condition.([]uint); ok {
+		for _, v := range ids {
+			if v == id {
+				return true
+			}
+		}
+		return false
+	}
+	return condition.(uint) == id
+}
+
+func (m *mockProductRepo) CreateProduct(ctx context.Context, product *entity.Product) error {
+	product.ID = uint(len(m.products) + 1)
+	m.products = append(m.products, *product)
+	return nil
+}
+
+func (m *mockProductRepo) UpdateProduct(ctx context.Context, product *entity.Product) error {
+	for i, p := range m.products {
+		if p.ID == product.ID {
+			m.products[i] = *product
+		}
+	}
+	return nil
+}
+
 func (m *mockCartRepo) QueryCart(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.CartEntity, error) {
 	var carts []entity.CartEntity
 	for _, c := range m.cards {
 		matched := true
 		for k, v := range conditions {
+			if k == "id" && c.ID == v.(uint) {
+				continue
+			}
 			if k == "session_id" && c.SessionID == v.(string) {
 				continue
 			}
+			if k == "user_id" && c.UserID == v.(uint) {
+				continue
+			}
 			if k == "status" && c.Status == v.(entity.Status) {
 				continue
 			}
@@ -147,12 +986,15 @@ func (m *mockCartRepo) QueryCartItem(ctx context.Context, conditions map[string]
 	for _, c := range m.items {
 		matched := true
 		for k, v := range conditions {
-			if k == "ID" && c.CartID == v.(uint) {
+			if k == "id" && c.ID == v.(uint) {
 				continue
 			}
 			if k == "cart_id" && c.CartID == v.(uint) {
 				continue
 			}
+			if k == "product_name" && c.ProductName == v.(string) {
+				continue
+			}
 			matched = false
 		}
 		if matched {
@@ -162,6 +1004,11 @@ func (m *mockCartRepo) QueryCartItem(ctx context.Context, conditions map[string]
 	return items, nil
 }
 
+func (m *mockCartRepo) CountCart(ctx context.Context, conditions map[string]interface{}) (int64, error) {
+	carts, err := m.QueryCart(ctx, conditions, "", -1, -1)
+	return int64(len(carts)), err
+}
+
 func (m *mockCartRepo) CreateCart(ctx context.Context, cartEntity *entity.CartEntity) error {
 	cartEntity.ID = uint(len(m.cards) + 1)
 	m.cards = append(m.cards, *cartEntity)
@@ -183,6 +1030,16 @@ func (m *mockCartRepo) UpdateCart(ctx context.Context, cartEntity *entity.CartEn
 	return nil
 }
 
+func (m *mockCartRepo) UpdateCartStatus(ctx context.Context, id uint, from entity.Status, to entity.Status) (bool, error) {
+	for i, c := range m.cards {
+		if c.ID == id && c.Status == from {
+			m.cards[i].Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (m *mockCartRepo) UpdateCartItem(ctx context.Context, cartItem *entity.CartItem) error {
 	for i, c := range m.items {
 		if c.ID == cartItem.ID {
@@ -231,21 +1088,226 @@ func (m *mockCartRepo) DeleteCart(ctx context.Context, conditions map[string]int
 }
 
 func (m *mockCartRepo) DeleteCartItem(ctx context.Context, conditions map[string]interface{}) error {
-	for k, v := range conditions {
-		if k == "id" {
-			for i, c := range m.items {
-				if c.ID == v.(uint) {
-					m.items = append(m.items[:i], m.items[i+1:]...)
-				}
+	var items []entity.CartItem
+	for _, c := range m.items {
+		matched := true
+		for k, v := range conditions {
+			if k == "id" && c.ID == v.(uint) {
+				continue
 			}
+			if k == "cart_id" && c.CartID == v.(uint) {
+				continue
+			}
+			matched = false
 		}
-		if k == "cart_id" {
-			for i, c := range m.items {
-				if c.CartID == v.(uint) {
-					m.items = append(m.items[:i], m.items[i+1:]...)
-				}
+		if !matched {
+			items = append(items, c)
+		}
+	}
+	m.items = items
+	return nil
+}
+
+func (m *mockCartRepo) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *mockOrderRepo) QueryOrder(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Order, error) {
+	var orders []entity.Order
+	for _, o := range m.orders {
+		matched := true
+		for k, v := range conditions {
+			if k == "id" && o.ID == v.(uint) {
+				continue
+			}
+			if k == "session_id" && o.SessionID == v.(string) {
+				continue
 			}
+			if k == "user_id" && o.UserID == v.(uint) {
+				continue
+			}
+			if k == "status" && o.Status == v.(entity.OrderStatus) {
+				continue
+			}
+			matched = false
+		}
+		if matched {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (m *mockOrderRepo) QueryOrderInPeriod(ctx context.Context, conditions map[string]interface{}, period order.Period, order string, limit int, offset int) ([]entity.Order, error) {
+	orders, _ := m.QueryOrder(ctx, conditions, order, -1, -1)
+	var selected []entity.Order
+	for _, o := range orders {
+		if (period.From.IsZero() || !o.CreatedAt.Before(period.From)) && (period.To.IsZero() || o.CreatedAt.Before(period.To)) {
+			selected = append(selected, o)
+		}
+	}
+	// most recent first, as requested by the order history
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].CreatedAt.After(selected[j].CreatedAt) })
+	if offset > len(selected) {
+		offset = len(selected)
+	}
+	selected = selected[offset:]
+	if limit >= 0 && limit < len(selected) {
+		selected = selected[:limit]
+	}
+	return selected, nil
+}
+
+func (m *mockOrderRepo) CountOrderInPeriod(ctx context.Context, conditions map[string]interface{}, period order.Period) (int64, error) {
+	orders, err := m.QueryOrderInPeriod(ctx, conditions, period, "", -1, 0)
+	return int64(len(orders)), err
+}
+
+func (m *mockOrderRepo) QueryOrderLine(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.OrderLine, error) {
+	var lines []entity.OrderLine
+	for _, l := range m.lines {
+		if orderID, ok := conditions["order_id"]; ok && l.OrderID != orderID.(uint) {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+func (m *mockOrderRepo) CreateOrder(ctx context.Context, orderEntity *entity.Order) error {
+	orderEntity.ID = uint(len(m.orders) + 1)
+	if orderEntity.CreatedAt.IsZero() {
+		orderEntity.CreatedAt = time.Now()
+	}
+	m.orders = append(m.orders, *orderEntity)
+	return nil
+}
+
+func (m *mockOrderRepo) CreateOrderLine(ctx context.Context, orderLine *entity.OrderLine) error {
+	orderLine.ID = uint(len(m.lines) + 1)
+	m.lines = append(m.lines, *orderLine)
+	return nil
+}
+
+func (m *mockOrderRepo) QueryOrderTaxLine(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.OrderTaxLine, error) {
+	var taxLines []entity.OrderTaxLine
+	for _, l := range m.taxLines {
+		if orderID, ok := conditions["order_id"]; ok && l.OrderID != orderID.(uint) {
+			continue
+		}
+		taxLines = append(taxLines, l)
+	}
+	return taxLines, nil
+}
+
+func (m *mockOrderRepo) CreateOrderTaxLine(ctx context.Context, taxLine *entity.OrderTaxLine) error {
+	taxLine.ID = uint(len(m.taxLines) + 1)
+	m.taxLines = append(m.taxLines, *taxLine)
+	return nil
+}
+
+func (m *mockInventory) init() {
+	if m.reserved == nil {
+		m.reserved = map[uint]int{}
+		m.committed = map[uint]int{}
+	}
+}
+
+func (m *mockInventory) Reserve(ctx context.Context, productID uint, quantity int) error {
+	if available, ok := m.available[productID]; ok {
+		if available < quantity {
+			return inventory.OutOfStockError
 		}
+		m.available[productID] -= quantity
 	}
+	m.init()
+	m.reserved[productID] += quantity
 	return nil
 }
+
+func (m *mockInventory) Release(ctx context.Context, productID uint, quantity int) error {
+	if _, ok := m.available[productID]; ok {
+		m.available[productID] += quantity
+	}
+	m.init()
+	m.reserved[productID] -= quantity
+	return nil
+}
+
+func (m *mockInventory) Commit(ctx context.Context, productID uint, quantity int) error {
+	m.init()
+	m.reserved[productID] -= quantity
+	m.committed[productID] += quantity
+	return nil
+}
+
+func (m *mockPromotions) Evaluate(ctx context.Context, code string, items []entity.CartItem) (promotion.Discount, error) {
+	for _, coupon := range m.coupons {
+		if coupon.Code == promotion.NormalizeCode(code) {
+			return promotion.Evaluate(coupon, items, time.Now())
+		}
+	}
+	return promotion.Discount{}, promotion.CouponNotFoundError
+}
+
+func (m *mockPromotions) Redeem(ctx context.Context, code string) error {
+	for i, coupon := range m.coupons {
+		if coupon.Code == code {
+			if coupon.MaxUses > 0 && coupon.Uses >= coupon.MaxUses {
+				return promotion.CouponUsedUpError
+			}
+			m.coupons[i].Uses++
+			return nil
+		}
+	}
+	return promotion.CouponNotFoundError
+}
+
+func (m *mockTaxes) Calculate(ctx context.Context, region string, lines []tax.Line) (tax.Breakdown, error) {
+	settings := m.settings
+	if region != "" {
+		settings.Region = region
+	}
+	var rates []entity.TaxRate
+	for _, rate := range m.rates {
+		if rate.Region == settings.Region {
+			rates = append(rates, rate)
+		}
+	}
+	if len(rates) == 0 && settings.Region != m.settings.Region {
+		return tax.Breakdown{}, tax.UnknownRegionError
+	}
+	return tax.Calculate(lines, rates, settings), nil
+}
+
+func (m *mockTaxes) Regions(ctx context.Context) ([]string, error) {
+	var regions []string
+	for _, rate := range m.rates {
+		if !slices.Contains(regions, rate.Region) {
+			regions = append(regions, rate.Region)
+		}
+	}
+	return regions, nil
+}
+
+func (m *mockShipping) Quotes(ctx context.Context, country string, items []shipping.Item, subtotal money.Money) ([]shipping.ShippingQuote, error) {
+	zone, ok := shipping.ZoneFor(m.zones, country)
+	if !ok {
+		return nil, shipping.UnknownDestinationError
+	}
+	var rates []entity.ShippingRate
+	for _, rate := range m.rates {
+		if rate.ZoneID == zone.ID {
+			rates = append(rates, rate)
+		}
+	}
+	quotes := shipping.Quote(zone, rates, shipping.ParcelWeight(items), subtotal)
+	if len(quotes) == 0 {
+		return nil, shipping.NotShippableError
+	}
+	return quotes, nil
+}
+
+func (m *mockShipping) Required(ctx context.Context) (bool, error) {
+	return len(m.zones) > 0, nil
+}
@@ -0,0 +1,107 @@
+package cart
+
+import (
+	"context"
+	"errors"
+
+	"interview/pkg/shipping"
+)
+
+// SetShipping sets the destination of the open cart and the shipping method chosen for it. Without a method the
+// method chosen before is kept if it is still available for the destination. An empty country clears both.
+// A destination or method the cart cannot be shipped with is refused, e.g. with shipping.UnknownDestinationError.
+func (s service) SetShipping(ctx context.Context, country string, method string) error {
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, cartItems, err := s.getCartWithItems(ctx)
+		if err != nil {
+			return err
+		}
+		chosen := method != ""
+		if !chosen {
+			method = cartEntity.ShippingMethod
+		}
+		cartEntity.ShippingCountry = shipping.NormalizeCountry(country)
+		cartEntity.ShippingMethod = ""
+		if cartEntity.ShippingCountry != "" {
+			cart := Cart{CartEntity: cartEntity, Items: cartItems}
+			if err := s.evaluateCoupon(ctx, &cart); err != nil {
+				return err
+			}
+			quotes, err := s.quoteShipping(ctx, cart)
+			if err != nil {
+				return err
+			}
+			if _, ok := findQuote(quotes, method); ok {
+				cartEntity.ShippingMethod = method
+			} else if chosen {
+				return shipping.UnknownMethodError
+			}
+		}
+		if err := s.repo.UpdateCart(ctx, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error setting cart shipping: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+// quoteShipping returns the shipping methods available for the items of the cart to its destination.
+func (s service) quoteShipping(ctx context.Context, cart Cart) ([]shipping.ShippingQuote, error) {
+	products, err := s.productsOf(ctx, cart.Items)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]shipping.Item, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		product := products[item.ProductID]
+		items = append(items, shipping.Item{
+			Quantity: item.Quantity,
+			Weight:   product.Weight,
+			Length:   product.Length,
+			Width:    product.Width,
+			Height:   product.Height,
+		})
+	}
+	quotes, err := s.shipping.Quotes(ctx, cart.ShippingCountry, items, cart.Total.Sub(cart.Discount()))
+	if errors.Is(err, shipping.InternalError) {
+		return nil, InternalError
+	}
+	return quotes, err
+}
+
+// estimateShipping sets the shipping quotes of the cart and the quote of its shipping method. A destination or
+// method that is no longer available, e.g. because items were added, stays on the cart without a quote, and
+// ShippingError tells why.
+func (s service) estimateShipping(ctx context.Context, cart *Cart) error {
+	if cart.ShippingCountry == "" {
+		return nil
+	}
+	quotes, err := s.quoteShipping(ctx, *cart)
+	if errors.Is(err, InternalError) {
+		return err
+	}
+	if err != nil {
+		cart.ShippingError = err
+		return nil
+	}
+	cart.ShippingQuotes = quotes
+	if cart.ShippingMethod == "" {
+		return nil
+	}
+	quote, ok := findQuote(quotes, cart.ShippingMethod)
+	if !ok {
+		cart.ShippingError = shipping.UnknownMethodError
+		return nil
+	}
+	cart.ShippingQuote = &quote
+	return nil
+}
+
+func findQuote(quotes []shipping.ShippingQuote, method string) (shipping.ShippingQuote, bool) {
+	for _, quote := range quotes {
+		if quote.Method == method {
+			return quote, true
+		}
+	}
+	return shipping.ShippingQuote{}, false
+}
@@ -0,0 +1,80 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"interview/pkg/tax"
+)
+
+// SetRegion sets the tax region of the open cart. An empty region selects the default region.
+// A region without tax rates is refused with tax.UnknownRegionError.
+func (s service) SetRegion(ctx context.Context, region string) error {
+	region = tax.NormalizeName(region)
+	if region != "" {
+		regions, err := s.taxes.Regions(ctx)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error getting tax regions: %v", err)
+			return InternalError
+		}
+		if !slices.Contains(regions, region) {
+			return tax.UnknownRegionError
+		}
+	}
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		cartEntity, err := s.getCart(ctx)
+		if errors.Is(err, CartNotFoundError) {
+			return err
+		}
+		if err != nil {
+			s.logger.With(ctx).Errorf("error getting cart: %v", err)
+			return InternalError
+		}
+		if cartEntity.Region == region {
+			return nil
+		}
+		cartEntity.Region = region
+		if err := s.repo.UpdateCart(ctx, &cartEntity); err != nil {
+			s.logger.With(ctx).Errorf("error setting cart region: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+// GetRegions returns the tax regions customers can choose for their carts.
+func (s service) GetRegions(ctx context.Context) []string {
+	regions, err := s.taxes.Regions(ctx)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error getting tax regions: %v", err)
+	}
+	return regions
+}
+
+// calculateTax sets the taxes of the cart from its items less their share of the discounts, in the tax categories
+// of their products. A cart whose region lost its tax rates is taxed in the default region.
+func (s service) calculateTax(ctx context.Context, cart *Cart) error {
+	products, err := s.productsOf(ctx, cart.Items)
+	if err != nil {
+		return err
+	}
+	lines := make([]tax.Line, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		amount := item.Price
+		for _, discount := range cart.Discounts {
+			amount = amount.Sub(discount.Items[item.ID])
+		}
+		lines = append(lines, tax.Line{Category: products[item.ProductID].TaxCategory, Amount: amount})
+	}
+	breakdown, err := s.taxes.Calculate(ctx, cart.Region, lines)
+	if errors.Is(err, tax.UnknownRegionError) {
+		breakdown, err = s.taxes.Calculate(ctx, "", lines)
+	}
+	if err != nil {
+		s.logger.With(ctx).Errorf("error calculating tax: %v", err)
+		return InternalError
+	}
+	cart.Taxes = breakdown
+	return nil
+}
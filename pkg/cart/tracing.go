@@ -0,0 +1,162 @@
+package cart
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"interview/internal/utils"
+	"interview/pkg/order"
+)
+
+var tracer = otel.Tracer("interview/pkg/cart")
+
+type tracedService struct {
+	Service
+}
+
+// NewTracedService returns a Service that records every call to the given service as an OpenTelemetry span.
+// The span is started from the context of the call, so the repository queries of the call become its children.
+func NewTracedService(service Service) Service {
+	return tracedService{service}
+}
+
+func (s tracedService) GetCart(ctx context.Context) (Cart, error) {
+	ctx, span := tracer.Start(ctx, "cart.GetCart")
+	cart, err := s.Service.GetCart(ctx)
+	endSpan(span, err)
+	return cart, err
+}
+
+func (s tracedService) GetCartItems(ctx context.Context) []map[string]interface{} {
+	ctx, span := tracer.Start(ctx, "cart.GetCartItems")
+	defer span.End()
+	return s.Service.GetCartItems(ctx)
+}
+
+func (s tracedService) GetProducts(ctx context.Context) []string {
+	ctx, span := tracer.Start(ctx, "cart.GetProducts")
+	defer span.End()
+	return s.Service.GetProducts(ctx)
+}
+
+func (s tracedService) AddItemToCart(ctx context.Context, product string, qty int) error {
+	ctx, span := tracer.Start(ctx, "cart.AddItemToCart", trace.WithAttributes(
+		attribute.String("cart.product", product),
+		attribute.Int("cart.quantity", qty),
+	))
+	err := s.Service.AddItemToCart(ctx, product, qty)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) UpdateCartItemQuantity(ctx context.Context, cartItemID uint, qty int) error {
+	ctx, span := tracer.Start(ctx, "cart.UpdateCartItemQuantity", trace.WithAttributes(
+		attribute.Int64("cart.item_id", int64(cartItemID)),
+		attribute.Int("cart.quantity", qty),
+	))
+	err := s.Service.UpdateCartItemQuantity(ctx, cartItemID, qty)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) DeleteCartItem(ctx context.Context, cartItemID uint) error {
+	ctx, span := tracer.Start(ctx, "cart.DeleteCartItem", trace.WithAttributes(
+		attribute.Int64("cart.item_id", int64(cartItemID)),
+	))
+	err := s.Service.DeleteCartItem(ctx, cartItemID)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) MergeCart(ctx context.Context, userID uint) error {
+	ctx, span := tracer.Start(ctx, "cart.MergeCart", trace.WithAttributes(
+		attribute.Int64("user.id", int64(userID)),
+	))
+	err := s.Service.MergeCart(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) ApplyCoupon(ctx context.Context, code string) error {
+	ctx, span := tracer.Start(ctx, "cart.ApplyCoupon", trace.WithAttributes(
+		attribute.String("cart.coupon", code),
+	))
+	err := s.Service.ApplyCoupon(ctx, code)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) RemoveCoupon(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "cart.RemoveCoupon")
+	err := s.Service.RemoveCoupon(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) SetRegion(ctx context.Context, region string) error {
+	ctx, span := tracer.Start(ctx, "cart.SetRegion", trace.WithAttributes(
+		attribute.String("cart.region", region),
+	))
+	err := s.Service.SetRegion(ctx, region)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) SetShipping(ctx context.Context, country string, method string) error {
+	ctx, span := tracer.Start(ctx, "cart.SetShipping", trace.WithAttributes(
+		attribute.String("cart.shipping_country", country),
+		attribute.String("cart.shipping_method", method),
+	))
+	err := s.Service.SetShipping(ctx, country, method)
+	endSpan(span, err)
+	return err
+}
+
+func (s tracedService) Checkout(ctx context.Context) (order.Order, error) {
+	ctx, span := tracer.Start(ctx, "cart.Checkout")
+	placed, err := s.Service.Checkout(ctx)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("order.id", int64(placed.ID)))
+	}
+	endSpan(span, err)
+	return placed, err
+}
+
+func (s tracedService) GetOrder(ctx context.Context, orderID uint) (order.Order, error) {
+	ctx, span := tracer.Start(ctx, "cart.GetOrder", trace.WithAttributes(
+		attribute.Int64("order.id", int64(orderID)),
+	))
+	placed, err := s.Service.GetOrder(ctx, orderID)
+	endSpan(span, err)
+	return placed, err
+}
+
+func (s tracedService) GetOrders(ctx context.Context, query OrderQuery) (OrderPage, error) {
+	ctx, span := tracer.Start(ctx, "cart.GetOrders", trace.WithAttributes(
+		attribute.Int("orders.page", query.Page),
+	))
+	page, err := s.Service.GetOrders(ctx, query)
+	endSpan(span, err)
+	return page, err
+}
+
+// endSpan records the error returned by a traced call, if any, and ends its span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// renderTemplate renders an HTML template within a span, so that slow pages can be told apart from slow queries.
+func renderTemplate(ctx context.Context, data interface{}, templateName string) (string, error) {
+	_, span := tracer.Start(ctx, "render "+templateName)
+	html, err := utils.RenderTemplate(ctx, data, templateName)
+	endSpan(span, err)
+	return html, err
+}
@@ -0,0 +1,44 @@
+package cart
+
+import (
+	"context"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	logger, _ := log.NewForTest()
+	repo := getMockedRepo()
+	productRepo := getMockedProductRepo()
+	service := NewTracedService(NewService(&repo, &productRepo, &mockOrderRepo{}, &mockInventory{}, &mockPromotions{}, &mockTaxes{}, &mockShipping{}, logger))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	ctx = session.WithSession(ctx, &session.Session{ID: sessionID})
+
+	assert.Nil(t, service.AddItemToCart(ctx, "watch", 1))
+	assert.Equal(t, NegativeQuantityError, service.UpdateCartItemQuantity(ctx, 1, -1))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Equal(t, 3, len(spans))
+	assert.Equal(t, "cart.AddItemToCart", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, "cart.UpdateCartItemQuantity", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	for _, span := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
@@ -1,12 +1,13 @@
 package db
 
 import (
+	"bytes"
 	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
-	"interview/pkg/entity"
 	"interview/pkg/log"
 )
 
@@ -33,40 +34,126 @@ func (db *DB) DB() *gorm.DB {
 	return db.db
 }
 
+// Ping checks that the database is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	sqlDB, err := db.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 // With returns a Builder that can be used to build and execute SQL queries.
 // With will return the transaction if it is found in the given context.
 // Otherwise it will return a DB connection associated with the context.
+// Either way the queries run with the given context, so they are cancelled and traced along with it.
 func (db *DB) With(ctx context.Context) *gorm.DB {
 	if tx, ok := ctx.Value(txKey).(*gorm.DB); ok {
-		return tx
+		return tx.WithContext(ctx)
 	}
 	return db.db.WithContext(ctx)
 }
 
 // Transactional starts a transaction and calls the given function with a context storing the transaction.
 // The transaction associated with the context can be accesse via With().
+// If the context already carries a transaction (e.g. one started by TransactionHandler), a nested
+// transaction is started on it using a savepoint instead of opening a second connection.
 func (db *DB) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
-	return db.db.Transaction(func(tx *gorm.DB) error {
+	return db.With(ctx).Transaction(func(tx *gorm.DB) error {
 		return f(context.WithValue(ctx, txKey, tx))
 	})
 }
 
 // TransactionHandler returns a middleware that starts a transaction.
 // The transaction started is kept in the context and can be accessed via With().
+// The transaction is rolled back when the handlers record an error in the gin context, and committed otherwise.
+// The response is held back until the transaction is committed, so that a request whose transaction cannot be
+// started or committed gets a 500 response instead of the one written by its handlers.
 func (db *DB) TransactionHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		db.db.Transaction(func(tx *gorm.DB) error {
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		var handlerErr error
+		err := db.db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 			ctx := context.WithValue(c.Request.Context(), txKey, tx)
 			c.Request = c.Request.WithContext(ctx)
 			c.Next()
 			if c.Errors.Errors() != nil {
-				return c.Errors.Last()
+				handlerErr = c.Errors.Last()
+				return handlerErr
 			}
 			return nil
 		})
+		c.Writer = w.ResponseWriter
+		if err != nil && err != handlerErr {
+			db.logger.With(c.Request.Context()).Errorf("error running the transaction of the request: %v", err)
+			c.Writer.Header().Del("Location")
+			c.Writer.Header().Del("Content-Type")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		w.flush()
 	}
 }
 
-func (db *DB) MigrateDatabase() error {
-	return db.db.AutoMigrate(&entity.CartEntity{}, &entity.CartItem{})
+// bufferedWriter holds back the status and body written to a response until they are flushed.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+func (w *bufferedWriter) Flush() {
+	w.WriteHeaderNow()
+}
+
+// flush writes the status and body held back to the response.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if !w.written {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
 }
@@ -2,8 +2,6 @@ package db
 
 import (
 	"context"
-	"interview/internal/config"
-	"interview/internal/utils"
 	"interview/pkg/log"
 	"net/http"
 	"net/http/httptest"
@@ -24,6 +22,14 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestDB_Ping(t *testing.T) {
+	runDBTest(t, func(db *gorm.DB) {
+		logger, _ := log.NewForTest()
+		dbc := New(db, logger)
+		assert.Nil(t, dbc.Ping(context.Background()))
+	})
+}
+
 func TestDB_Transactional(t *testing.T) {
 	runDBTest(t, func(db *gorm.DB) {
 		assert.Zero(t, successfulQueryCount(t, db))
@@ -52,6 +58,11 @@ func TestDB_Transactional(t *testing.T) {
 		assert.Equal(t, gorm.ErrRecordNotFound, err)
 		assert.Equal(t, 2, successfulQueryCount(t, db))
 
+		// SQLite allows only one writer at a time, so writing outside of an open write transaction would block.
+		if db.Dialector.Name() == "sqlite" {
+			return
+		}
+
 		// failed transaction, but queries made outside of the transaction
 		err = dbc.Transactional(context.Background(), func(ctx context.Context) error {
 			err := dbc.With(context.Background()).Exec("INSERT INTO dbcontexttest (id, name) VALUES(?, ?)", "3", "name1")
@@ -109,28 +120,84 @@ func TestDB_TransactionHandler(t *testing.T) {
 
 			assert.Equal(t, 2, successfulQueryCount(t, db))
 		}
+
+		// the response is held back until the transaction is committed
+		{
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			req, _ := http.NewRequest("POST", "/", nil)
+			engine.Use(txHandler)
+			engine.POST("/", func(c *gin.Context) {
+				c.Header("Location", "/items/5")
+				c.JSON(http.StatusCreated, gin.H{"id": 5})
+				assert.Zero(t, w.Body.Len())
+				assert.Equal(t, http.StatusCreated, c.Writer.Status())
+			})
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, `{"id":5}`, w.Body.String())
+			assert.Equal(t, "/items/5", w.Header().Get("Location"))
+		}
+
+		// failed commit
+		{
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			req, _ := http.NewRequest("POST", "/", nil)
+			engine.Use(txHandler)
+			engine.POST("/", func(c *gin.Context) {
+				ctx := c.Request.Context()
+				err := dbc.With(ctx).Exec("INSERT INTO dbcontexttest (id, name) VALUES(?, ?)", "5", "name5")
+				assert.Nil(t, err.Error)
+				// ends the transaction, so that the handler cannot commit it
+				assert.Nil(t, dbc.With(ctx).Rollback().Error)
+				c.Header("Location", "/items/5")
+				c.JSON(http.StatusCreated, gin.H{"id": 5})
+			})
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Empty(t, w.Body.String())
+			assert.Empty(t, w.Header().Get("Location"))
+			assert.Equal(t, 2, successfulQueryCount(t, db))
+		}
+
+		// transaction not started
+		{
+			w := httptest.NewRecorder()
+			_, engine := gin.CreateTestContext(w)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req, _ := http.NewRequestWithContext(ctx, "POST", "/", nil)
+			handled := false
+			engine.Use(txHandler)
+			engine.POST("/", func(c *gin.Context) {
+				handled = true
+				c.Status(http.StatusCreated)
+			})
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.False(t, handled)
+		}
 	})
 }
 
 func runDBTest(t *testing.T, f func(db *gorm.DB)) {
 	logger, _ := log.NewForTest()
-	cfg, err := config.Load("test.yml", logger)
-	if err != nil {
-		t.Error(err)
-		t.FailNow()
-	}
-	db, err := utils.GetDBConnection(cfg.DSN)
+	db, closeDB, err := OpenForTest(logger)
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
 	}
 	defer func() {
-		_ = utils.CloseDBConnection(db)
+		_ = closeDB()
 	}()
 
 	sqls := []string{
 		"CREATE TABLE IF NOT EXISTS dbcontexttest (id INT NOT NULL, name varchar(255), PRIMARY KEY (id))",
-		"TRUNCATE dbcontexttest",
+		"DELETE FROM dbcontexttest",
 	}
 	for _, s := range sqls {
 		tx := db.Exec(s)
@@ -0,0 +1,290 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MigrationLockedError is returned when another process holds the migration lock for longer than the lock wait time.
+var MigrationLockedError = errors.New("migrations are locked by another process")
+
+// PendingMigrationsError is returned by Migrator.Check when the schema is not up to date.
+var PendingMigrationsError = errors.New("migrations are pending")
+
+// IrreversibleMigrationError is returned when rolling back a migration that has no Down function.
+var IrreversibleMigrationError = errors.New("migration cannot be rolled back")
+
+const (
+	defaultLockWait    = 30 * time.Second
+	defaultLockExpiry  = 10 * time.Minute
+	lockPollInterval   = 500 * time.Millisecond
+	migrationLockRowID = 1
+)
+
+// Migration is a versioned change of the database schema or data.
+type Migration struct {
+	// Version orders the migrations. It must be unique and never change once the migration has been applied.
+	Version int
+	// Name describes the migration.
+	Name string
+	// Up applies the migration.
+	Up func(tx *gorm.DB) error
+	// Down reverts the migration. A nil Down makes the migration irreversible.
+	Down func(tx *gorm.DB) error
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is the time the migration was applied, or nil if it is pending.
+	AppliedAt *time.Time
+}
+
+// schemaMigration records an applied migration.
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaMigrationLock is a single-row table whose row exists while a process is migrating.
+type schemaMigrationLock struct {
+	ID       int `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Migrator applies and reverts migrations and keeps track of them in the schema_migrations table.
+// Concurrent migrators, e.g. in several instances starting at the same time, are serialized through
+// the schema_migrations_lock table.
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+	owner      string
+	// LockWait is how long to wait for another process to release the migration lock.
+	LockWait time.Duration
+	// LockExpiry is the age after which a lock is considered abandoned, e.g. by a crashed process.
+	LockExpiry time.Duration
+}
+
+// NewMigrator returns a Migrator for the given migrations.
+func NewMigrator(db *DB, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), uuid.New().String()),
+		LockWait:   defaultLockWait,
+		LockExpiry: defaultLockExpiry,
+	}
+}
+
+// Up applies up to steps pending migrations in version order and returns the applied migrations.
+// If steps is zero or negative, all pending migrations are applied.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func() error {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if steps > 0 && steps < len(pending) {
+			pending = pending[:steps]
+		}
+		for _, migration := range pending {
+			m.db.logger.Infof("applying migration %d %s", migration.Version, migration.Name)
+			err := m.db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d %s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts up to steps applied migrations, newest first, and returns the reverted migrations.
+// If steps is zero or negative, one migration is reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	var reverted []Migration
+	err := m.withLock(ctx, func() error {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := statuses[i]
+			if migration.AppliedAt == nil {
+				continue
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, IrreversibleMigrationError)
+			}
+			m.db.logger.Infof("reverting migration %d %s", migration.Version, migration.Name)
+			err := m.db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d %s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration.Migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns every known migration in version order along with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db := m.db.db.WithContext(ctx)
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := map[int]time.Time{}
+	for _, record := range records {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if t, ok := appliedAt[migration.Version]; ok {
+			t := t
+			status.AppliedAt = &t
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet, in version order.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns an error if migrations are pending or the applied migrations cannot be read.
+// Unlike Pending it never creates the schema_migrations table, which keeps it cheap enough for readiness probes.
+func (m *Migrator) Check(ctx context.Context) error {
+	var versions []int
+	if err := m.db.db.WithContext(ctx).Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return err
+	}
+	applied := map[int]bool{}
+	for _, version := range versions {
+		applied[version] = true
+	}
+	pending := 0
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d %w", pending, PendingMigrationsError)
+	}
+	return nil
+}
+
+// withLock runs f while holding the migration lock.
+func (m *Migrator) withLock(ctx context.Context, f func() error) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		err := m.db.db.WithContext(context.Background()).
+			Where("id = ? AND owner = ?", migrationLockRowID, m.owner).
+			Delete(&schemaMigrationLock{}).Error
+		if err != nil {
+			m.db.logger.Errorf("error releasing migration lock: %v", err)
+		}
+	}()
+	return f()
+}
+
+func (m *Migrator) lock(ctx context.Context) error {
+	db := m.db.db.WithContext(ctx)
+	if err := db.AutoMigrate(&schemaMigrationLock{}); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(m.LockWait)
+	for {
+		err := db.Create(&schemaMigrationLock{ID: migrationLockRowID, Owner: m.owner, LockedAt: time.Now()}).Error
+		if err == nil {
+			return nil
+		}
+
+		var held []schemaMigrationLock
+		if findErr := db.Where("id = ?", migrationLockRowID).Find(&held).Error; findErr != nil {
+			return findErr
+		}
+		if len(held) == 0 {
+			// the lock was released in the meantime, or inserting failed for another reason
+			if time.Now().After(deadline) {
+				return err
+			}
+		} else if time.Since(held[0].LockedAt) > m.LockExpiry {
+			m.db.logger.Infof("removing migration lock abandoned by %s", held[0].Owner)
+			err := db.Where("id = ? AND owner = ?", migrationLockRowID, held[0].Owner).
+				Delete(&schemaMigrationLock{}).Error
+			if err != nil {
+				return err
+			}
+			continue
+		} else if time.Now().After(deadline) {
+			return fmt.Errorf("%w (held by %s since %s)", MigrationLockedError, held[0].Owner, held[0].LockedAt.Format(time.RFC3339))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"interview/pkg/log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testMigrations(log *[]string) []Migration {
+	step := func(name string) func(tx *gorm.DB) error {
+		return func(tx *gorm.DB) error {
+			*log = append(*log, name)
+			return nil
+		}
+	}
+	return []Migration{
+		{Version: 2, Name: "second", Up: step("up 2"), Down: step("down 2")},
+		{Version: 1, Name: "first", Up: step("up 1"), Down: step("down 1")},
+		{Version: 3, Name: "third", Up: step("up 3")},
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	runDBTest(t, func(db *gorm.DB) {
+		ctx := context.Background()
+		logger, _ := log.NewForTest()
+		assert.Nil(t, db.Migrator().DropTable(&schemaMigration{}, &schemaMigrationLock{}))
+		var steps []string
+		migrator := NewMigrator(New(db, logger), testMigrations(&steps))
+
+		assert.NotNil(t, migrator.Check(ctx))
+
+		pending, err := migrator.Pending(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(pending))
+		assert.True(t, errors.Is(migrator.Check(ctx), PendingMigrationsError))
+
+		applied, err := migrator.Up(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(applied))
+		assert.Equal(t, []string{"up 1"}, steps)
+
+		applied, err = migrator.Up(ctx, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		assert.Equal(t, []string{"up 1", "up 2", "up 3"}, steps)
+
+		assert.Nil(t, migrator.Check(ctx))
+
+		statuses, err := migrator.Status(ctx)
+		assert.Nil(t, err)
+		for _, status := range statuses {
+			assert.NotNil(t, status.AppliedAt)
+		}
+
+		// the third migration has no Down function
+		_, err = migrator.Down(ctx, 1)
+		assert.True(t, errors.Is(err, IrreversibleMigrationError))
+
+		migrator.migrations[2].Down = func(tx *gorm.DB) error { return nil }
+		reverted, err := migrator.Down(ctx, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []int{3, 2}, []int{reverted[0].Version, reverted[1].Version})
+		assert.Equal(t, []string{"up 1", "up 2", "up 3", "down 2"}, steps)
+
+		pending, err = migrator.Pending(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(pending))
+
+		// a failing migration is not recorded
+		failure := errors.New("failure")
+		migrator.migrations[1].Up = func(tx *gorm.DB) error { return failure }
+		_, err = migrator.Up(ctx, 0)
+		assert.True(t, errors.Is(err, failure))
+		pending, err = migrator.Pending(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(pending))
+	})
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	runDBTest(t, func(db *gorm.DB) {
+		ctx := context.Background()
+		logger, _ := log.NewForTest()
+		assert.Nil(t, db.Migrator().DropTable(&schemaMigration{}, &schemaMigrationLock{}))
+		var steps []string
+		first := NewMigrator(New(db, logger), testMigrations(&steps))
+		second := NewMigrator(New(db, logger), testMigrations(&steps))
+		second.LockWait = 0
+
+		assert.Nil(t, first.lock(ctx))
+		_, err := second.Up(ctx, 0)
+		assert.True(t, errors.Is(err, MigrationLockedError))
+		assert.Empty(t, steps)
+
+		// an abandoned lock is taken over
+		second.LockExpiry = 0
+		time.Sleep(time.Millisecond)
+		_, err = second.Up(ctx, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(steps))
+
+		// the lock is released after migrating
+		first.LockWait = 0
+		_, err = first.Up(ctx, 0)
+		assert.Nil(t, err)
+	})
+}
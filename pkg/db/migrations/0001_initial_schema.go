@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// The schema as it was created by AutoMigrate before versioned migrations were introduced.
+// Applying this migration to such a database only adds what is missing.
+
+type amount0001 struct {
+	Amount   int64
+	Currency string `gorm:"size:3"`
+}
+
+type product0001 struct {
+	gorm.Model
+	Name   string     `gorm:"uniqueIndex;size:255"`
+	SKU    string     `gorm:"uniqueIndex;size:64"`
+	Price  amount0001 `gorm:"embedded;embeddedPrefix:price_"`
+	Active bool
+}
+
+func (product0001) TableName() string { return "products" }
+
+type cartEntity0001 struct {
+	gorm.Model
+	Total     amount0001 `gorm:"embedded;embeddedPrefix:total_"`
+	SessionID string
+	Status    string `gorm:"size:16"`
+}
+
+func (cartEntity0001) TableName() string { return "cart_entities" }
+
+type cartItem0001 struct {
+	gorm.Model
+	CartID      uint
+	ProductID   uint
+	ProductName string
+	Quantity    int
+	Price       amount0001 `gorm:"embedded;embeddedPrefix:price_"`
+}
+
+func (cartItem0001) TableName() string { return "cart_items" }
+
+type order0001 struct {
+	gorm.Model
+	CartID    uint
+	SessionID string
+	Total     amount0001 `gorm:"embedded;embeddedPrefix:total_"`
+	Status    string     `gorm:"size:16"`
+}
+
+func (order0001) TableName() string { return "orders" }
+
+type orderLine0001 struct {
+	gorm.Model
+	OrderID     uint
+	ProductID   uint
+	ProductName string
+	Quantity    int
+	Price       amount0001 `gorm:"embedded;embeddedPrefix:price_"`
+}
+
+func (orderLine0001) TableName() string { return "order_lines" }
+
+func init() {
+	register(db.Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&product0001{},
+				&cartEntity0001{},
+				&cartItem0001{},
+				&order0001{},
+				&orderLine0001{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&orderLine0001{},
+				&order0001{},
+				&cartItem0001{},
+				&cartEntity0001{},
+				&product0001{},
+			)
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"fmt"
+
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// Amounts used to be stored in float64 columns in major units (e.g. dollars). This migration copies
+// them into the <column>_amount and <column>_currency columns and drops the float columns.
+// Databases created after the switch have no float columns, so the migration does nothing for them.
+// Down adds the float columns back, filled from the money columns.
+
+var floatMoneyColumns0002 = []struct {
+	table  string
+	column string
+}{
+	{"products", "price"},
+	{"cart_entities", "total"},
+	{"cart_items", "price"},
+	{"orders", "total"},
+	{"order_lines", "price"},
+}
+
+// row0002 stands in for the models of the converted tables, since some migrators only work with models.
+// Its fields are the float columns.
+type row0002 struct {
+	ID    uint
+	Price float64
+	Total float64
+}
+
+// legacyCurrency0002 is the currency of the float amounts; it has two decimal places.
+const legacyCurrency0002 = "USD"
+
+func init() {
+	register(db.Migration{
+		Version: 2,
+		Name:    "convert_float_money_columns",
+		Up: func(tx *gorm.DB) error {
+			for _, c := range floatMoneyColumns0002 {
+				migrator := tx.Table(c.table).Migrator()
+				if !migrator.HasColumn(&row0002{}, c.column) {
+					continue
+				}
+				err := tx.Table(c.table).
+					Session(&gorm.Session{AllowGlobalUpdate: true}).
+					UpdateColumns(map[string]interface{}{
+						c.column + "_amount":   gorm.Expr(fmt.Sprintf("ROUND(%s * 100)", c.column)),
+						c.column + "_currency": legacyCurrency0002,
+					}).Error
+				if err != nil {
+					return err
+				}
+				if err := migrator.DropColumn(&row0002{}, c.column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// The float columns are restored from the money columns, which stay.
+		Down: func(tx *gorm.DB) error {
+			for _, c := range floatMoneyColumns0002 {
+				migrator := tx.Table(c.table).Migrator()
+				if migrator.HasColumn(&row0002{}, c.column) {
+					continue
+				}
+				if err := migrator.AddColumn(&row0002{}, c.column); err != nil {
+					return err
+				}
+				err := tx.Table(c.table).
+					Session(&gorm.Session{AllowGlobalUpdate: true}).
+					UpdateColumn(c.column, gorm.Expr(fmt.Sprintf("%s_amount / 100.0", c.column))).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// The products the shop started with, formerly hard-coded in the cart service.
+
+var products0003 = []product0001{
+	{Name: "shoe", SKU: "SHOE-001", Price: amount0001{10000, "USD"}, Active: true},
+	{Name: "purse", SKU: "PURSE-001", Price: amount0001{20000, "USD"}, Active: true},
+	{Name: "bag", SKU: "BAG-001", Price: amount0001{30000, "USD"}, Active: true},
+	{Name: "watch", SKU: "WATCH-001", Price: amount0001{30000, "USD"}, Active: true},
+}
+
+func init() {
+	register(db.Migration{
+		Version: 3,
+		Name:    "seed_products",
+		Up: func(tx *gorm.DB) error {
+			for _, product := range products0003 {
+				product := product
+				if err := tx.Where("sku = ?", product.SKU).FirstOrCreate(&product).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			var skus []string
+			for _, product := range products0003 {
+				skus = append(skus, product.SKU)
+			}
+			return tx.Unscoped().Where("sku IN ?", skus).Delete(&product0001{}).Error
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// User accounts, and the user that owns a cart or placed an order. Zero means anonymous.
+
+type user0004 struct {
+	gorm.Model
+	Email        string `gorm:"uniqueIndex;size:255"`
+	PasswordHash string `gorm:"size:255"`
+}
+
+func (user0004) TableName() string { return "users" }
+
+type cartEntity0004 struct {
+	UserID uint `gorm:"index"`
+}
+
+func (cartEntity0004) TableName() string { return "cart_entities" }
+
+type order0004 struct {
+	UserID uint `gorm:"index"`
+}
+
+func (order0004) TableName() string { return "orders" }
+
+func init() {
+	register(db.Migration{
+		Version: 4,
+		Name:    "add_users",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.CreateTable(&user0004{}); err != nil {
+				return err
+			}
+			for _, model := range []interface{}{&cartEntity0004{}, &order0004{}} {
+				if err := migrator.AddColumn(model, "UserID"); err != nil {
+					return err
+				}
+				if err := migrator.CreateIndex(model, "UserID"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, model := range []interface{}{&order0004{}, &cartEntity0004{}} {
+				if err := migrator.DropIndex(model, "UserID"); err != nil {
+					return err
+				}
+				if err := migrator.DropColumn(model, "UserID"); err != nil {
+					return err
+				}
+			}
+			return migrator.DropTable(&user0004{})
+		},
+	})
+}
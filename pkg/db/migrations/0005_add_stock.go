@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// The stock of every product. The products seeded by migration 3 start with 100 units on hand, of which the
+// quantities in open carts are reserved. Products without a stock row are not tracked and never run out.
+
+type stock0005 struct {
+	gorm.Model
+	ProductID uint `gorm:"uniqueIndex"`
+	OnHand    int
+	Reserved  int
+}
+
+func (stock0005) TableName() string { return "stocks" }
+
+const initialOnHand0005 = 100
+
+func init() {
+	register(db.Migration{
+		Version: 5,
+		Name:    "add_stock",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&stock0005{}); err != nil {
+				return err
+			}
+			var skus []string
+			for _, product := range products0003 {
+				skus = append(skus, product.SKU)
+			}
+			var productIDs []uint
+			err := tx.Table("products").Where("sku IN ? AND deleted_at IS NULL", skus).Order("id").Pluck("id", &productIDs).Error
+			if err != nil {
+				return err
+			}
+			for _, productID := range productIDs {
+				var reserved int
+				err := tx.Table("cart_items").
+					Joins("JOIN cart_entities ON cart_entities.id = cart_items.cart_id").
+					Where("cart_items.product_id = ? AND cart_entities.status = ?", productID, "open").
+					Where("cart_items.deleted_at IS NULL AND cart_entities.deleted_at IS NULL").
+					Select("COALESCE(SUM(cart_items.quantity), 0)").
+					Row().Scan(&reserved)
+				if err != nil {
+					return err
+				}
+				stock := stock0005{ProductID: productID, OnHand: initialOnHand0005, Reserved: reserved}
+				if err := tx.Create(&stock).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&stock0005{})
+		},
+	})
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Coupons, the coupon applied to a cart, and the coupon and discount of an order.
+
+type money0006 struct {
+	Amount   int64
+	Currency string `gorm:"size:3"`
+}
+
+type coupon0006 struct {
+	gorm.Model
+	Code         string `gorm:"uniqueIndex;size:64"`
+	Kind         string `gorm:"size:16"`
+	Percent      int
+	Amount       money0006 `gorm:"embedded;embeddedPrefix:amount_"`
+	BuyQuantity  int
+	FreeQuantity int
+	ProductID    uint
+	MinSubtotal  money0006 `gorm:"embedded;embeddedPrefix:min_subtotal_"`
+	ValidFrom    *time.Time
+	ValidUntil   *time.Time
+	MaxUses      int
+	Uses         int
+	Active       bool
+}
+
+func (coupon0006) TableName() string { return "coupons" }
+
+type cartEntity0006 struct {
+	CouponCode string `gorm:"size:64"`
+}
+
+func (cartEntity0006) TableName() string { return "cart_entities" }
+
+type order0006 struct {
+	CouponCode string    `gorm:"size:64"`
+	Discount   money0006 `gorm:"embedded;embeddedPrefix:discount_"`
+}
+
+func (order0006) TableName() string { return "orders" }
+
+var orderColumns0006 = []string{"coupon_code", "discount_amount", "discount_currency"}
+
+func init() {
+	register(db.Migration{
+		Version: 6,
+		Name:    "add_coupons",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.CreateTable(&coupon0006{}); err != nil {
+				return err
+			}
+			if err := migrator.AddColumn(&cartEntity0006{}, "coupon_code"); err != nil {
+				return err
+			}
+			for _, column := range orderColumns0006 {
+				if err := migrator.AddColumn(&order0006{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &order0006{}, orderColumns0006...); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, &cartEntity0006{}, "coupon_code"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&coupon0006{})
+		},
+	})
+}
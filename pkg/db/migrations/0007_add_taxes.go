@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// Tax rates per region and product tax category, the tax category of products, the tax region of carts, and the
+// net amount, tax and tax lines of orders. Products are put in the standard category, and orders placed before
+// taxes were charged get their total as net amount and no tax.
+
+type taxRate0007 struct {
+	gorm.Model
+	Region   string `gorm:"uniqueIndex:idx_tax_rates_region_category;size:32"`
+	Category string `gorm:"uniqueIndex:idx_tax_rates_region_category;size:32"`
+	Rate     int
+	Name     string `gorm:"size:64"`
+}
+
+func (taxRate0007) TableName() string { return "tax_rates" }
+
+type orderTaxLine0007 struct {
+	gorm.Model
+	OrderID  uint `gorm:"index"`
+	Name     string
+	Category string
+	Rate     int
+	Taxable  money0006 `gorm:"embedded;embeddedPrefix:taxable_"`
+	Tax      money0006 `gorm:"embedded;embeddedPrefix:tax_"`
+}
+
+func (orderTaxLine0007) TableName() string { return "order_tax_lines" }
+
+type product0007 struct {
+	TaxCategory string `gorm:"size:32"`
+}
+
+func (product0007) TableName() string { return "products" }
+
+type cartEntity0007 struct {
+	Region string `gorm:"size:32"`
+}
+
+func (cartEntity0007) TableName() string { return "cart_entities" }
+
+type order0007 struct {
+	Region string    `gorm:"size:32"`
+	Net    money0006 `gorm:"embedded;embeddedPrefix:net_"`
+	Tax    money0006 `gorm:"embedded;embeddedPrefix:tax_"`
+}
+
+func (order0007) TableName() string { return "orders" }
+
+var orderColumns0007 = []string{"region", "net_amount", "net_currency", "tax_amount", "tax_currency"}
+
+func init() {
+	register(db.Migration{
+		Version: 7,
+		Name:    "add_taxes",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, model := range []interface{}{&taxRate0007{}, &orderTaxLine0007{}} {
+				if err := migrator.CreateTable(model); err != nil {
+					return err
+				}
+			}
+			if err := migrator.AddColumn(&product0007{}, "tax_category"); err != nil {
+				return err
+			}
+			if err := migrator.AddColumn(&cartEntity0007{}, "region"); err != nil {
+				return err
+			}
+			for _, column := range orderColumns0007 {
+				if err := migrator.AddColumn(&order0007{}, column); err != nil {
+					return err
+				}
+			}
+			err := tx.Table("products").
+				Session(&gorm.Session{AllowGlobalUpdate: true}).
+				UpdateColumn("tax_category", "standard").Error
+			if err != nil {
+				return err
+			}
+			return tx.Table("orders").
+				Session(&gorm.Session{AllowGlobalUpdate: true}).
+				UpdateColumns(map[string]interface{}{
+					"net_amount":   gorm.Expr("total_amount"),
+					"net_currency": gorm.Expr("total_currency"),
+					"tax_amount":   0,
+					"tax_currency": gorm.Expr("total_currency"),
+				}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &order0007{}, orderColumns0007...); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, &cartEntity0007{}, "region"); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, &product0007{}, "tax_category"); err != nil {
+				return err
+			}
+			migrator := tx.Migrator()
+			for _, model := range []interface{}{&orderTaxLine0007{}, &taxRate0007{}} {
+				if err := migrator.DropTable(model); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// Shipping zones and their rates, the weight and dimensions of products, the shipping destination and method of
+// carts, and the shipping of orders. Orders placed before shipping was charged get no shipping.
+
+type shippingZone0008 struct {
+	gorm.Model
+	Name      string `gorm:"uniqueIndex;size:32"`
+	Countries string `gorm:"size:255"`
+}
+
+func (shippingZone0008) TableName() string { return "shipping_zones" }
+
+type shippingRate0008 struct {
+	gorm.Model
+	ZoneID    uint      `gorm:"index"`
+	Method    string    `gorm:"size:32"`
+	Name      string    `gorm:"size:64"`
+	Kind      string    `gorm:"size:16"`
+	Price     money0006 `gorm:"embedded;embeddedPrefix:price_"`
+	MaxWeight int
+	Threshold money0006 `gorm:"embedded;embeddedPrefix:threshold_"`
+}
+
+func (shippingRate0008) TableName() string { return "shipping_rates" }
+
+type product0008 struct {
+	Weight int
+	Length int
+	Width  int
+	Height int
+}
+
+func (product0008) TableName() string { return "products" }
+
+var productColumns0008 = []string{"weight", "length", "width", "height"}
+
+type cartEntity0008 struct {
+	ShippingCountry string `gorm:"size:2"`
+	ShippingMethod  string `gorm:"size:32"`
+}
+
+func (cartEntity0008) TableName() string { return "cart_entities" }
+
+var cartColumns0008 = []string{"shipping_country", "shipping_method"}
+
+type order0008 struct {
+	ShippingCountry string    `gorm:"size:2"`
+	ShippingMethod  string    `gorm:"size:32"`
+	Shipping        money0006 `gorm:"embedded;embeddedPrefix:shipping_"`
+}
+
+func (order0008) TableName() string { return "orders" }
+
+var orderColumns0008 = []string{"shipping_country", "shipping_method", "shipping_amount", "shipping_currency"}
+
+func init() {
+	register(db.Migration{
+		Version: 8,
+		Name:    "add_shipping",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, model := range []interface{}{&shippingZone0008{}, &shippingRate0008{}} {
+				if err := migrator.CreateTable(model); err != nil {
+					return err
+				}
+			}
+			for _, column := range productColumns0008 {
+				if err := migrator.AddColumn(&product0008{}, column); err != nil {
+					return err
+				}
+			}
+			for _, column := range cartColumns0008 {
+				if err := migrator.AddColumn(&cartEntity0008{}, column); err != nil {
+					return err
+				}
+			}
+			for _, column := range orderColumns0008 {
+				if err := migrator.AddColumn(&order0008{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Table("orders").
+				Session(&gorm.Session{AllowGlobalUpdate: true}).
+				UpdateColumns(map[string]interface{}{
+					"shipping_amount":   0,
+					"shipping_currency": gorm.Expr("total_currency"),
+				}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &order0008{}, orderColumns0008...); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, &cartEntity0008{}, cartColumns0008...); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, &product0008{}, productColumns0008...); err != nil {
+				return err
+			}
+			migrator := tx.Migrator()
+			for _, model := range []interface{}{&shippingRate0008{}, &shippingZone0008{}} {
+				if err := migrator.DropTable(model); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// The requests made with idempotency keys and their responses.
+
+type idempotencyKey0009 struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Scope       string `gorm:"uniqueIndex:idx_idempotency_keys_scope_key;size:64"`
+	Key         string `gorm:"uniqueIndex:idx_idempotency_keys_scope_key;size:255"`
+	Fingerprint string `gorm:"size:64"`
+	Status      int
+	ContentType string `gorm:"size:255"`
+	Location    string `gorm:"size:2048"`
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+func (idempotencyKey0009) TableName() string { return "idempotency_keys" }
+
+func init() {
+	register(db.Migration{
+		Version: 9,
+		Name:    "add_idempotency_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&idempotencyKey0009{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&idempotencyKey0009{})
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// Whether the prices of orders include their tax, as the tax lines of an order do not tell whether the tax was
+// worked out of the prices or added to them. Orders placed before get false, the default of tax_inclusive_prices.
+
+type order0010 struct {
+	PricesIncludeTax bool
+}
+
+func (order0010) TableName() string { return "orders" }
+
+func init() {
+	register(db.Migration{
+		Version: 10,
+		Name:    "add_order_prices_include_tax",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&order0010{}, "prices_include_tax"); err != nil {
+				return err
+			}
+			return tx.Table("orders").
+				Session(&gorm.Session{AllowGlobalUpdate: true}).
+				UpdateColumn("prices_include_tax", false).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &order0010{}, "prices_include_tax")
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+// The product IDs of cart items and order lines stored before items referenced their product, which are found by
+// the product name. The stock of the items in open carts, which migration 5 could not tell the product of, is
+// reserved. Items of products that no longer exist get 0.
+//
+// Down keeps the IDs, which are right for every version of the schema.
+
+var productTables0011 = []string{"cart_items", "order_lines"}
+
+func init() {
+	register(db.Migration{
+		Version: 11,
+		Name:    "fill_product_ids",
+		Up: func(tx *gorm.DB) error {
+			rows, err := tx.Table("cart_items").
+				Joins("JOIN cart_entities ON cart_entities.id = cart_items.cart_id").
+				Joins("JOIN products ON products.name = cart_items.product_name").
+				Where("(cart_items.product_id = 0 OR cart_items.product_id IS NULL) AND cart_entities.status = ?", "open").
+				Where("cart_items.deleted_at IS NULL AND cart_entities.deleted_at IS NULL").
+				Group("products.id").
+				Select("products.id, SUM(cart_items.quantity)").
+				Rows()
+			if err != nil {
+				return err
+			}
+			reserved := map[uint]int{}
+			for rows.Next() {
+				var productID uint
+				var quantity int
+				if err := rows.Scan(&productID, &quantity); err != nil {
+					_ = rows.Close()
+					return err
+				}
+				reserved[productID] = quantity
+			}
+			if err := rows.Close(); err != nil {
+				return err
+			}
+			for productID, quantity := range reserved {
+				err := tx.Table("stocks").
+					Where("product_id = ?", productID).
+					UpdateColumn("reserved", gorm.Expr("reserved + ?", quantity)).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, table := range productTables0011 {
+				err := tx.Table(table).
+					Where("product_id = 0 OR product_id IS NULL").
+					UpdateColumn("product_id", gorm.Expr(
+						"COALESCE((SELECT products.id FROM products WHERE products.name = "+table+".product_name), 0)")).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
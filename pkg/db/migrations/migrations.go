@@ -0,0 +1,135 @@
+// Package migrations contains the versioned migrations of the application database.
+//
+// Every migration lives in its own file named after its version, e.g. 0004_add_users.go, and registers
+// itself from an init function. New files are created with `web-api migrate create <name>`.
+// A migration must describe the schema it creates with its own structs rather than the entity types,
+// because entities keep changing while an applied migration must not.
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+var registered []db.Migration
+
+// register adds a migration to the list returned by All.
+func register(m db.Migration) {
+	registered = append(registered, m)
+}
+
+// All returns all migrations in version order.
+func All() []db.Migration {
+	migrations := append([]db.Migration(nil), registered...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// dropColumns drops columns from the table of the model. SQLite drops a column by copying the table, which loses
+// its indexes, so there the indexes that don't cover a dropped column are created again.
+func dropColumns(tx *gorm.DB, model interface{}, columns ...string) error {
+	migrator := tx.Migrator()
+	var indexes []string
+	if tx.Dialector.Name() == "sqlite" {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		err := tx.Raw("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", stmt.Table).
+			Scan(&indexes).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, column := range columns {
+		if err := migrator.DropColumn(model, column); err != nil {
+			return err
+		}
+	}
+	for _, index := range indexes {
+		if coversAny(index, columns) {
+			continue
+		}
+		if err := tx.Exec(strings.Replace(index, "INDEX", "INDEX IF NOT EXISTS", 1)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coversAny tells whether the CREATE INDEX statement names one of the columns.
+func coversAny(index string, columns []string) bool {
+	for _, column := range columns {
+		if strings.Contains(index, "`"+column+"`") {
+			return true
+		}
+	}
+	return false
+}
+
+var fileNameRegex = regexp.MustCompile(`^(\d+)_.*\.go$`)
+var migrationNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+const fileTemplate = `package migrations
+
+import (
+	"interview/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(db.Migration{
+		Version: %d,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
+
+// Create writes a new, empty migration file to dir and returns its path.
+// The version is one higher than the highest version found in dir.
+func Create(dir string, name string) (string, error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", "_"))
+	if !migrationNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	version := 0
+	for _, entry := range entries {
+		match := fileNameRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		v, _ := strconv.Atoi(match[1])
+		if v > version {
+			version = v
+		}
+	}
+	version++
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	content := fmt.Sprintf(fileTemplate, version, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,173 @@
+package migrations
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/money"
+)
+
+func TestAll(t *testing.T) {
+	versions := map[int]bool{}
+	for i, m := range All() {
+		assert.False(t, versions[m.Version], "duplicate migration version %d", m.Version)
+		versions[m.Version] = true
+		assert.Equal(t, i+1, m.Version, "migration versions must be consecutive")
+		assert.NotEmpty(t, m.Name)
+		assert.NotNil(t, m.Up)
+	}
+}
+
+func TestMigrations_UpDown(t *testing.T) {
+	runMigrationTest(t, func(conn *gorm.DB, migrator *db.Migrator) {
+		ctx := context.Background()
+		_, err := migrator.Up(ctx, 0)
+		require.Nil(t, err)
+
+		var products []entity.Product
+		require.Nil(t, conn.Order("id asc").Find(&products).Error)
+		require.Equal(t, 4, len(products))
+		assert.Equal(t, "shoe", products[0].Name)
+		assert.Equal(t, money.New(10000, "USD"), products[0].Price)
+
+		_, err = migrator.Down(ctx, len(All()))
+		require.Nil(t, err)
+		assert.False(t, conn.Migrator().HasTable("cart_entities"))
+
+		_, err = migrator.Up(ctx, 0)
+		require.Nil(t, err)
+		pending, err := migrator.Pending(ctx)
+		require.Nil(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestMigrations_ConvertFloatMoneyColumns(t *testing.T) {
+	runMigrationTest(t, func(conn *gorm.DB, migrator *db.Migrator) {
+		// a cart stored before amounts were kept in minor units
+		type CartEntity struct {
+			gorm.Model
+			Total     float64
+			SessionID string
+			Status    string
+		}
+		require.Nil(t, conn.AutoMigrate(&CartEntity{}))
+		require.Nil(t, conn.Create(&CartEntity{Total: 12.34, SessionID: "legacy", Status: "open"}).Error)
+
+		_, err := migrator.Up(context.Background(), 0)
+		require.Nil(t, err)
+
+		var carts []entity.CartEntity
+		require.Nil(t, conn.Find(&carts).Error)
+		require.Equal(t, 1, len(carts))
+		assert.Equal(t, money.New(1234, "USD"), carts[0].Total)
+		assert.False(t, conn.Migrator().HasColumn("cart_entities", "total"))
+
+		// going back to the first version restores the float column
+		_, err = migrator.Down(context.Background(), len(All())-1)
+		require.Nil(t, err)
+		var legacy []CartEntity
+		require.Nil(t, conn.Find(&legacy).Error)
+		require.Equal(t, 1, len(legacy))
+		assert.Equal(t, 12.34, legacy[0].Total)
+
+		_, err = migrator.Up(context.Background(), 0)
+		require.Nil(t, err)
+		require.Nil(t, conn.Find(&carts).Error)
+		assert.Equal(t, money.New(1234, "USD"), carts[0].Total)
+	})
+}
+
+func TestMigrations_FillProductIDs(t *testing.T) {
+	runMigrationTest(t, func(conn *gorm.DB, migrator *db.Migrator) {
+		// carts stored before items referenced their product
+		type CartEntity struct {
+			gorm.Model
+			Total     float64
+			SessionID string
+			Status    string
+		}
+		type CartItem struct {
+			gorm.Model
+			CartID      uint
+			ProductName string
+			Quantity    int
+			Price       float64
+		}
+		require.Nil(t, conn.AutoMigrate(&CartEntity{}, &CartItem{}))
+		open := CartEntity{Total: 500, SessionID: "legacy", Status: "open"}
+		closed := CartEntity{Total: 300, SessionID: "legacy", Status: "closed"}
+		require.Nil(t, conn.Create(&open).Error)
+		require.Nil(t, conn.Create(&closed).Error)
+		require.Nil(t, conn.Create(&[]CartItem{
+			{CartID: open.ID, ProductName: "purse", Quantity: 2, Price: 400},
+			{CartID: open.ID, ProductName: "sold out", Quantity: 1, Price: 100},
+			{CartID: closed.ID, ProductName: "shoe", Quantity: 3, Price: 300},
+		}).Error)
+
+		_, err := migrator.Up(context.Background(), 0)
+		require.Nil(t, err)
+
+		products := map[string]uint{}
+		var all []entity.Product
+		require.Nil(t, conn.Find(&all).Error)
+		for _, product := range all {
+			products[product.Name] = product.ID
+		}
+		var items []entity.CartItem
+		require.Nil(t, conn.Order("id asc").Find(&items).Error)
+		require.Equal(t, 3, len(items))
+		assert.Equal(t, products["purse"], items[0].ProductID)
+		assert.Zero(t, items[1].ProductID)
+		assert.Equal(t, products["shoe"], items[2].ProductID)
+
+		// only the items of open carts are reserved
+		var stocks []entity.Stock
+		require.Nil(t, conn.Find(&stocks).Error)
+		reserved := map[uint]int{}
+		for _, stock := range stocks {
+			reserved[stock.ProductID] = stock.Reserved
+		}
+		assert.Equal(t, map[uint]int{products["shoe"]: 0, products["purse"]: 2, products["bag"]: 0, products["watch"]: 0}, reserved)
+	})
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "0007_existing.go"), nil, 0o644))
+
+	path, err := Create(dir, "Add-Users")
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "0008_add_users.go"), path)
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "Version: 8,")
+	assert.Contains(t, string(content), `Name:    "add_users",`)
+
+	_, err = Create(dir, "drop table;")
+	assert.NotNil(t, err)
+}
+
+func runMigrationTest(t *testing.T, f func(conn *gorm.DB, migrator *db.Migrator)) {
+	logger, _ := log.NewForTest()
+	conn, closeDB, err := db.OpenForTest(logger)
+	require.Nil(t, err)
+	defer func() {
+		_ = closeDB()
+	}()
+	tables, err := conn.Migrator().GetTables()
+	require.Nil(t, err)
+	for _, table := range tables {
+		require.Nil(t, conn.Migrator().DropTable(table))
+	}
+	f(conn, db.NewMigrator(db.New(conn, logger), All()))
+}
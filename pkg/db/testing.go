@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"interview/internal/config"
+	"interview/internal/utils"
+	"interview/pkg/log"
+)
+
+// OpenForTest opens a connection to the database used by tests, along with a function that closes it.
+//
+// The database is described by config/test.yml. When that file does not exist, a fresh SQLite
+// database in a temporary directory is used, so tests can run without a database server.
+func OpenForTest(logger log.Logger) (*gorm.DB, func() error, error) {
+	cfg, err := config.Load("test.yml", logger)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	driver, dsn, dir := "sqlite", "", ""
+	if cfg != nil {
+		driver, dsn = cfg.DBDriver, cfg.DSN
+	} else {
+		dir, err = os.MkdirTemp("", "interview-test-")
+		if err != nil {
+			return nil, nil, err
+		}
+		dsn = filepath.Join(dir, "test.db")
+	}
+	db, err := utils.GetDBConnection(driver, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	closer := func() error {
+		err := utils.CloseDBConnection(db)
+		if dir != "" {
+			_ = os.RemoveAll(dir)
+		}
+		return err
+	}
+	return db, closer, nil
+}
+
+// MigratedForTest opens the database used by tests, applies the migrations to it and deletes the rows of the given
+// tables, so that each test starts with empty tables. The connection is closed when the test finishes. The
+// migrations are passed in, as the migrations package depends on this one.
+func MigratedForTest(t testing.TB, migrations []Migration, tables ...string) *DB {
+	t.Helper()
+	logger, _ := log.NewForTest()
+	conn, closeDB, err := OpenForTest(logger)
+	if err != nil {
+		t.Fatalf("error opening the test database: %v", err)
+	}
+	t.Cleanup(func() { _ = closeDB() })
+	db := New(conn, logger)
+	if _, err := NewMigrator(db, migrations).Up(context.Background(), 0); err != nil {
+		t.Fatalf("error migrating the test database: %v", err)
+	}
+	for _, table := range tables {
+		if err := conn.Exec("DELETE FROM " + table).Error; err != nil {
+			t.Fatalf("error emptying table %s: %v", table, err)
+		}
+	}
+	return db
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	tracerName     = "interview/pkg/db"
+	tracingSpanKey = "tracing:span"
+)
+
+// RegisterTracing registers GORM callbacks that record every query as an OpenTelemetry span.
+// The span is a child of the span in the context of the query, so queries run through With
+// show up under the request that issued them. The SQL is recorded without its bound values.
+func RegisterTracing(db *gorm.DB) error {
+	tracer := otel.Tracer(tracerName)
+	before := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			_, span := tracer.Start(tx.Statement.Context, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient))
+			tx.InstanceSet(tracingSpanKey, span)
+		}
+	}
+	after := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(tracingSpanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		defer span.End()
+		span.SetAttributes(
+			attribute.String("db.system", tx.Dialector.Name()),
+			attribute.String("db.sql.table", tx.Statement.Table),
+			attribute.String("db.statement", tx.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", tx.RowsAffected),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+			span.SetStatus(codes.Error, tx.Error.Error())
+		}
+	}
+
+	callbacks := db.Callback()
+	processors := []struct {
+		operation      string
+		registerBefore func(name string, fn func(*gorm.DB)) error
+		registerAfter  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, p := range processors {
+		if err := p.registerBefore("tracing:before_"+p.operation, before(p.operation)); err != nil {
+			return err
+		}
+		if err := p.registerAfter("tracing:after_"+p.operation, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"interview/pkg/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestRegisterTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	runDBTest(t, func(db *gorm.DB) {
+		require.Nil(t, RegisterTracing(db))
+		logger, _ := log.NewForTest()
+		dbc := New(db, logger)
+
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+		err := dbc.Transactional(ctx, func(ctx context.Context) error {
+			return dbc.With(ctx).Exec("INSERT INTO dbcontexttest (id, name) VALUES(?, ?)", "1", "secret").Error
+		})
+		assert.Nil(t, err)
+		var count int64
+		assert.Nil(t, dbc.With(ctx).Table("dbcontexttest").Count(&count).Error)
+		parent.End()
+
+		var queries []sdktrace.ReadOnlySpan
+		for _, span := range recorder.Ended() {
+			if span.Name() != "request" {
+				queries = append(queries, span)
+			}
+		}
+		require.Equal(t, 2, len(queries))
+		assert.Equal(t, "gorm.raw", queries[0].Name())
+		assert.Equal(t, "gorm.query", queries[1].Name())
+		for _, span := range queries {
+			assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+			for _, attr := range span.Attributes() {
+				assert.NotContains(t, attr.Value.Emit(), "secret")
+			}
+		}
+	})
+}
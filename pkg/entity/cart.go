@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"interview/pkg/money"
+
 	"gorm.io/gorm"
 )
 
@@ -9,11 +11,21 @@ type Status string
 const (
 	CartOpen   Status = "open"
 	CartClosed Status = "closed"
+	// CartExpired marks a cart that was abandoned; the stock reserved for its items has been released.
+	CartExpired Status = "expired"
 )
 
 type CartEntity struct {
 	gorm.Model
-	Total     float64
+	Total     money.Money `gorm:"embedded;embeddedPrefix:total_"`
 	SessionID string
-	Status    Status `gorm:"type:enum('open', 'closed')"`
+	UserID    uint   `gorm:"index"`
+	Status    Status `gorm:"size:16"`
+	// CouponCode is the code of the coupon applied to the cart, if any.
+	CouponCode string `gorm:"size:64"`
+	// Region is the tax region of the cart; empty means the default region.
+	Region string `gorm:"size:32"`
+	// ShippingCountry is the destination the shipping is quoted for, and ShippingMethod the method chosen.
+	ShippingCountry string `gorm:"size:2"`
+	ShippingMethod  string `gorm:"size:32"`
 }
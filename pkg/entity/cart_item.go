@@ -1,11 +1,16 @@
 package entity
 
-import "gorm.io/gorm"
+import (
+	"interview/pkg/money"
+
+	"gorm.io/gorm"
+)
 
 type CartItem struct {
 	gorm.Model
 	CartID      uint
+	ProductID   uint
 	ProductName string
 	Quantity    int
-	Price       float64
+	Price       money.Money `gorm:"embedded;embeddedPrefix:price_"`
 }
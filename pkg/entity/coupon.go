@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"interview/pkg/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CouponKind string
+
+const (
+	// CouponPercentage takes Percent percent off the eligible items.
+	CouponPercentage CouponKind = "percentage"
+	// CouponFixed takes Amount off the eligible items.
+	CouponFixed CouponKind = "fixed"
+	// CouponBuyXGetY makes FreeQuantity units free for every BuyQuantity units bought of the eligible items.
+	CouponBuyXGetY CouponKind = "buy_x_get_y"
+)
+
+// Coupon is a promotion rule that a customer applies to a cart by entering its code.
+type Coupon struct {
+	gorm.Model
+	Code         string     `gorm:"uniqueIndex;size:64"`
+	Kind         CouponKind `gorm:"size:16"`
+	Percent      int
+	Amount       money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	BuyQuantity  int
+	FreeQuantity int
+	// ProductID limits the coupon to the items of one product; zero makes all items eligible.
+	ProductID uint
+	// MinSubtotal is the smallest cart subtotal the coupon applies to; zero means no minimum.
+	MinSubtotal money.Money `gorm:"embedded;embeddedPrefix:min_subtotal_"`
+	// ValidFrom and ValidUntil bound the period in which the coupon can be used; nil leaves that side open.
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+	// MaxUses is the number of orders the coupon can be used for; zero means no limit. Uses counts those orders.
+	MaxUses int
+	Uses    int
+	Active  bool
+}
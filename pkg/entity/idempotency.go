@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// IdempotencyKey is a request made with an idempotency key and the response to it, which is sent again instead of
+// repeating the request when the key comes back. Keys are deleted for good once they expire, so that they can be
+// used again, which is why the table has no soft deletes.
+type IdempotencyKey struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Scope is the session that made the request; the same key sent by different sessions is different keys.
+	Scope string `gorm:"uniqueIndex:idx_idempotency_keys_scope_key;size:64"`
+	Key   string `gorm:"uniqueIndex:idx_idempotency_keys_scope_key;size:255"`
+	// Fingerprint is a hash of the method, URL and body of the request.
+	Fingerprint string `gorm:"size:64"`
+	// Status is the status code of the response, or 0 while the request is being handled.
+	Status      int
+	ContentType string `gorm:"size:255"`
+	Location    string `gorm:"size:2048"`
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+}
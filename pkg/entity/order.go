@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"interview/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+type OrderStatus string
+
+const (
+	OrderPlaced OrderStatus = "placed"
+)
+
+// OrderStatuses lists every order status. A new status must be added here, so that the order history can be
+// filtered by it.
+var OrderStatuses = []OrderStatus{OrderPlaced}
+
+// Valid tells whether the status is one of OrderStatuses.
+func (s OrderStatus) Valid() bool {
+	for _, status := range OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+type Order struct {
+	gorm.Model
+	CartID    uint
+	SessionID string
+	UserID    uint `gorm:"index"`
+	// Total is the amount charged: the sum of the line prices less the discount, plus the tax if the prices
+	// exclude it, plus the shipping. It is made up of Net, Tax and Shipping.
+	Total      money.Money `gorm:"embedded;embeddedPrefix:total_"`
+	Status     OrderStatus `gorm:"size:16"`
+	CouponCode string      `gorm:"size:64"`
+	Discount   money.Money `gorm:"embedded;embeddedPrefix:discount_"`
+	Region     string      `gorm:"size:32"`
+	Net        money.Money `gorm:"embedded;embeddedPrefix:net_"`
+	Tax        money.Money `gorm:"embedded;embeddedPrefix:tax_"`
+	// PricesIncludeTax tells whether the tax was worked out of the prices of the lines instead of added to them.
+	PricesIncludeTax bool
+	// ShippingCountry and ShippingMethod are empty if the order was placed without shipping.
+	ShippingCountry string      `gorm:"size:2"`
+	ShippingMethod  string      `gorm:"size:32"`
+	Shipping        money.Money `gorm:"embedded;embeddedPrefix:shipping_"`
+}
+
+// OrderTaxLine is the tax charged on the lines of an order in one tax category.
+type OrderTaxLine struct {
+	gorm.Model
+	OrderID  uint `gorm:"index"`
+	Name     string
+	Category string
+	// Rate is in basis points.
+	Rate    int
+	Taxable money.Money `gorm:"embedded;embeddedPrefix:taxable_"`
+	Tax     money.Money `gorm:"embedded;embeddedPrefix:tax_"`
+}
+
+type OrderLine struct {
+	gorm.Model
+	OrderID     uint
+	ProductID   uint
+	ProductName string
+	Quantity    int
+	Price       money.Money `gorm:"embedded;embeddedPrefix:price_"`
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"interview/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+type Product struct {
+	gorm.Model
+	Name   string      `gorm:"uniqueIndex;size:255"`
+	SKU    string      `gorm:"uniqueIndex;size:64"`
+	Price  money.Money `gorm:"embedded;embeddedPrefix:price_"`
+	Active bool
+	// TaxCategory selects the tax rates that apply to the product.
+	TaxCategory string `gorm:"size:32"`
+	// Weight is in grams and the dimensions of the packed product in millimetres.
+	Weight int
+	Length int
+	Width  int
+	Height int
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"interview/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+type ShippingRateKind string
+
+const (
+	// ShippingFlat charges Price whatever the cart holds.
+	ShippingFlat ShippingRateKind = "flat"
+	// ShippingWeight charges Price for parcels up to MaxWeight. A method has one weight rate per tier, and the
+	// parcel is charged at the lightest tier it fits in.
+	ShippingWeight ShippingRateKind = "weight"
+	// ShippingFreeOver charges Price, or nothing once the discounted cart subtotal reaches Threshold.
+	ShippingFreeOver ShippingRateKind = "free_over"
+)
+
+// ShippingZone is a group of destination countries that share shipping rates.
+type ShippingZone struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex;size:32"`
+	// Countries are ISO 3166 country codes separated by commas, e.g. "DE,FR", or "*" for the countries of no
+	// other zone.
+	Countries string `gorm:"size:255"`
+}
+
+// ShippingRate prices a shipping method in a zone.
+type ShippingRate struct {
+	gorm.Model
+	ZoneID uint `gorm:"index"`
+	// Method identifies the shipping method within the zone, e.g. "standard"; Name is shown to customers.
+	Method string           `gorm:"size:32"`
+	Name   string           `gorm:"size:64"`
+	Kind   ShippingRateKind `gorm:"size:16"`
+	Price  money.Money      `gorm:"embedded;embeddedPrefix:price_"`
+	// MaxWeight is the heaviest parcel of a weight tier, in grams.
+	MaxWeight int
+	Threshold money.Money `gorm:"embedded;embeddedPrefix:threshold_"`
+}
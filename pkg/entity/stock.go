@@ -0,0 +1,11 @@
+package entity
+
+import "gorm.io/gorm"
+
+// Stock is the inventory of a product. Reserved units are held by open carts; the rest of OnHand can be added to carts.
+type Stock struct {
+	gorm.Model
+	ProductID uint `gorm:"uniqueIndex"`
+	OnHand    int
+	Reserved  int
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "gorm.io/gorm"
+
+// DefaultTaxCategory is the tax category of products that have not been given another one.
+const DefaultTaxCategory = "standard"
+
+// TaxRate is the rate at which products of a tax category are taxed in a region.
+type TaxRate struct {
+	gorm.Model
+	Region   string `gorm:"uniqueIndex:idx_tax_rates_region_category;size:32"`
+	Category string `gorm:"uniqueIndex:idx_tax_rates_region_category;size:32"`
+	// Rate is in basis points: 825 is 8.25%.
+	Rate int
+	// Name is shown on the tax lines, e.g. "VAT".
+	Name string `gorm:"size:64"`
+}
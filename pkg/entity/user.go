@@ -0,0 +1,9 @@
+package entity
+
+import "gorm.io/gorm"
+
+type User struct {
+	gorm.Model
+	Email        string `gorm:"uniqueIndex;size:255"`
+	PasswordHash string `gorm:"size:255"`
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LivePath  = "/healthz"
+	ReadyPath = "/readyz"
+)
+
+type readyResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// RegisterHandlers registers the liveness and readiness probes.
+// The liveness probe succeeds as long as the process serves requests. The readiness probe responds with 503
+// while the server starts up or shuts down, or when one of the registered checks fails.
+func RegisterHandlers(r gin.IRoutes, readiness *Readiness, registry *Registry) {
+	r.GET(LivePath, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "alive"})
+	})
+	r.GET(ReadyPath, func(c *gin.Context) {
+		if !readiness.Ready() {
+			c.JSON(http.StatusServiceUnavailable, readyResponse{Status: "not ready"})
+			return
+		}
+		report := registry.Run(c.Request.Context())
+		if !report.Healthy {
+			c.JSON(http.StatusServiceUnavailable, readyResponse{Status: "not ready", Checks: report.Checks})
+			return
+		}
+		c.JSON(http.StatusOK, readyResponse{Status: "ready", Checks: report.Checks})
+	})
+}
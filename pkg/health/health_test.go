@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	registry := NewRegistry()
+	report := registry.Run(context.Background())
+	assert.True(t, report.Healthy)
+	assert.Empty(t, report.Checks)
+
+	registry.Register("ok", time.Second, func(ctx context.Context) error { return nil })
+	registry.Register("failing", time.Second, func(ctx context.Context) error { return errors.New("unreachable") })
+	registry.Register("slow", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	registry.Register("stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report = registry.Run(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.False(t, report.Healthy)
+	assert.Equal(t, CheckResult{Status: StatusOK}, withoutDuration(report.Checks["ok"]))
+	assert.Equal(t, CheckResult{Status: StatusFailed, Error: "unreachable"}, withoutDuration(report.Checks["failing"]))
+	assert.Equal(t, CheckResult{Status: StatusFailed, Error: TimeoutError.Error()}, withoutDuration(report.Checks["slow"]))
+	assert.Equal(t, CheckResult{Status: StatusFailed, Error: TimeoutError.Error()}, withoutDuration(report.Checks["stuck"]))
+
+	// registering a name again replaces the check
+	registry.Register("failing", time.Second, func(ctx context.Context) error { return nil })
+	report = registry.Run(context.Background())
+	assert.Equal(t, 4, len(report.Checks))
+	assert.Equal(t, StatusOK, report.Checks["failing"].Status)
+}
+
+func withoutDuration(r CheckResult) CheckResult {
+	r.DurationMS = 0
+	return r
+}
+
+func TestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	readiness := &Readiness{}
+	registry := NewRegistry()
+	var dbErr error
+	registry.Register("database", time.Second, func(ctx context.Context) error { return dbErr })
+	RegisterHandlers(engine, readiness, registry)
+
+	probe := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := probe(LivePath)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"alive"}`, w.Body.String())
+
+	w = probe(ReadyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"not ready"}`, w.Body.String())
+
+	readiness.SetReady(true)
+	w = probe(ReadyPath)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res readyResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "ready", res.Status)
+	assert.Equal(t, StatusOK, res.Checks["database"].Status)
+
+	dbErr = errors.New("connection refused")
+	w = probe(ReadyPath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "not ready", res.Status)
+	assert.Equal(t, CheckResult{Status: StatusFailed, Error: "connection refused"}, withoutDuration(res.Checks["database"]))
+
+	readiness.SetReady(false)
+	dbErr = nil
+	assert.Equal(t, http.StatusServiceUnavailable, probe(ReadyPath).Code)
+	// the liveness probe does not depend on readiness
+	assert.Equal(t, http.StatusOK, probe(LivePath).Code)
+}
@@ -0,0 +1,19 @@
+// Package health reports whether the application is alive and ready to serve traffic.
+package health
+
+import "sync/atomic"
+
+// Readiness tells whether the server accepts traffic. The zero value is not ready.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// SetReady marks the server as ready or not ready.
+func (r *Readiness) SetReady(ready bool) {
+	r.ready.Store(ready)
+}
+
+// Ready reports whether the server is ready.
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check returns an error if a dependency is unavailable. It should give up when ctx is done.
+type Check func(ctx context.Context) error
+
+// TimeoutError is reported for a check that did not complete within its timeout.
+var TimeoutError = errors.New("check timed out")
+
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of all the checks in a registry, keyed by check name.
+type Report struct {
+	Healthy bool
+	Checks  map[string]CheckResult
+}
+
+type registeredCheck struct {
+	name    string
+	timeout time.Duration
+	check   Check
+}
+
+// Registry holds the checks of the dependencies the application needs to serve traffic.
+// Subsystems register their own checks, e.g. when they are wired up.
+type Registry struct {
+	mu     sync.RWMutex
+	checks []registeredCheck
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check under the given name. The check fails if it takes longer than the timeout.
+// Registering a name again replaces the previous check.
+func (r *Registry) Register(name string, timeout time.Duration, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = registeredCheck{name, timeout, check}
+			return
+		}
+	}
+	r.checks = append(r.checks, registeredCheck{name, timeout, check})
+	sort.Slice(r.checks, func(i, j int) bool {
+		return r.checks[i].name < r.checks[j].name
+	})
+}
+
+// Run runs all checks concurrently and reports their results.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]registeredCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c registeredCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Healthy: true, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// runCheck runs a single check and stops waiting for it once its timeout has passed,
+// even if the check does not respect the context.
+func runCheck(ctx context.Context, c registeredCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = TimeoutError
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = TimeoutError
+	}
+	result := CheckResult{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
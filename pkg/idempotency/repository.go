@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"time"
+)
+
+// Repository stores idempotency keys.
+type Repository interface {
+	QueryKey(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.IdempotencyKey, error)
+	CreateKey(ctx context.Context, key *entity.IdempotencyKey) error
+	UpdateKey(ctx context.Context, key *entity.IdempotencyKey) error
+	DeleteKey(ctx context.Context, conditions map[string]interface{}) error
+	// DeleteExpiredKeys deletes the keys that expired before the given time and returns how many there were.
+	DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error)
+	Transactional(ctx context.Context, f func(ctx context.Context) error) error
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryKey(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.IdempotencyKey, error) {
+	var keys []entity.IdempotencyKey
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+func (r repository) CreateKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	db := r.db.With(ctx)
+	result := db.Create(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) UpdateKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	db := r.db.With(ctx)
+	result := db.Save(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) DeleteKey(ctx context.Context, conditions map[string]interface{}) error {
+	db := r.db.With(ctx)
+	result := db.Where(conditions).Delete(&entity.IdempotencyKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	db := r.db.With(ctx)
+	result := db.Where("expires_at < ?", before).Delete(&entity.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r repository) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	return r.db.Transactional(ctx, f)
+}
@@ -0,0 +1,170 @@
+// Package idempotency keeps the responses to requests made with idempotency keys, so that a request that is sent
+// again, e.g. because a form was submitted twice or an API client retried after a timeout, gets the response to the
+// first request instead of being carried out twice.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+// KeyHeader and KeyField are the request header and the form field that carry the idempotency key of a request.
+const (
+	KeyHeader = "Idempotency-Key"
+	KeyField  = "idempotency_key"
+)
+
+// MaxKeyLength is the maximum length of an idempotency key.
+const MaxKeyLength = 255
+
+// Response is the response to a request, which is sent again to the requests that repeat it.
+type Response struct {
+	Status      int
+	ContentType string
+	// Location is the Location header of redirects and created resources.
+	Location string
+	Body     []byte
+}
+
+// Service keeps the idempotency keys of requests and the responses to them.
+type Service interface {
+	// Begin records that the request with the key and fingerprint is being handled, and returns nil. If the key was
+	// used before, it returns the response to the earlier request instead, or KeyReusedError if that request had
+	// another fingerprint, or InProgressError if it has no response yet.
+	Begin(ctx context.Context, scope string, key string, fingerprint string) (*Response, error)
+	// Finish stores the response to the request with the key, which is kept until the key expires.
+	Finish(ctx context.Context, scope string, key string, res Response) error
+	// Abandon forgets the request with the key, so that it can be retried, e.g. because it failed with a server
+	// error.
+	Abandon(ctx context.Context, scope string, key string) error
+	// Purge deletes the expired keys and returns how many there were.
+	Purge(ctx context.Context) (int64, error)
+}
+
+type service struct {
+	repo   Repository
+	window time.Duration
+	now    func() time.Time
+	logger log.Logger
+}
+
+var KeyReusedError = errors.New("the idempotency key was already used for another request")
+var InProgressError = errors.New("a request with the same idempotency key is still being handled")
+var InternalError = errors.New("internal error")
+
+// NewService returns a Service that keeps keys for the given window after their first use.
+func NewService(repo Repository, window time.Duration, logger log.Logger) Service {
+	return service{repo, window, time.Now, logger}
+}
+
+func (s service) Begin(ctx context.Context, scope string, key string, fingerprint string) (*Response, error) {
+	var earlier *entity.IdempotencyKey
+	err := s.repo.Transactional(ctx, func(ctx context.Context) error {
+		var err error
+		earlier, err = s.getKey(ctx, scope, key)
+		if earlier != nil || err != nil {
+			return err
+		}
+		return s.repo.CreateKey(ctx, &entity.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   s.now().Add(s.window),
+		})
+	})
+	if err != nil {
+		// a request with the same key may have created it in the meantime
+		earlier, _ = s.getKey(ctx, scope, key)
+		if earlier == nil {
+			s.logger.With(ctx).Errorf("error creating idempotency key: %v", err)
+			return nil, InternalError
+		}
+	}
+	if earlier == nil {
+		return nil, nil
+	}
+	switch {
+	case earlier.Fingerprint != fingerprint:
+		return nil, KeyReusedError
+	case earlier.Status == 0:
+		return nil, InProgressError
+	}
+	return &Response{
+		Status:      earlier.Status,
+		ContentType: earlier.ContentType,
+		Location:    earlier.Location,
+		Body:        earlier.Body,
+	}, nil
+}
+
+func (s service) Finish(ctx context.Context, scope string, key string, res Response) error {
+	return s.repo.Transactional(ctx, func(ctx context.Context) error {
+		keys, err := s.repo.QueryKey(ctx, map[string]interface{}{"scope": scope, "key": key}, "", 1, 0)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error querying idempotency key: %v", err)
+			return InternalError
+		}
+		if len(keys) == 0 {
+			s.logger.With(ctx).Errorf("idempotency key %q of %s disappeared before its response was stored", key, scope)
+			return InternalError
+		}
+		keys[0].Status = res.Status
+		keys[0].ContentType = res.ContentType
+		keys[0].Location = res.Location
+		keys[0].Body = res.Body
+		if err := s.repo.UpdateKey(ctx, &keys[0]); err != nil {
+			s.logger.With(ctx).Errorf("error storing idempotent response: %v", err)
+			return InternalError
+		}
+		return nil
+	})
+}
+
+func (s service) Abandon(ctx context.Context, scope string, key string) error {
+	if err := s.repo.DeleteKey(ctx, map[string]interface{}{"scope": scope, "key": key}); err != nil {
+		s.logger.With(ctx).Errorf("error deleting idempotency key: %v", err)
+		return InternalError
+	}
+	return nil
+}
+
+func (s service) Purge(ctx context.Context) (int64, error) {
+	purged, err := s.repo.DeleteExpiredKeys(ctx, s.now())
+	if err != nil {
+		s.logger.With(ctx).Errorf("error deleting expired idempotency keys: %v", err)
+		return 0, InternalError
+	}
+	return purged, nil
+}
+
+// getKey returns the unexpired key of the scope, or nil if there is none. An expired key is deleted, so that the
+// key can be used again.
+func (s service) getKey(ctx context.Context, scope string, key string) (*entity.IdempotencyKey, error) {
+	conditions := map[string]interface{}{"scope": scope, "key": key}
+	keys, err := s.repo.QueryKey(ctx, conditions, "", 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if keys[0].ExpiresAt.After(s.now()) {
+		return &keys[0], nil
+	}
+	return nil, s.repo.DeleteKey(ctx, conditions)
+}
+
+// Fingerprint returns a hash of the method, URL and body of a request, which tells whether a request with a key
+// that was used before repeats the earlier request.
+func Fingerprint(method string, url string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + url + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, now *time.Time) Service {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "idempotency_keys")
+	return service{NewRepository(dbc, logger), time.Hour, func() time.Time { return *now }, logger}
+}
+
+func TestService_Begin(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestService(t, &now)
+	ctx := context.Background()
+	fingerprint := Fingerprint("POST", "/api/v1/cart/items", []byte(`{"product":"shoe","quantity":1}`))
+	created := Response{Status: http.StatusCreated, ContentType: "application/json", Body: []byte(`{"id":1}`)}
+
+	earlier, err := s.Begin(ctx, "session-1", "key-1", fingerprint)
+	require.Nil(t, err)
+	assert.Nil(t, earlier)
+
+	_, err = s.Begin(ctx, "session-1", "key-1", fingerprint)
+	assert.Equal(t, InProgressError, err)
+
+	require.Nil(t, s.Finish(ctx, "session-1", "key-1", created))
+	earlier, err = s.Begin(ctx, "session-1", "key-1", fingerprint)
+	require.Nil(t, err)
+	assert.Equal(t, &created, earlier)
+
+	_, err = s.Begin(ctx, "session-1", "key-1", Fingerprint("POST", "/api/v1/cart/items", []byte(`{"product":"purse"}`)))
+	assert.Equal(t, KeyReusedError, err)
+
+	// the key of another session is another key
+	earlier, err = s.Begin(ctx, "session-2", "key-1", fingerprint)
+	require.Nil(t, err)
+	assert.Nil(t, earlier)
+
+	// an expired key can be used again
+	now = now.Add(time.Hour)
+	earlier, err = s.Begin(ctx, "session-1", "key-1", fingerprint)
+	require.Nil(t, err)
+	assert.Nil(t, earlier)
+}
+
+func TestService_Abandon(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestService(t, &now)
+	ctx := context.Background()
+	fingerprint := Fingerprint("POST", "/cart/checkout", nil)
+
+	_, err := s.Begin(ctx, "session-1", "key-1", fingerprint)
+	require.Nil(t, err)
+	require.Nil(t, s.Abandon(ctx, "session-1", "key-1"))
+
+	earlier, err := s.Begin(ctx, "session-1", "key-1", fingerprint)
+	require.Nil(t, err)
+	assert.Nil(t, earlier)
+}
+
+func TestService_Purge(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestService(t, &now)
+	ctx := context.Background()
+	fingerprint := Fingerprint("POST", "/cart/add", nil)
+
+	_, err := s.Begin(ctx, "session-1", "old", fingerprint)
+	require.Nil(t, err)
+	now = now.Add(30 * time.Minute)
+	_, err = s.Begin(ctx, "session-1", "new", fingerprint)
+	require.Nil(t, err)
+
+	now = now.Add(45 * time.Minute)
+	purged, err := s.Purge(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, err = s.Begin(ctx, "session-1", "new", fingerprint)
+	assert.Equal(t, InProgressError, err)
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := Fingerprint("POST", "/cart/add", []byte("product=shoe&quantity=1"))
+	assert.Len(t, fingerprint, 64)
+	assert.Equal(t, fingerprint, Fingerprint("POST", "/cart/add", []byte("product=shoe&quantity=1")))
+	assert.NotEqual(t, fingerprint, Fingerprint("POST", "/cart/add", []byte("product=shoe&quantity=2")))
+	assert.NotEqual(t, fingerprint, Fingerprint("PUT", "/cart/add", []byte("product=shoe&quantity=1")))
+	assert.NotEqual(t, fingerprint, Fingerprint("POST", "/cart/update", []byte("product=shoe&quantity=1")))
+}
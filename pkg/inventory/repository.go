@@ -0,0 +1,101 @@
+package inventory
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+// Repository stores the stock of products. Reserve, Release and Commit change the counts with a single
+// conditional UPDATE, so that concurrent requests cannot reserve the same units twice.
+type Repository interface {
+	QueryStock(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Stock, error)
+	CreateStock(ctx context.Context, stock *entity.Stock) error
+	UpdateStock(ctx context.Context, stock *entity.Stock) error
+	// Reserve adds the quantity to the reserved units of the product if that many units are available,
+	// and reports whether it did.
+	Reserve(ctx context.Context, productID uint, quantity int) (bool, error)
+	// Release returns reserved units of the product to the available units.
+	Release(ctx context.Context, productID uint, quantity int) error
+	// Commit removes reserved units of the product from the units on hand, when they are sold.
+	Commit(ctx context.Context, productID uint, quantity int) error
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryStock(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Stock, error) {
+	var stocks []entity.Stock
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&stocks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return stocks, nil
+}
+
+func (r repository) CreateStock(ctx context.Context, stock *entity.Stock) error {
+	db := r.db.With(ctx)
+	result := db.Create(stock)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) UpdateStock(ctx context.Context, stock *entity.Stock) error {
+	db := r.db.With(ctx)
+	result := db.Save(stock)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) Reserve(ctx context.Context, productID uint, quantity int) (bool, error) {
+	db := r.db.With(ctx)
+	result := db.Model(&entity.Stock{}).
+		Where("product_id = ? AND on_hand - reserved >= ?", productID, quantity).
+		Update("reserved", gorm.Expr("reserved + ?", quantity))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r repository) Release(ctx context.Context, productID uint, quantity int) error {
+	db := r.db.With(ctx)
+	result := db.Model(&entity.Stock{}).
+		Where("product_id = ?", productID).
+		Update("reserved", decrement("reserved", quantity))
+	return result.Error
+}
+
+func (r repository) Commit(ctx context.Context, productID uint, quantity int) error {
+	db := r.db.With(ctx)
+	result := db.Model(&entity.Stock{}).
+		Where("product_id = ?", productID).
+		Updates(map[string]interface{}{
+			"on_hand":  decrement("on_hand", quantity),
+			"reserved": decrement("reserved", quantity),
+		})
+	return result.Error
+}
+
+// decrement subtracts the quantity from a count without letting it drop below zero.
+func decrement(column string, quantity int) interface{} {
+	return gorm.Expr("CASE WHEN "+column+" > ? THEN "+column+" - ? ELSE 0 END", quantity, quantity)
+}
@@ -0,0 +1,76 @@
+// Package inventory keeps track of the stock of products and of the units reserved by carts.
+package inventory
+
+import (
+	"context"
+	"errors"
+	"interview/pkg/log"
+)
+
+// Service reserves stock for the items in carts. Products without a stock record are not tracked:
+// any quantity of them can be reserved.
+type Service interface {
+	// Reserve reserves units of the product, or returns OutOfStockError if fewer units are available.
+	Reserve(ctx context.Context, productID uint, quantity int) error
+	// Release returns reserved units when they leave a cart.
+	Release(ctx context.Context, productID uint, quantity int) error
+	// Commit takes reserved units off the stock when their cart is checked out.
+	Commit(ctx context.Context, productID uint, quantity int) error
+}
+
+type service struct {
+	repo   Repository
+	logger log.Logger
+}
+
+var OutOfStockError = errors.New("not enough stock")
+var InternalError = errors.New("internal error")
+
+func NewService(repo Repository, logger log.Logger) Service {
+	return service{repo, logger}
+}
+
+func (s service) Reserve(ctx context.Context, productID uint, quantity int) error {
+	if quantity <= 0 {
+		return nil
+	}
+	reserved, err := s.repo.Reserve(ctx, productID, quantity)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error reserving stock of product %d: %v", productID, err)
+		return InternalError
+	}
+	if reserved {
+		return nil
+	}
+	stocks, err := s.repo.QueryStock(ctx, map[string]interface{}{"product_id": productID}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying stock of product %d: %v", productID, err)
+		return InternalError
+	}
+	if len(stocks) == 0 {
+		return nil
+	}
+	return OutOfStockError
+}
+
+func (s service) Release(ctx context.Context, productID uint, quantity int) error {
+	if quantity <= 0 {
+		return nil
+	}
+	if err := s.repo.Release(ctx, productID, quantity); err != nil {
+		s.logger.With(ctx).Errorf("error releasing stock of product %d: %v", productID, err)
+		return InternalError
+	}
+	return nil
+}
+
+func (s service) Commit(ctx context.Context, productID uint, quantity int) error {
+	if quantity <= 0 {
+		return nil
+	}
+	if err := s.repo.Commit(ctx, productID, quantity); err != nil {
+		s.logger.With(ctx).Errorf("error committing stock of product %d: %v", productID, err)
+		return InternalError
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package inventory
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) Repository {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "stocks")
+	return NewRepository(dbc, logger)
+}
+
+func TestService(t *testing.T) {
+	repo := newTestRepository(t)
+	logger, _ := log.NewForTest()
+	s := NewService(repo, logger)
+	ctx := context.Background()
+	require.Nil(t, repo.CreateStock(ctx, &entity.Stock{ProductID: 1, OnHand: 5}))
+
+	assert.Nil(t, s.Reserve(ctx, 1, 3))
+	assert.Equal(t, OutOfStockError, s.Reserve(ctx, 1, 3))
+	assert.Nil(t, s.Reserve(ctx, 1, 2))
+	assert.Equal(t, entity.Stock{OnHand: 5, Reserved: 5}, stockOf(t, repo, 1))
+
+	assert.Nil(t, s.Release(ctx, 1, 1))
+	assert.Nil(t, s.Commit(ctx, 1, 4))
+	assert.Equal(t, entity.Stock{OnHand: 1, Reserved: 0}, stockOf(t, repo, 1))
+
+	// counts never drop below zero
+	assert.Nil(t, s.Release(ctx, 1, 10))
+	assert.Equal(t, entity.Stock{OnHand: 1, Reserved: 0}, stockOf(t, repo, 1))
+
+	// products without stock are not tracked
+	assert.Nil(t, s.Reserve(ctx, 2, 1000))
+	assert.Nil(t, s.Release(ctx, 2, 1000))
+}
+
+func TestService_ConcurrentReservations(t *testing.T) {
+	repo := newTestRepository(t)
+	logger, _ := log.NewForTest()
+	s := NewService(repo, logger)
+	ctx := context.Background()
+	require.Nil(t, repo.CreateStock(ctx, &entity.Stock{ProductID: 1, OnHand: 10}))
+
+	var wg sync.WaitGroup
+	results := make(chan error, 25)
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- s.Reserve(ctx, 1, 1)
+		}()
+	}
+	wg.Wait()
+	close(results)
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.Equal(t, OutOfStockError, err)
+		}
+	}
+	assert.Equal(t, 10, succeeded)
+	assert.Equal(t, entity.Stock{OnHand: 10, Reserved: 10}, stockOf(t, repo, 1))
+}
+
+// stockOf returns the counts of the stock of the product.
+func stockOf(t *testing.T, repo Repository, productID uint) entity.Stock {
+	stocks, err := repo.QueryStock(context.Background(), map[string]interface{}{"product_id": productID}, "id asc", 1, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(stocks))
+	return entity.Stock{OnHand: stocks[0].OnHand, Reserved: stocks[0].Reserved}
+}
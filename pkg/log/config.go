@@ -0,0 +1,115 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces secret values in log entries.
+const redacted = "***"
+
+// Config describes how a logger created by NewWithConfig writes log entries.
+type Config struct {
+	// Level is the lowest level that is logged: "debug", "info", "warn", "error" or "fatal".
+	Level string
+	// Encoding is "json" or "console".
+	Encoding string
+	// OutputPath is "stdout", "stderr" or the path of a file that log entries are appended to.
+	OutputPath string
+	// Sampling limits every message to the first 100 entries per second and every 100th entry after that.
+	Sampling bool
+	// Secrets are values that are replaced with "***" wherever they appear in a log entry.
+	Secrets []string
+}
+
+// NewWithConfig creates a new logger using the given configuration.
+// It also returns the level of the logger, which can be changed while the logger is in use.
+// The level is an http.Handler that reports the level on GET and changes it on PUT, e.g. with {"level":"debug"}.
+func NewWithConfig(c Config) (Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(c.Level)
+	if err != nil {
+		return nil, level, err
+	}
+	zc := zap.NewProductionConfig()
+	zc.Level = level
+	zc.Encoding = c.Encoding
+	if c.Encoding == "console" {
+		zc.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	zc.OutputPaths = []string{c.OutputPath}
+	if !c.Sampling {
+		zc.Sampling = nil
+	}
+	var opts []zap.Option
+	if replacer := newSecretReplacer(c.Secrets); replacer != nil {
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &redactingCore{Core: core, replacer: replacer}
+		}))
+	}
+	l, err := zc.Build(opts...)
+	if err != nil {
+		return nil, level, err
+	}
+	return NewWithZap(l), level, nil
+}
+
+// newSecretReplacer returns a replacer of the given secrets, or nil if there are none.
+func newSecretReplacer(secrets []string) *strings.Replacer {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, redacted)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// redactingCore removes secrets from the message and the fields of log entries before they are written.
+type redactingCore struct {
+	zapcore.Core
+	replacer *strings.Replacer
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redact(fields)), replacer: c.replacer}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// the wrapped core, e.g. a sampler, decides whether the entry is written
+	if c.Core.Check(ent, nil) == nil {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.replacer.Replace(ent.Message)
+	return c.Core.Write(ent, c.redact(fields))
+}
+
+// redact returns the fields with every secret replaced. Fields that are not strings are
+// replaced with their formatted value only when that contains a secret.
+func (c *redactingCore) redact(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = c.replacer.Replace(f.String)
+		case zapcore.ErrorType, zapcore.StringerType, zapcore.ReflectType:
+			if f.Interface != nil {
+				s := fmt.Sprint(f.Interface)
+				if r := c.replacer.Replace(s); r != s {
+					f = zap.String(f.Key, r)
+				}
+			}
+		}
+		result[i] = f
+	}
+	return result
+}
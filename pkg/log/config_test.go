@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileLogger(t *testing.T, c Config) (Logger, func() string) {
+	c.OutputPath = filepath.Join(t.TempDir(), "log.json")
+	l, level, err := NewWithConfig(c)
+	require.NoError(t, err)
+	assert.Equal(t, c.Level, level.String())
+	return l, func() string {
+		_ = l.Sync()
+		out, err := os.ReadFile(c.OutputPath)
+		require.NoError(t, err)
+		return string(out)
+	}
+}
+
+func TestNewWithConfig(t *testing.T) {
+	l, read := newFileLogger(t, Config{Level: "warn", Encoding: "json"})
+	l.Info("hidden")
+	l.Warnf("shown %d", 1)
+	l.Error("error")
+	out := read()
+	assert.NotContains(t, out, "hidden")
+	assert.Contains(t, out, `"level":"warn","ts":`)
+	assert.Contains(t, out, `"msg":"shown 1"`)
+	assert.Contains(t, out, `"msg":"error"`)
+
+	l, read = newFileLogger(t, Config{Level: "debug", Encoding: "console"})
+	l.Debug("debug message")
+	out = read()
+	assert.Contains(t, out, "DEBUG")
+	assert.Contains(t, out, "debug message")
+
+	_, _, err := NewWithConfig(Config{Level: "verbose", Encoding: "json", OutputPath: "stderr"})
+	assert.Error(t, err)
+}
+
+func TestNewWithConfig_Sampling(t *testing.T) {
+	l, read := newFileLogger(t, Config{Level: "info", Encoding: "json", Sampling: true})
+	for i := 0; i < 300; i++ {
+		l.Info("repeated")
+	}
+	assert.Less(t, strings.Count(read(), "repeated"), 300)
+
+	l, read = newFileLogger(t, Config{Level: "info", Encoding: "json"})
+	for i := 0; i < 300; i++ {
+		l.Info("repeated")
+	}
+	assert.Equal(t, 300, strings.Count(read(), "repeated"))
+}
+
+func TestNewWithConfig_Level(t *testing.T) {
+	c := Config{Level: "info", Encoding: "json", OutputPath: filepath.Join(t.TempDir(), "log.json")}
+	l, level, err := NewWithConfig(c)
+	require.NoError(t, err)
+
+	l.Debug("before")
+	res := httptest.NewRecorder()
+	level.ServeHTTP(res, httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, res.Code)
+	l.Debug("after")
+
+	_ = l.Sync()
+	out, _ := os.ReadFile(c.OutputPath)
+	assert.NotContains(t, string(out), "before")
+	assert.Contains(t, string(out), "after")
+}
+
+func TestNewWithConfig_Secrets(t *testing.T) {
+	l, read := newFileLogger(t, Config{Level: "info", Encoding: "json", Secrets: []string{"s3cret", ""}})
+	l = l.With(nil, "dsn", "user:s3cret@tcp(db)/app")
+	l.Errorf("cannot connect with password %s", "s3cret")
+	l.With(nil, "error", errors.New("bad password s3cret"), "count", 3).Info("failed")
+	out := read()
+	assert.NotContains(t, out, "s3cret")
+	assert.Contains(t, out, `"dsn":"user:***@tcp(db)/app"`)
+	assert.Contains(t, out, `"msg":"cannot connect with password ***"`)
+	assert.Contains(t, out, `"error":"bad password ***"`)
+	assert.Contains(t, out, `"count":3`)
+}
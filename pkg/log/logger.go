@@ -4,8 +4,10 @@ package log
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -20,15 +22,26 @@ type Logger interface {
 	Debug(args ...interface{})
 	// Info uses fmt.Sprint to construct and log a message at INFO level
 	Info(args ...interface{})
+	// Warn uses fmt.Sprint to construct and log a message at WARN level
+	Warn(args ...interface{})
 	// Error uses fmt.Sprint to construct and log a message at ERROR level
 	Error(args ...interface{})
+	// Fatal uses fmt.Sprint to construct and log a message at FATAL level, then calls os.Exit(1)
+	Fatal(args ...interface{})
 
 	// Debugf uses fmt.Sprintf to construct and log a message at DEBUG level
 	Debugf(format string, args ...interface{})
 	// Infof uses fmt.Sprintf to construct and log a message at INFO level
 	Infof(format string, args ...interface{})
+	// Warnf uses fmt.Sprintf to construct and log a message at WARN level
+	Warnf(format string, args ...interface{})
 	// Errorf uses fmt.Sprintf to construct and log a message at ERROR level
 	Errorf(format string, args ...interface{})
+	// Fatalf uses fmt.Sprintf to construct and log a message at FATAL level, then calls os.Exit(1)
+	Fatalf(format string, args ...interface{})
+
+	// Sync flushes any buffered log entries
+	Sync() error
 }
 
 type logger struct {
@@ -42,6 +55,9 @@ const (
 	correlationIDKey
 )
 
+// maxIDLength is the longest request or correlation ID accepted from a client.
+const maxIDLength = 128
+
 // New creates a new logger using the default configuration.
 func New() Logger {
 	l, _ := zap.NewProduction()
@@ -63,6 +79,7 @@ func NewForTest() (Logger, *observer.ObservedLogs) {
 //
 // If the context contains request ID and/or correlation ID information (recorded via WithRequestID()
 // and WithCorrelationID()), they will be added to every log message generated by the new logger.
+// So will the trace and span IDs of an OpenTelemetry span in the context.
 //
 // The arguments should be specified as a sequence of name, value pairs with names being strings.
 // The arguments will also be added to every log message generated by the logger.
@@ -74,6 +91,9 @@ func (l *logger) With(ctx context.Context, args ...interface{}) Logger {
 		if id, ok := ctx.Value(correlationIDKey).(string); ok {
 			args = append(args, zap.String("correlation_id", id))
 		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			args = append(args, zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+		}
 	}
 	if len(args) > 0 {
 		return &logger{l.SugaredLogger.With(args...)}
@@ -82,18 +102,25 @@ func (l *logger) With(ctx context.Context, args ...interface{}) Logger {
 }
 
 // WithRequest returns a context which knows the request ID and correlation ID in the given request.
+// A new request ID is generated if the request has none or an invalid one.
 func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	id := getRequestID(req)
-	if id == "" {
+	if !validID(id) {
 		id = uuid.New().String()
 	}
 	ctx = context.WithValue(ctx, requestIDKey, id)
-	if id := getCorrelationID(req); id != "" {
+	if id := getCorrelationID(req); validID(id) {
 		ctx = context.WithValue(ctx, correlationIDKey, id)
 	}
 	return ctx
 }
 
+// RequestID returns the request ID recorded in the context by WithRequest, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
 // getCorrelationID extracts the correlation ID from the HTTP request
 func getCorrelationID(req *http.Request) string {
 	return req.Header.Get("X-Correlation-ID")
@@ -103,3 +130,16 @@ func getCorrelationID(req *http.Request) string {
 func getRequestID(req *http.Request) string {
 	return req.Header.Get("X-Request-ID")
 }
+
+// validID tells whether an ID sent by a client is safe to copy into logs and response headers.
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:", r)) {
+			return false
+		}
+	}
+	return true
+}
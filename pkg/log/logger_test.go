@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"net/http"
 	"reflect"
@@ -30,6 +31,15 @@ func TestWithRequest(t *testing.T) {
 	ctx = WithRequest(context.Background(), req)
 	assert.NotEmpty(t, ctx.Value(requestIDKey).(string))
 	assert.Equal(t, "123", ctx.Value(correlationIDKey).(string))
+
+	// IDs that are not safe to log are replaced or dropped
+	req = buildRequest("abc\ninjected", "1 2")
+	ctx = WithRequest(context.Background(), req)
+	assert.NotEqual(t, "abc\ninjected", RequestID(ctx))
+	assert.Len(t, RequestID(ctx), 36)
+	assert.Nil(t, ctx.Value(correlationIDKey))
+
+	assert.Empty(t, RequestID(context.Background()))
 }
 
 func Test_getCorrelationID(t *testing.T) {
@@ -57,6 +67,20 @@ func Test_logger_With(t *testing.T) {
 	assert.False(t, reflect.DeepEqual(l3, l2))
 }
 
+func Test_logger_With_Trace(t *testing.T) {
+	l, entries := NewForTest()
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	l.With(ctx).Info("traced")
+	fields := entries.All()[0].ContextMap()
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", fields["span_id"])
+}
+
 func buildRequest(requestID, correlationID string) *http.Request {
 	req, _ := http.NewRequest("GET", "http://example.com", bytes.NewBufferString(""))
 	if requestID != "" {
@@ -0,0 +1,144 @@
+// Package money provides an exact monetary amount type.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultCurrency is the currency used for prices when none is specified.
+const DefaultCurrency = "USD"
+
+// minorUnits lists the number of decimal places of currencies that do not use two.
+var minorUnits = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// Money is an amount of money in the minor unit of its currency (e.g. cents for USD).
+// Arithmetic on Money is exact. A zero value has no currency and adopts the currency
+// of the amount it is combined with.
+type Money struct {
+	Amount   int64
+	Currency string `gorm:"size:3"`
+}
+
+// New returns an amount of money given in minor units of the currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Parse parses a decimal string such as "12.50" into an amount of the given currency.
+// It returns an error if the string has more decimal places than the currency allows.
+func Parse(s string, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(scale(currency)))
+	if !r.IsInt() {
+		return Money{}, fmt.Errorf("amount %q has too many decimal places for %s", s, currency)
+	}
+	if !r.Num().IsInt64() {
+		return Money{}, fmt.Errorf("amount %q is out of range", s)
+	}
+	return Money{Amount: r.Num().Int64(), Currency: currency}, nil
+}
+
+// Exponent returns the number of decimal places used by the currency.
+func Exponent(currency string) int {
+	if e, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+func scale(currency string) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Exponent(currency))), nil)
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add returns the sum of m and o. It panics if the amounts are in different currencies.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Sub returns the difference of m and o. It panics if the amounts are in different currencies.
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Multiply returns m multiplied by n.
+func (m Money) Multiply(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+func (m Money) currencyWith(o Money) string {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "":
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: currency mismatch %s and %s", m.Currency, o.Currency))
+}
+
+// Decimal returns the amount as a decimal string in major units, e.g. "12.50".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := fmt.Sprintf("%0*d", exp+1, amount)
+	if exp == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats the amount for display, e.g. "12.50 USD".
+func (m Money) String() string {
+	if m.Currency == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + m.Currency
+}
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"amount": "12.50", "currency": "USD"}.
+// The amount is a string so that clients do not lose precision by parsing it as a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes an amount encoded by MarshalJSON.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v jsonMoney
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	parsed, err := Parse(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,57 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse("12.5", "usd")
+	assert.Nil(t, err)
+	assert.Equal(t, New(1250, "USD"), m)
+
+	m, err = Parse("-0.01", "USD")
+	assert.Nil(t, err)
+	assert.Equal(t, New(-1, "USD"), m)
+
+	m, err = Parse("1000", "JPY")
+	assert.Nil(t, err)
+	assert.Equal(t, New(1000, "JPY"), m)
+
+	_, err = Parse("0.001", "USD")
+	assert.NotNil(t, err)
+	_, err = Parse("abc", "USD")
+	assert.NotNil(t, err)
+}
+
+func TestArithmetic(t *testing.T) {
+	// 0.1 + 0.2 drifts with float64 but not with Money
+	sum := New(10, "USD").Add(New(20, "USD"))
+	assert.Equal(t, New(30, "USD"), sum)
+	assert.Equal(t, New(10, "USD"), sum.Sub(New(20, "USD")))
+	assert.Equal(t, New(90, "USD"), sum.Multiply(3))
+	assert.Equal(t, New(30, "USD"), Money{}.Add(sum))
+	assert.True(t, Money{}.IsZero())
+	assert.Panics(t, func() { New(1, "USD").Add(New(1, "EUR")) })
+}
+
+func TestFormatting(t *testing.T) {
+	assert.Equal(t, "12.50", New(1250, "USD").Decimal())
+	assert.Equal(t, "0.05", New(5, "USD").Decimal())
+	assert.Equal(t, "-1.05", New(-105, "USD").Decimal())
+	assert.Equal(t, "1000", New(1000, "JPY").Decimal())
+	assert.Equal(t, "1.234", New(1234, "KWD").Decimal())
+	assert.Equal(t, "12.50 USD", New(1250, "USD").String())
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(New(1250, "USD"))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"amount":"12.50","currency":"USD"}`, string(data))
+
+	var m Money
+	assert.Nil(t, json.Unmarshal(data, &m))
+	assert.Equal(t, New(1250, "USD"), m)
+}
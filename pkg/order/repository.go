@@ -0,0 +1,149 @@
+package order
+
+import (
+	"context"
+	"time"
+
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+// Order is an order together with its lines and tax lines.
+type Order struct {
+	entity.Order
+	Lines    []entity.OrderLine
+	TaxLines []entity.OrderTaxLine
+}
+
+// Period is a range of creation times, including From and excluding To. A zero bound leaves that side open.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+type Repository interface {
+	QueryOrder(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Order, error)
+	QueryOrderInPeriod(ctx context.Context, conditions map[string]interface{}, period Period, order string, limit int, offset int) ([]entity.Order, error)
+	CountOrderInPeriod(ctx context.Context, conditions map[string]interface{}, period Period) (int64, error)
+	QueryOrderLine(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.OrderLine, error)
+	CreateOrder(ctx context.Context, orderEntity *entity.Order) error
+	CreateOrderLine(ctx context.Context, orderLine *entity.OrderLine) error
+	QueryOrderTaxLine(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.OrderTaxLine, error)
+	CreateOrderTaxLine(ctx context.Context, taxLine *entity.OrderTaxLine) error
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryOrder(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Order, error) {
+	var orders []entity.Order
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&orders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return orders, nil
+}
+
+func (r repository) QueryOrderInPeriod(ctx context.Context, conditions map[string]interface{}, period Period, order string, limit int, offset int) ([]entity.Order, error) {
+	var orders []entity.Order
+	db := r.db.With(ctx)
+	result := inPeriod(db.Where(conditions), period).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&orders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return orders, nil
+}
+
+func (r repository) CountOrderInPeriod(ctx context.Context, conditions map[string]interface{}, period Period) (int64, error) {
+	var count int64
+	db := r.db.With(ctx)
+	result := inPeriod(db.Model(&entity.Order{}).Where(conditions), period).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// inPeriod restricts a query to the orders created within the period.
+func inPeriod(db *gorm.DB, period Period) *gorm.DB {
+	if !period.From.IsZero() {
+		db = db.Where("created_at >= ?", period.From)
+	}
+	if !period.To.IsZero() {
+		db = db.Where("created_at < ?", period.To)
+	}
+	return db
+}
+
+func (r repository) QueryOrderLine(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.OrderLine, error) {
+	var orderLines []entity.OrderLine
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&orderLines)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return orderLines, nil
+}
+
+func (r repository) CreateOrder(ctx context.Context, orderEntity *entity.Order) error {
+	db := r.db.With(ctx)
+	result := db.Create(orderEntity)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) CreateOrderLine(ctx context.Context, orderLine *entity.OrderLine) error {
+	db := r.db.With(ctx)
+	result := db.Create(orderLine)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) QueryOrderTaxLine(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.OrderTaxLine, error) {
+	var taxLines []entity.OrderTaxLine
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&taxLines)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return taxLines, nil
+}
+
+func (r repository) CreateOrderTaxLine(ctx context.Context, taxLine *entity.OrderTaxLine) error {
+	db := r.db.With(ctx)
+	result := db.Create(taxLine)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package order
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRepository_QueryOrderInPeriod(t *testing.T) {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "orders")
+	repo := NewRepository(dbc, logger)
+	ctx := context.Background()
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, sessionID := range []string{"a", "a", "a", "b"} {
+		placed := entity.Order{
+			Model:     gorm.Model{CreatedAt: day.AddDate(0, 0, i)},
+			SessionID: sessionID,
+			Status:    entity.OrderPlaced,
+		}
+		require.Nil(t, repo.CreateOrder(ctx, &placed))
+	}
+
+	conditions := map[string]interface{}{"session_id": "a"}
+	orders, err := repo.QueryOrderInPeriod(ctx, conditions, Period{}, "created_at desc", 2, 0)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(orders))
+	assert.True(t, orders[0].CreatedAt.Equal(day.AddDate(0, 0, 2)))
+	count, err := repo.CountOrderInPeriod(ctx, conditions, Period{})
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+
+	period := Period{From: day.AddDate(0, 0, 1), To: day.AddDate(0, 0, 2)}
+	orders, err = repo.QueryOrderInPeriod(ctx, conditions, period, "id asc", -1, -1)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(orders))
+	assert.True(t, orders[0].CreatedAt.Equal(day.AddDate(0, 0, 1)))
+	count, err = repo.CountOrderInPeriod(ctx, conditions, Period{From: day.AddDate(0, 0, 1)})
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
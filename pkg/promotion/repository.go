@@ -0,0 +1,72 @@
+package promotion
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+// Repository stores coupons.
+type Repository interface {
+	QueryCoupon(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Coupon, error)
+	CreateCoupon(ctx context.Context, coupon *entity.Coupon) error
+	UpdateCoupon(ctx context.Context, coupon *entity.Coupon) error
+	// Redeem counts a use of the coupon if it has uses left, and reports whether it did. The check and the count
+	// are a single conditional UPDATE, so concurrent orders cannot use the coupon more often than allowed.
+	Redeem(ctx context.Context, couponID uint) (bool, error)
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryCoupon(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.Coupon, error) {
+	var coupons []entity.Coupon
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&coupons)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return coupons, nil
+}
+
+func (r repository) CreateCoupon(ctx context.Context, coupon *entity.Coupon) error {
+	db := r.db.With(ctx)
+	result := db.Create(coupon)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) UpdateCoupon(ctx context.Context, coupon *entity.Coupon) error {
+	db := r.db.With(ctx)
+	result := db.Save(coupon)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) Redeem(ctx context.Context, couponID uint) (bool, error) {
+	db := r.db.With(ctx)
+	result := db.Model(&entity.Coupon{}).
+		Where("id = ? AND (max_uses = 0 OR uses < max_uses)", couponID).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
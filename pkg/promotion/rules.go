@@ -0,0 +1,158 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"interview/pkg/entity"
+	"interview/pkg/money"
+)
+
+var CouponNotFoundError = errors.New("unknown coupon code")
+var CouponNotStartedError = errors.New("coupon is not valid yet")
+var CouponExpiredError = errors.New("coupon has expired")
+var CouponUsedUpError = errors.New("coupon has been used up")
+var MinimumNotMetError = errors.New("coupon requires a cart subtotal")
+var NotApplicableError = errors.New("coupon does not apply to the items in the cart")
+
+// Discount is the reduction a coupon gives on a cart.
+type Discount struct {
+	Code        string
+	Description string
+	Amount      money.Money
+	// Items splits the amount over the cart items it is taken off, by item ID.
+	Items map[uint]money.Money
+}
+
+// NormalizeCode returns the form in which coupon codes are stored: trimmed and upper case.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Evaluate returns the discount the coupon gives on the cart items at the given time, or an error telling why the
+// coupon does not apply. The result depends on nothing but its arguments: items are considered in ID order and
+// the cheapest units are the free ones, and amounts are rounded down to the minor unit of the currency.
+// A percentage or fixed amount is split over the eligible items in proportion to their prices.
+func Evaluate(coupon entity.Coupon, items []entity.CartItem, now time.Time) (Discount, error) {
+	if !coupon.Active {
+		return Discount{}, CouponNotFoundError
+	}
+	if coupon.ValidFrom != nil && now.Before(*coupon.ValidFrom) {
+		return Discount{}, CouponNotStartedError
+	}
+	if coupon.ValidUntil != nil && !now.Before(*coupon.ValidUntil) {
+		return Discount{}, CouponExpiredError
+	}
+	if coupon.MaxUses > 0 && coupon.Uses >= coupon.MaxUses {
+		return Discount{}, CouponUsedUpError
+	}
+
+	items = append([]entity.CartItem(nil), items...)
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	var subtotal money.Money
+	var eligible []entity.CartItem
+	for _, item := range items {
+		subtotal = subtotal.Add(item.Price)
+		if coupon.ProductID == 0 || item.ProductID == coupon.ProductID {
+			eligible = append(eligible, item)
+		}
+	}
+	if !coupon.MinSubtotal.IsZero() &&
+		(subtotal.Currency != coupon.MinSubtotal.Currency || subtotal.Amount < coupon.MinSubtotal.Amount) {
+		return Discount{}, fmt.Errorf("%w of %s", MinimumNotMetError, coupon.MinSubtotal)
+	}
+	if len(eligible) == 0 {
+		return Discount{}, NotApplicableError
+	}
+
+	var eligibleSubtotal money.Money
+	for _, item := range eligible {
+		eligibleSubtotal = eligibleSubtotal.Add(item.Price)
+	}
+	discount := Discount{Code: coupon.Code, Amount: money.Money{Currency: eligibleSubtotal.Currency}}
+	switch coupon.Kind {
+	case entity.CouponPercentage:
+		discount.Description = fmt.Sprintf("%d%% off", coupon.Percent)
+		discount.Amount.Amount = eligibleSubtotal.Amount * int64(coupon.Percent) / 100
+		discount.Items = split(discount.Amount, eligible)
+	case entity.CouponFixed:
+		if coupon.Amount.Currency != eligibleSubtotal.Currency {
+			return Discount{}, NotApplicableError
+		}
+		discount.Description = fmt.Sprintf("%s off", coupon.Amount)
+		discount.Amount.Amount = min(coupon.Amount.Amount, eligibleSubtotal.Amount)
+		discount.Items = split(discount.Amount, eligible)
+	case entity.CouponBuyXGetY:
+		discount.Description = fmt.Sprintf("buy %d get %d free", coupon.BuyQuantity, coupon.FreeQuantity)
+		discount.Items = freeUnits(eligible, coupon.BuyQuantity, coupon.FreeQuantity)
+		for _, amount := range discount.Items {
+			discount.Amount = discount.Amount.Add(amount)
+		}
+	default:
+		return Discount{}, NotApplicableError
+	}
+	if coupon.ProductID != 0 {
+		discount.Description += " " + eligible[0].ProductName
+	}
+	if discount.Amount.Amount <= 0 {
+		return Discount{}, NotApplicableError
+	}
+	return discount, nil
+}
+
+// split divides an amount over the items in proportion to their prices. The cents left over by rounding down
+// go to the first items.
+func split(amount money.Money, items []entity.CartItem) map[uint]money.Money {
+	var total int64
+	for _, item := range items {
+		total += item.Price.Amount
+	}
+	shares := map[uint]money.Money{}
+	if total == 0 {
+		return shares
+	}
+	left := amount.Amount
+	for _, item := range items {
+		share := amount.Amount * item.Price.Amount / total
+		shares[item.ID] = money.Money{Amount: share, Currency: amount.Currency}
+		left -= share
+	}
+	for i := 0; left > 0; i = (i + 1) % len(items) {
+		share := shares[items[i].ID]
+		share.Amount++
+		shares[items[i].ID] = share
+		left--
+	}
+	return shares
+}
+
+// freeUnits returns the price of the units that are free when free units are given for every buy units bought,
+// by item ID. The cheapest units are the free ones.
+func freeUnits(items []entity.CartItem, buy int, free int) map[uint]money.Money {
+	type unit struct {
+		itemID uint
+		price  money.Money
+	}
+	var units []unit
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		price := money.Money{Amount: item.Price.Amount / int64(item.Quantity), Currency: item.Price.Currency}
+		for i := 0; i < item.Quantity; i++ {
+			units = append(units, unit{item.ID, price})
+		}
+	}
+	sort.SliceStable(units, func(i, j int) bool { return units[i].price.Amount < units[j].price.Amount })
+	freed := map[uint]money.Money{}
+	if buy <= 0 || free <= 0 {
+		return freed
+	}
+	for _, u := range units[:len(units)/(buy+free)*free] {
+		freed[u.itemID] = freed[u.itemID].Add(u.price)
+	}
+	return freed
+}
@@ -0,0 +1,172 @@
+package promotion
+
+import (
+	"interview/pkg/entity"
+	"interview/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func usd(cents int64) money.Money {
+	return money.New(cents, "USD")
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	items := []entity.CartItem{
+		{Model: gorm.Model{ID: 2}, ProductID: 2, ProductName: "purse", Quantity: 1, Price: usd(20000)},
+		{Model: gorm.Model{ID: 1}, ProductID: 1, ProductName: "shoe", Quantity: 3, Price: usd(30000)},
+	}
+	tests := []struct {
+		name        string
+		coupon      entity.Coupon
+		description string
+		amount      money.Money
+		err         error
+	}{
+		{
+			name:        "percentage of the cart",
+			coupon:      entity.Coupon{Kind: entity.CouponPercentage, Percent: 15},
+			description: "15% off",
+			amount:      usd(7500),
+		},
+		{
+			name:        "percentage of a product",
+			coupon:      entity.Coupon{Kind: entity.CouponPercentage, Percent: 33, ProductID: 1},
+			description: "33% off shoe",
+			amount:      usd(9900),
+		},
+		{
+			name:        "fixed amount",
+			coupon:      entity.Coupon{Kind: entity.CouponFixed, Amount: usd(1000)},
+			description: "10.00 USD off",
+			amount:      usd(1000),
+		},
+		{
+			name:        "fixed amount up to the price of the product",
+			coupon:      entity.Coupon{Kind: entity.CouponFixed, Amount: usd(50000), ProductID: 2},
+			description: "500.00 USD off purse",
+			amount:      usd(20000),
+		},
+		{
+			name:   "fixed amount in another currency",
+			coupon: entity.Coupon{Kind: entity.CouponFixed, Amount: money.New(1000, "EUR")},
+			err:    NotApplicableError,
+		},
+		{
+			name:        "buy 2 get 1 free on the cart makes the cheapest unit free",
+			coupon:      entity.Coupon{Kind: entity.CouponBuyXGetY, BuyQuantity: 2, FreeQuantity: 1},
+			description: "buy 2 get 1 free",
+			amount:      usd(10000),
+		},
+		{
+			name:        "buy 1 get 1 free on a product",
+			coupon:      entity.Coupon{Kind: entity.CouponBuyXGetY, BuyQuantity: 1, FreeQuantity: 1, ProductID: 1},
+			description: "buy 1 get 1 free shoe",
+			amount:      usd(10000),
+		},
+		{
+			name:   "buy 1 get 1 free without enough units",
+			coupon: entity.Coupon{Kind: entity.CouponBuyXGetY, BuyQuantity: 1, FreeQuantity: 1, ProductID: 2},
+			err:    NotApplicableError,
+		},
+		{
+			name:   "product not in the cart",
+			coupon: entity.Coupon{Kind: entity.CouponPercentage, Percent: 10, ProductID: 3},
+			err:    NotApplicableError,
+		},
+		{
+			name:        "minimum subtotal met",
+			coupon:      entity.Coupon{Kind: entity.CouponFixed, Amount: usd(1000), MinSubtotal: usd(50000)},
+			description: "10.00 USD off",
+			amount:      usd(1000),
+		},
+		{
+			name:   "minimum subtotal not met",
+			coupon: entity.Coupon{Kind: entity.CouponFixed, Amount: usd(1000), MinSubtotal: usd(50001)},
+			err:    MinimumNotMetError,
+		},
+		{
+			name:        "within the validity period",
+			coupon:      entity.Coupon{Kind: entity.CouponPercentage, Percent: 10, ValidFrom: &now, ValidUntil: &after},
+			description: "10% off",
+			amount:      usd(5000),
+		},
+		{
+			name:   "before the validity period",
+			coupon: entity.Coupon{Kind: entity.CouponPercentage, Percent: 10, ValidFrom: &after},
+			err:    CouponNotStartedError,
+		},
+		{
+			name:   "after the validity period",
+			coupon: entity.Coupon{Kind: entity.CouponPercentage, Percent: 10, ValidFrom: &before, ValidUntil: &now},
+			err:    CouponExpiredError,
+		},
+		{
+			name:        "uses left",
+			coupon:      entity.Coupon{Kind: entity.CouponPercentage, Percent: 10, MaxUses: 2, Uses: 1},
+			description: "10% off",
+			amount:      usd(5000),
+		},
+		{
+			name:   "used up",
+			coupon: entity.Coupon{Kind: entity.CouponPercentage, Percent: 10, MaxUses: 2, Uses: 2},
+			err:    CouponUsedUpError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Code = "TEST"
+			tt.coupon.Active = true
+			discount, err := Evaluate(tt.coupon, items, now)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "TEST", discount.Code)
+			assert.Equal(t, tt.description, discount.Description)
+			assert.Equal(t, tt.amount, discount.Amount)
+			split := money.Money{}
+			for _, amount := range discount.Items {
+				split = split.Add(amount)
+			}
+			assert.Equal(t, tt.amount, split)
+		})
+	}
+}
+
+func TestEvaluate_Items(t *testing.T) {
+	now := time.Now()
+	items := []entity.CartItem{
+		{Model: gorm.Model{ID: 1}, ProductID: 1, Quantity: 1, Price: usd(100)},
+		{Model: gorm.Model{ID: 2}, ProductID: 2, Quantity: 1, Price: usd(100)},
+		{Model: gorm.Model{ID: 3}, ProductID: 3, Quantity: 2, Price: usd(50)},
+	}
+
+	discount, err := Evaluate(entity.Coupon{Kind: entity.CouponFixed, Amount: usd(100), Active: true}, items, now)
+	assert.Nil(t, err)
+	assert.Equal(t, map[uint]money.Money{1: usd(40), 2: usd(40), 3: usd(20)}, discount.Items)
+
+	discount, err = Evaluate(entity.Coupon{Kind: entity.CouponBuyXGetY, BuyQuantity: 1, FreeQuantity: 1, Active: true}, items, now)
+	assert.Nil(t, err)
+	assert.Equal(t, map[uint]money.Money{3: usd(50)}, discount.Items)
+}
+
+func TestEvaluate_Inactive(t *testing.T) {
+	coupon := entity.Coupon{Code: "TEST", Kind: entity.CouponPercentage, Percent: 10}
+	items := []entity.CartItem{{ProductID: 1, Quantity: 1, Price: usd(10000)}}
+	_, err := Evaluate(coupon, items, time.Now())
+	assert.Equal(t, CouponNotFoundError, err)
+}
+
+func TestEvaluate_MinimumNotMetMessage(t *testing.T) {
+	coupon := entity.Coupon{Code: "TEST", Kind: entity.CouponPercentage, Percent: 10, MinSubtotal: usd(5000), Active: true}
+	items := []entity.CartItem{{ProductID: 1, Quantity: 1, Price: usd(1000)}}
+	_, err := Evaluate(coupon, items, time.Now())
+	assert.EqualError(t, err, "coupon requires a cart subtotal of 50.00 USD")
+}
@@ -0,0 +1,142 @@
+// Package promotion keeps the coupons customers apply to their carts and evaluates the discounts they give.
+package promotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+// Service manages coupons and evaluates them on carts.
+type Service interface {
+	// CreateCoupon checks the rules of a new coupon and stores it with a normalized code.
+	CreateCoupon(ctx context.Context, coupon *entity.Coupon) error
+	// GetCoupons returns all coupons ordered by code.
+	GetCoupons(ctx context.Context) ([]entity.Coupon, error)
+	// Evaluate returns the discount the coupon with the code gives on the items now.
+	Evaluate(ctx context.Context, code string, items []entity.CartItem) (Discount, error)
+	// Redeem counts a use of the coupon with the code by an order, or returns CouponUsedUpError.
+	Redeem(ctx context.Context, code string) error
+}
+
+type service struct {
+	repo   Repository
+	logger log.Logger
+	now    func() time.Time
+}
+
+var InvalidCouponError = errors.New("invalid coupon")
+var CouponCodeTakenError = errors.New("a coupon with this code already exists")
+var InternalError = errors.New("internal error")
+
+var codeRegex = regexp.MustCompile(`^[A-Z0-9_-]{1,64}$`)
+
+func NewService(repo Repository, logger log.Logger) Service {
+	return service{repo, logger, time.Now}
+}
+
+func (s service) CreateCoupon(ctx context.Context, coupon *entity.Coupon) error {
+	coupon.Code = NormalizeCode(coupon.Code)
+	if err := validateCoupon(*coupon); err != nil {
+		return err
+	}
+	coupons, err := s.repo.QueryCoupon(ctx, map[string]interface{}{"code": coupon.Code}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying coupon: %v", err)
+		return InternalError
+	}
+	if len(coupons) > 0 {
+		return CouponCodeTakenError
+	}
+	if err := s.repo.CreateCoupon(ctx, coupon); err != nil {
+		s.logger.With(ctx).Errorf("error creating coupon: %v", err)
+		return InternalError
+	}
+	return nil
+}
+
+// validateCoupon checks that the rules of the coupon can be evaluated.
+func validateCoupon(coupon entity.Coupon) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", InvalidCouponError, fmt.Sprintf(format, args...))
+	}
+	if !codeRegex.MatchString(coupon.Code) {
+		return invalid("the code must be 1 to 64 letters, digits, dashes or underscores")
+	}
+	switch coupon.Kind {
+	case entity.CouponPercentage:
+		if coupon.Percent < 1 || coupon.Percent > 100 {
+			return invalid("the percentage must be between 1 and 100")
+		}
+	case entity.CouponFixed:
+		if coupon.Amount.Amount <= 0 || coupon.Amount.Currency == "" {
+			return invalid("the amount must be positive")
+		}
+	case entity.CouponBuyXGetY:
+		if coupon.BuyQuantity < 1 || coupon.FreeQuantity < 1 {
+			return invalid("the quantities to buy and get free must be at least 1")
+		}
+	default:
+		return invalid("unknown kind %q", coupon.Kind)
+	}
+	if coupon.MinSubtotal.Amount < 0 {
+		return invalid("the minimum subtotal cannot be negative")
+	}
+	if coupon.ValidFrom != nil && coupon.ValidUntil != nil && !coupon.ValidFrom.Before(*coupon.ValidUntil) {
+		return invalid("the start of the validity period must be before its end")
+	}
+	if coupon.MaxUses < 0 {
+		return invalid("the number of uses cannot be negative")
+	}
+	return nil
+}
+
+func (s service) GetCoupons(ctx context.Context) ([]entity.Coupon, error) {
+	coupons, err := s.repo.QueryCoupon(ctx, map[string]interface{}{}, "code asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying coupons: %v", err)
+		return nil, InternalError
+	}
+	return coupons, nil
+}
+
+func (s service) Evaluate(ctx context.Context, code string, items []entity.CartItem) (Discount, error) {
+	coupon, err := s.getCoupon(ctx, code)
+	if err != nil {
+		return Discount{}, err
+	}
+	return Evaluate(coupon, items, s.now())
+}
+
+func (s service) Redeem(ctx context.Context, code string) error {
+	coupon, err := s.getCoupon(ctx, code)
+	if err != nil {
+		return err
+	}
+	redeemed, err := s.repo.Redeem(ctx, coupon.ID)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error redeeming coupon %s: %v", coupon.Code, err)
+		return InternalError
+	}
+	if !redeemed {
+		return CouponUsedUpError
+	}
+	return nil
+}
+
+func (s service) getCoupon(ctx context.Context, code string) (entity.Coupon, error) {
+	coupons, err := s.repo.QueryCoupon(ctx, map[string]interface{}{"code": NormalizeCode(code)}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying coupon: %v", err)
+		return entity.Coupon{}, InternalError
+	}
+	if len(coupons) == 0 {
+		return entity.Coupon{}, CouponNotFoundError
+	}
+	return coupons[0], nil
+}
@@ -0,0 +1,100 @@
+package promotion
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) Service {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "coupons")
+	return NewService(NewRepository(dbc, logger), logger)
+}
+
+func TestService_CreateCoupon(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	coupon := entity.Coupon{Code: " spring-10 ", Kind: entity.CouponPercentage, Percent: 10, Active: true}
+	require.Nil(t, s.CreateCoupon(ctx, &coupon))
+	assert.Equal(t, "SPRING-10", coupon.Code)
+	assert.Equal(t, CouponCodeTakenError, s.CreateCoupon(ctx, &entity.Coupon{Code: "Spring-10", Kind: entity.CouponPercentage, Percent: 5}))
+
+	invalid := []entity.Coupon{
+		{Code: "", Kind: entity.CouponPercentage, Percent: 10},
+		{Code: "TWO WORDS", Kind: entity.CouponPercentage, Percent: 10},
+		{Code: "A", Kind: "free_shipping"},
+		{Code: "A", Kind: entity.CouponPercentage, Percent: 101},
+		{Code: "A", Kind: entity.CouponFixed},
+		{Code: "A", Kind: entity.CouponBuyXGetY, BuyQuantity: 2},
+		{Code: "A", Kind: entity.CouponPercentage, Percent: 10, ValidFrom: &now, ValidUntil: &now},
+		{Code: "A", Kind: entity.CouponPercentage, Percent: 10, MaxUses: -1},
+	}
+	for _, c := range invalid {
+		assert.ErrorIs(t, s.CreateCoupon(ctx, &c), InvalidCouponError, "%+v", c)
+	}
+
+	coupons, err := s.GetCoupons(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(coupons))
+}
+
+func TestService_EvaluateAndRedeem(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	coupon := entity.Coupon{Code: "ONCE", Kind: entity.CouponFixed, Amount: usd(500), MaxUses: 1, Active: true}
+	require.Nil(t, s.CreateCoupon(ctx, &coupon))
+	items := []entity.CartItem{{ProductID: 1, Quantity: 1, Price: usd(10000)}}
+
+	discount, err := s.Evaluate(ctx, "once", items)
+	require.Nil(t, err)
+	assert.Equal(t, usd(500), discount.Amount)
+	_, err = s.Evaluate(ctx, "twice", items)
+	assert.Equal(t, CouponNotFoundError, err)
+
+	assert.Nil(t, s.Redeem(ctx, "ONCE"))
+	assert.Equal(t, CouponUsedUpError, s.Redeem(ctx, "ONCE"))
+	_, err = s.Evaluate(ctx, "ONCE", items)
+	assert.Equal(t, CouponUsedUpError, err)
+}
+
+func TestService_ConcurrentRedeems(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	coupon := entity.Coupon{Code: "FIVE", Kind: entity.CouponPercentage, Percent: 10, MaxUses: 5, Active: true}
+	require.Nil(t, s.CreateCoupon(ctx, &coupon))
+
+	var wg sync.WaitGroup
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- s.Redeem(ctx, "FIVE")
+		}()
+	}
+	wg.Wait()
+	close(results)
+	redeemed := 0
+	for err := range results {
+		if err == nil {
+			redeemed++
+		} else {
+			assert.Equal(t, CouponUsedUpError, err)
+		}
+	}
+	assert.Equal(t, 5, redeemed)
+	coupons, err := s.GetCoupons(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, 5, coupons[0].Uses)
+}
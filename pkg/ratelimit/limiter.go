@@ -0,0 +1,58 @@
+// Package ratelimit limits how often clients may do something, with a token bucket per client kept in memory or
+// in Redis.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is the size of a token bucket and how fast it refills. Every request takes a token, and a client whose
+// bucket is empty has to wait for the next token.
+type Limit struct {
+	// Burst is the number of requests a client can make at once.
+	Burst int
+	// PerMinute is the number of tokens that are added to the bucket per minute, up to Burst.
+	PerMinute int
+}
+
+// interval returns the time it takes to add one token to the bucket.
+func (l Limit) interval() time.Duration {
+	return time.Minute / time.Duration(l.PerMinute)
+}
+
+// Result tells whether a request is allowed and, if it is not, when it will be.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is the time until the next token is added. It is zero for allowed requests.
+	RetryAfter time.Duration
+}
+
+// Limiter keeps the token buckets of the clients.
+type Limiter interface {
+	// Allow takes a token from the bucket with the given key, which starts full, and reports whether there was one.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	// Ping checks that the backend of the limiter is reachable.
+	Ping(ctx context.Context) error
+}
+
+// take refills a bucket that had the given number of tokens the given time ago and takes a token from it.
+// It returns the tokens left and the result.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	tokens = math.Min(float64(limit.Burst), tokens+float64(elapsed)/float64(limit.interval()))
+	if tokens < 1 {
+		retryAfter := time.Duration(math.Ceil((1 - tokens) * float64(limit.interval())))
+		return tokens, Result{RetryAfter: retryAfter}
+	}
+	tokens--
+	return tokens, Result{Allowed: true, Remaining: int(tokens)}
+}
+
+// fullAfter returns the time after which a bucket with the given number of tokens is full again, and can be
+// forgotten.
+func fullAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil((float64(limit.Burst) - tokens) * float64(limit.interval())))
+}
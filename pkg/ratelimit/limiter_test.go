@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTake(t *testing.T) {
+	limit := Limit{Burst: 3, PerMinute: 6}
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		left    float64
+		result  Result
+	}{
+		{"full bucket", 3, 0, 2, Result{Allowed: true, Remaining: 2}},
+		{"refill is capped at the burst", 3, time.Hour, 2, Result{Allowed: true, Remaining: 2}},
+		{"last token", 1, 0, 0, Result{Allowed: true}},
+		{"empty bucket", 0, 4 * time.Second, 0.4, Result{RetryAfter: 6 * time.Second}},
+		{"refilled token", 0, 10 * time.Second, 0, Result{Allowed: true}},
+		{"partly refilled token", 0.5, 5 * time.Second, 0, Result{Allowed: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, result := take(tt.tokens, tt.elapsed, limit)
+			assert.InDelta(t, tt.left, left, 1e-9)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	m := limiter.(*memoryLimiter)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	testLimiter(t, limiter, func(d time.Duration) {
+		now = now.Add(d)
+	})
+
+	// buckets that are full again are forgotten
+	now = now.Add(time.Hour)
+	_, _ = limiter.Allow(context.Background(), "other", Limit{Burst: 1, PerMinute: 60})
+	assert.Equal(t, []string{"other"}, keys(m.buckets))
+}
+
+func TestRedisLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	now := time.Now()
+	limiter := redisLimiter{client, func() time.Time { return now }}
+	testLimiter(t, limiter, func(d time.Duration) {
+		now = now.Add(d)
+		mr.FastForward(d)
+	})
+
+	// buckets that are full again expire
+	mr.FastForward(time.Hour)
+	assert.Empty(t, mr.Keys())
+
+	mr.Close()
+	assert.NotNil(t, limiter.Ping(context.Background()))
+}
+
+func testLimiter(t *testing.T, limiter Limiter, advance func(d time.Duration)) {
+	ctx := context.Background()
+	limit := Limit{Burst: 2, PerMinute: 30}
+
+	assert.Nil(t, limiter.Ping(ctx))
+
+	result, err := limiter.Allow(ctx, "a", limit)
+	assert.Nil(t, err)
+	assert.Equal(t, Result{Allowed: true, Remaining: 1}, result)
+	result, _ = limiter.Allow(ctx, "a", limit)
+	assert.Equal(t, Result{Allowed: true}, result)
+	result, _ = limiter.Allow(ctx, "a", limit)
+	assert.Equal(t, Result{RetryAfter: 2 * time.Second}, result)
+
+	// other keys have their own bucket
+	result, _ = limiter.Allow(ctx, "b", limit)
+	assert.True(t, result.Allowed)
+
+	advance(time.Second)
+	result, _ = limiter.Allow(ctx, "a", limit)
+	assert.Equal(t, Result{RetryAfter: time.Second}, result)
+	advance(time.Second)
+	result, _ = limiter.Allow(ctx, "a", limit)
+	assert.Equal(t, Result{Allowed: true}, result)
+}
+
+func keys(buckets map[string]memoryBucket) []string {
+	var keys []string
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	return keys
+}
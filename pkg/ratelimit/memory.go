@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory limiter forgets the buckets that are full again.
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+// NewMemoryLimiter returns a Limiter that keeps the buckets in the memory of the current process.
+// It is meant for development and single-instance deployments.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		buckets: map[string]memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+func (m *memoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+	}
+	tokens, result := take(bucket.tokens, now.Sub(bucket.updatedAt), limit)
+	m.buckets[key] = memoryBucket{tokens: tokens, updatedAt: now, fullAt: now.Add(fullAfter(tokens, limit))}
+	return result, nil
+}
+
+// sweep removes the buckets that are full again, so that the clients seen once do not take memory forever.
+func (m *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < sweepInterval {
+		return
+	}
+	m.sweptAt = now
+	for key, bucket := range m.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func (m *memoryLimiter) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// takeScript refills the bucket stored in a hash and takes a token from it atomically, like take. The bucket
+// expires when it is full again. It returns whether the token was taken and the tokens left, as a string since
+// Redis truncates numbers returned by scripts.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated_at')
+local tokens = tonumber(bucket[1]) or burst
+local updated_at = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated_at) / interval)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated_at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * interval) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+type redisLimiter struct {
+	client redis.UniversalClient
+	now    func() time.Time
+}
+
+// NewRedisLimiter returns a Limiter that keeps the buckets in Redis, so that they are shared between instances.
+// Expiry is delegated to Redis so that full buckets are removed without a sweeper.
+func NewRedisLimiter(client redis.UniversalClient) Limiter {
+	return redisLimiter{client, time.Now}
+}
+
+func (r redisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	interval := float64(limit.interval()) / float64(time.Millisecond)
+	now := r.now().UnixMilli()
+	reply, err := takeScript.Run(ctx, r.client, []string{redisKeyPrefix + key}, limit.Burst, interval, now).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	allowed, _ := reply[0].(int64)
+	left, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	if allowed == 1 {
+		return Result{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	_, result := take(tokens, 0, limit)
+	return result, nil
+}
+
+func (r redisLimiter) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	session   Session
+	expiresAt time.Time
+}
+
+// sweepInterval is how often the memory store deletes the expired sessions.
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+	sweptAt  time.Time
+	now      func() time.Time
+}
+
+// NewMemoryStore returns a Store that keeps sessions in the memory of the current process.
+// It is meant for development and single-instance deployments.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		sessions: map[string]memoryEntry{},
+		now:      time.Now,
+	}
+}
+
+func (m *memoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.sessions[id]
+	if !ok {
+		return nil, NotFoundError
+	}
+	if !m.now().Before(entry.expiresAt) {
+		delete(m.sessions, id)
+		return nil, NotFoundError
+	}
+	s := entry.session
+	s.Flashes = append([]string(nil), entry.session.Flashes...)
+	return &s, nil
+}
+
+func (m *memoryStore) Save(ctx context.Context, s *Session, lifetime time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	m.save(s, lifetime)
+	return nil
+}
+
+func (m *memoryStore) Update(ctx context.Context, s *Session, lifetime time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.sessions[s.ID]
+	if !ok || !m.now().Before(entry.expiresAt) {
+		delete(m.sessions, s.ID)
+		return NotFoundError
+	}
+	m.save(s, lifetime)
+	return nil
+}
+
+// sweep deletes the expired sessions, so that the sessions of clients seen once, e.g. bots that drop the cookie, do
+// not take memory forever. The caller must hold the lock.
+func (m *memoryStore) sweep() {
+	now := m.now()
+	if now.Sub(m.sweptAt) < sweepInterval {
+		return
+	}
+	m.sweptAt = now
+	for id, entry := range m.sessions {
+		if !now.Before(entry.expiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// save stores a copy of the session. The caller must hold the lock.
+func (m *memoryStore) save(s *Session, lifetime time.Duration) {
+	stored := *s
+	stored.Flashes = append([]string(nil), s.Flashes...)
+	m.sessions[s.ID] = memoryEntry{
+		session:   stored,
+		expiresAt: m.now().Add(lifetime),
+	}
+}
+
+func (m *memoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *memoryStore) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "session:"
+
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore returns a Store that keeps sessions in Redis.
+// Expiry is delegated to Redis so that expired sessions are removed without a sweeper.
+func NewRedisStore(client redis.UniversalClient) Store {
+	return redisStore{client}
+}
+
+func (r redisStore) Get(ctx context.Context, id string) (*Session, error) {
+	data, err := r.client.Get(ctx, redisKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r redisStore) Save(ctx context.Context, s *Session, lifetime time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, redisKeyPrefix+s.ID, data, lifetime).Err()
+}
+
+func (r redisStore) Update(ctx context.Context, s *Session, lifetime time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// SET XX only replaces an existing key, so that a session deleted in the meantime is not stored again
+	updated, err := r.client.SetXX(ctx, redisKeyPrefix+s.ID, data, lifetime).Result()
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return NotFoundError
+	}
+	return nil
+}
+
+func (r redisStore) Delete(ctx context.Context, id string) error {
+	return r.client.Del(ctx, redisKeyPrefix+id).Err()
+}
+
+func (r redisStore) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
@@ -0,0 +1,115 @@
+// Package session provides server-side HTTP sessions and the stores that persist them.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotFoundError is returned by a Store when a session does not exist or has expired.
+var NotFoundError = errors.New("session not found")
+
+// Session holds the server-side state associated with a session cookie.
+type Session struct {
+	ID string `json:"id"`
+	// CartID is the open cart of the session, which is looked up by its ID. It may refer to a cart that was
+	// closed or merged since, in which case the cart is searched for again.
+	CartID  uint     `json:"cart_id,omitempty"`
+	UserID  uint     `json:"user_id,omitempty"`
+	Flashes []string `json:"flashes,omitempty"`
+	CSRF    string   `json:"csrf,omitempty"`
+}
+
+// CSRFField and CSRFHeader are the form field and the request header that carry the CSRF token of the session
+// with state-changing requests.
+const (
+	CSRFField  = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// New creates a session with a new random ID.
+func New() *Session {
+	return &Session{ID: uuid.New().String()}
+}
+
+// Renew gives the session a new random ID and a new CSRF token. It is called when the user of the session changes,
+// e.g. on login, so that an ID or token obtained before cannot be used to take over the session.
+func (s *Session) Renew() {
+	s.ID = uuid.New().String()
+	s.CSRF = ""
+}
+
+// CSRFToken returns the token that state-changing requests of the session must carry, so that other sites cannot
+// make them on behalf of the user. The token is created with the first call and kept until the session is renewed.
+func (s *Session) CSRFToken() string {
+	if s.CSRF == "" {
+		token := make([]byte, 32)
+		if _, err := rand.Read(token); err != nil {
+			panic(err)
+		}
+		s.CSRF = base64.RawURLEncoding.EncodeToString(token)
+	}
+	return s.CSRF
+}
+
+// AddFlash queues a message to be shown on the next rendered page.
+func (s *Session) AddFlash(msg string) {
+	s.Flashes = append(s.Flashes, msg)
+}
+
+// PopFlashes returns the queued flash messages and removes them from the session.
+func (s *Session) PopFlashes() []string {
+	flashes := s.Flashes
+	s.Flashes = nil
+	return flashes
+}
+
+// Store persists sessions between requests.
+type Store interface {
+	// Get returns the session with the given ID or NotFoundError if it does not exist or has expired.
+	Get(ctx context.Context, id string) (*Session, error)
+	// Save stores the session so that it expires after the given lifetime.
+	Save(ctx context.Context, s *Session, lifetime time.Duration) error
+	// Update stores the session like Save, but only if a session with its ID is still stored. It returns
+	// NotFoundError otherwise, e.g. because the session was renewed or deleted by another request.
+	Update(ctx context.Context, s *Session, lifetime time.Duration) error
+	// Delete removes the session with the given ID.
+	Delete(ctx context.Context, id string) error
+	// Ping checks that the store is reachable.
+	Ping(ctx context.Context) error
+}
+
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+	newSessionKey
+)
+
+// WithSession returns a context which carries the given session.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
+
+// FromContext returns the session carried by the context, or nil if there is none.
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey).(*Session)
+	return s
+}
+
+// WithNewSession returns a context which carries the given session, created for the request because the client
+// sent the cookie of no stored session.
+func WithNewSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(WithSession(ctx, s), newSessionKey, true)
+}
+
+// IsNew reports whether the session carried by the context was created for the request.
+func IsNew(ctx context.Context) bool {
+	isNew, _ := ctx.Value(newSessionKey).(bool)
+	return isNew
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	testStore(t, store, func(d time.Duration) {
+		m := store.(*memoryStore)
+		now := m.now().Add(d)
+		m.now = func() time.Time { return now }
+	})
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := NewMemoryStore()
+	m := store.(*memoryStore)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+	first, second := New(), New()
+	assert.Nil(t, store.Save(ctx, first, time.Minute))
+
+	// expired sessions are deleted even if they are never looked up again
+	now = now.Add(2 * time.Minute)
+	assert.Nil(t, store.Save(ctx, second, time.Minute))
+	assert.Len(t, m.sessions, 1)
+	assert.Contains(t, m.sessions, second.ID)
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	testStore(t, NewRedisStore(client), mr.FastForward)
+
+	mr.Close()
+	assert.NotNil(t, NewRedisStore(client).Ping(context.Background()))
+}
+
+func testStore(t *testing.T, store Store, advance func(d time.Duration)) {
+	ctx := context.Background()
+
+	assert.Nil(t, store.Ping(ctx))
+
+	_, err := store.Get(ctx, "missing")
+	assert.Equal(t, NotFoundError, err)
+
+	s := New()
+	s.CartID = 3
+	s.UserID = 7
+	s.AddFlash("hello")
+	assert.Nil(t, store.Save(ctx, s, time.Minute))
+
+	got, err := store.Get(ctx, s.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, s, got)
+	assert.Equal(t, []string{"hello"}, got.PopFlashes())
+	assert.Empty(t, got.Flashes)
+
+	// mutating a loaded session does not affect the store until it is saved
+	got, _ = store.Get(ctx, s.ID)
+	assert.Equal(t, []string{"hello"}, got.Flashes)
+
+	s.CartID = 4
+	assert.Nil(t, store.Update(ctx, s, time.Minute))
+	got, _ = store.Get(ctx, s.ID)
+	assert.Equal(t, uint(4), got.CartID)
+
+	assert.Nil(t, store.Delete(ctx, s.ID))
+	_, err = store.Get(ctx, s.ID)
+	assert.Equal(t, NotFoundError, err)
+
+	// a deleted session is not stored again by an update
+	assert.Equal(t, NotFoundError, store.Update(ctx, s, time.Minute))
+	_, err = store.Get(ctx, s.ID)
+	assert.Equal(t, NotFoundError, err)
+
+	assert.Nil(t, store.Save(ctx, s, time.Minute))
+	advance(2 * time.Minute)
+	_, err = store.Get(ctx, s.ID)
+	assert.Equal(t, NotFoundError, err)
+}
+
+func TestContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+	s := New()
+	ctx := WithSession(context.Background(), s)
+	assert.Equal(t, s, FromContext(ctx))
+	assert.False(t, IsNew(ctx))
+	ctx = WithNewSession(context.Background(), s)
+	assert.Equal(t, s, FromContext(ctx))
+	assert.True(t, IsNew(ctx))
+}
+
+func TestSession_Renew(t *testing.T) {
+	s := New()
+	id, token := s.ID, s.CSRFToken()
+	s.Renew()
+	assert.NotEqual(t, id, s.ID)
+	assert.NotEqual(t, token, s.CSRFToken())
+}
@@ -0,0 +1,71 @@
+package shipping
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+// Repository stores shipping zones and rates.
+type Repository interface {
+	QueryZone(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.ShippingZone, error)
+	CreateZone(ctx context.Context, zone *entity.ShippingZone) error
+	QueryRate(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.ShippingRate, error)
+	CreateRate(ctx context.Context, rate *entity.ShippingRate) error
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryZone(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.ShippingZone, error) {
+	var zones []entity.ShippingZone
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&zones)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return zones, nil
+}
+
+func (r repository) CreateZone(ctx context.Context, zone *entity.ShippingZone) error {
+	db := r.db.With(ctx)
+	result := db.Create(zone)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) QueryRate(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.ShippingRate, error) {
+	var rates []entity.ShippingRate
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&rates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return rates, nil
+}
+
+func (r repository) CreateRate(ctx context.Context, rate *entity.ShippingRate) error {
+	db := r.db.With(ctx)
+	result := db.Create(rate)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package shipping
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"interview/pkg/entity"
+	"interview/pkg/money"
+)
+
+var UnknownDestinationError = errors.New("we do not ship to this country")
+var NotShippableError = errors.New("no shipping method can take the items to this country")
+var UnknownMethodError = errors.New("the shipping method is not available for the cart")
+
+// VolumetricDivisor turns the volume of a parcel in cubic millimetres into the weight in grams that carriers charge
+// for bulky parcels: 5000 cm³ count as 1 kg.
+const VolumetricDivisor = 5000
+
+// Item is a cart item as far as shipping is concerned: the quantity and the weight and dimensions of one unit.
+type Item struct {
+	Quantity int
+	// Weight is in grams and the dimensions in millimetres.
+	Weight int
+	Length int
+	Width  int
+	Height int
+}
+
+// ShippingQuote is the price of shipping a cart with one method.
+type ShippingQuote struct {
+	Zone   string
+	Method string
+	Name   string
+	// Weight is the chargeable weight of the parcel in grams.
+	Weight int
+	Price  money.Money
+	// Free tells that the price is waived because the cart reached the threshold of the rate.
+	Free bool
+}
+
+// NormalizeCountry returns the form in which country codes are stored: trimmed and upper case.
+func NormalizeCountry(country string) string {
+	return strings.ToUpper(strings.TrimSpace(country))
+}
+
+// ParcelWeight returns the chargeable weight of the items in grams: each unit counts with its weight or its
+// volumetric weight, whichever is larger.
+func ParcelWeight(items []Item) int {
+	weight := 0
+	for _, item := range items {
+		volumetric := item.Length * item.Width * item.Height / VolumetricDivisor
+		weight += item.Quantity * max(item.Weight, volumetric)
+	}
+	return weight
+}
+
+// ZoneFor returns the zone of the country: the zone that lists it, or else the zone of all other countries.
+func ZoneFor(zones []entity.ShippingZone, country string) (entity.ShippingZone, bool) {
+	var others *entity.ShippingZone
+	for i, zone := range zones {
+		for _, c := range strings.Split(zone.Countries, ",") {
+			if c == country {
+				return zone, true
+			}
+			if c == "*" {
+				others = &zones[i]
+			}
+		}
+	}
+	if others != nil {
+		return *others, true
+	}
+	return entity.ShippingZone{}, false
+}
+
+// Quote returns the price of every shipping method of the zone for a parcel of the given weight and a cart with
+// the given subtotal, cheapest first. A weight-tiered method whose tiers are all too light for the parcel is left
+// out, and so are rates in another currency than the subtotal.
+func Quote(zone entity.ShippingZone, rates []entity.ShippingRate, weight int, subtotal money.Money) []ShippingQuote {
+	byMethod := map[string][]entity.ShippingRate{}
+	var methods []string
+	for _, rate := range rates {
+		if rate.Price.Currency != subtotal.Currency {
+			continue
+		}
+		if _, ok := byMethod[rate.Method]; !ok {
+			methods = append(methods, rate.Method)
+		}
+		byMethod[rate.Method] = append(byMethod[rate.Method], rate)
+	}
+
+	var quotes []ShippingQuote
+	for _, method := range methods {
+		rate, ok := rateFor(byMethod[method], weight)
+		if !ok {
+			continue
+		}
+		quote := ShippingQuote{Zone: zone.Name, Method: rate.Method, Name: rate.Name, Weight: weight, Price: rate.Price}
+		if rate.Kind == entity.ShippingFreeOver && subtotal.Currency == rate.Threshold.Currency &&
+			subtotal.Amount >= rate.Threshold.Amount {
+			quote.Price = money.Money{Currency: rate.Price.Currency}
+			quote.Free = true
+		}
+		quotes = append(quotes, quote)
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if quotes[i].Price.Amount != quotes[j].Price.Amount {
+			return quotes[i].Price.Amount < quotes[j].Price.Amount
+		}
+		return quotes[i].Method < quotes[j].Method
+	})
+	return quotes
+}
+
+// rateFor returns the rate of a method that applies to the weight: the lightest tier the parcel fits in for
+// weight-tiered methods, and the only rate of the others.
+func rateFor(rates []entity.ShippingRate, weight int) (entity.ShippingRate, bool) {
+	if rates[0].Kind != entity.ShippingWeight {
+		return rates[0], true
+	}
+	var best *entity.ShippingRate
+	for i, rate := range rates {
+		if weight <= rate.MaxWeight && (best == nil || rate.MaxWeight < best.MaxWeight) {
+			best = &rates[i]
+		}
+	}
+	if best == nil {
+		return entity.ShippingRate{}, false
+	}
+	return *best, true
+}
@@ -0,0 +1,95 @@
+package shipping
+
+import (
+	"interview/pkg/entity"
+	"interview/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(cents int64) money.Money {
+	return money.New(cents, "USD")
+}
+
+func TestParcelWeight(t *testing.T) {
+	items := []Item{
+		// a 300 x 200 x 100 mm box weighs 1200 g by volume
+		{Quantity: 2, Weight: 500, Length: 300, Width: 200, Height: 100},
+		{Quantity: 1, Weight: 2000, Length: 100, Width: 100, Height: 100},
+		{Quantity: 3},
+	}
+	assert.Equal(t, 2*1200+2000, ParcelWeight(items))
+}
+
+func TestZoneFor(t *testing.T) {
+	zones := []entity.ShippingZone{
+		{Name: "world", Countries: "*"},
+		{Name: "eu", Countries: "AT,DE,FR"},
+	}
+	zone, ok := ZoneFor(zones, "DE")
+	assert.True(t, ok)
+	assert.Equal(t, "eu", zone.Name)
+	zone, ok = ZoneFor(zones, "US")
+	assert.True(t, ok)
+	assert.Equal(t, "world", zone.Name)
+	_, ok = ZoneFor(zones[1:], "US")
+	assert.False(t, ok)
+}
+
+func TestQuote(t *testing.T) {
+	zone := entity.ShippingZone{Name: "eu"}
+	rates := []entity.ShippingRate{
+		{Method: "express", Name: "Express", Kind: entity.ShippingFlat, Price: usd(2500)},
+		{Method: "parcel", Name: "Parcel", Kind: entity.ShippingWeight, Price: usd(1500), MaxWeight: 5000},
+		{Method: "parcel", Name: "Parcel", Kind: entity.ShippingWeight, Price: usd(900), MaxWeight: 1000},
+		{Method: "standard", Name: "Standard", Kind: entity.ShippingFreeOver, Price: usd(1200), Threshold: usd(10000)},
+	}
+	tests := []struct {
+		name     string
+		weight   int
+		subtotal money.Money
+		quotes   []ShippingQuote
+	}{
+		{
+			name:     "light parcel below the threshold",
+			weight:   800,
+			subtotal: usd(9999),
+			quotes: []ShippingQuote{
+				{Zone: "eu", Method: "parcel", Name: "Parcel", Weight: 800, Price: usd(900)},
+				{Zone: "eu", Method: "standard", Name: "Standard", Weight: 800, Price: usd(1200)},
+				{Zone: "eu", Method: "express", Name: "Express", Weight: 800, Price: usd(2500)},
+			},
+		},
+		{
+			name:     "heavier parcel at the threshold",
+			weight:   1001,
+			subtotal: usd(10000),
+			quotes: []ShippingQuote{
+				{Zone: "eu", Method: "standard", Name: "Standard", Weight: 1001, Price: usd(0), Free: true},
+				{Zone: "eu", Method: "parcel", Name: "Parcel", Weight: 1001, Price: usd(1500)},
+				{Zone: "eu", Method: "express", Name: "Express", Weight: 1001, Price: usd(2500)},
+			},
+		},
+		{
+			name:     "too heavy for the weight tiers",
+			weight:   5001,
+			subtotal: usd(100),
+			quotes: []ShippingQuote{
+				{Zone: "eu", Method: "standard", Name: "Standard", Weight: 5001, Price: usd(1200)},
+				{Zone: "eu", Method: "express", Name: "Express", Weight: 5001, Price: usd(2500)},
+			},
+		},
+		{
+			name:     "cart in another currency",
+			weight:   800,
+			subtotal: money.New(10000, "EUR"),
+			quotes:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.quotes, Quote(zone, rates, tt.weight, tt.subtotal))
+		})
+	}
+}
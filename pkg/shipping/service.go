@@ -0,0 +1,201 @@
+// Package shipping keeps the zones the shop ships to and their rates, and quotes the shipping of carts.
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/money"
+)
+
+// Service manages shipping zones and rates and quotes the shipping of carts.
+type Service interface {
+	// Quotes returns the price of every shipping method that can take the items to the country, cheapest first.
+	// The subtotal is the discounted amount of the items, which free shipping thresholds apply to.
+	Quotes(ctx context.Context, country string, items []Item, subtotal money.Money) ([]ShippingQuote, error)
+	// Required reports whether orders need a shipping method, which they do once a zone has been set up.
+	Required(ctx context.Context) (bool, error)
+	// CreateZone checks a new zone and stores it with normalized country codes.
+	CreateZone(ctx context.Context, zone *entity.ShippingZone) error
+	// GetZones returns all zones ordered by name.
+	GetZones(ctx context.Context) ([]entity.ShippingZone, error)
+	// CreateRate checks a new rate against the other rates of its method and stores it.
+	CreateRate(ctx context.Context, rate *entity.ShippingRate) error
+	// GetRates returns all rates ordered by zone, method and weight.
+	GetRates(ctx context.Context) ([]entity.ShippingRate, error)
+}
+
+type service struct {
+	repo   Repository
+	logger log.Logger
+}
+
+var InvalidZoneError = errors.New("invalid shipping zone")
+var InvalidRateError = errors.New("invalid shipping rate")
+var InternalError = errors.New("internal error")
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+var countryRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func NewService(repo Repository, logger log.Logger) Service {
+	return service{repo, logger}
+}
+
+func (s service) Quotes(ctx context.Context, country string, items []Item, subtotal money.Money) ([]ShippingQuote, error) {
+	country = NormalizeCountry(country)
+	if !countryRegex.MatchString(country) {
+		return nil, UnknownDestinationError
+	}
+	zones, err := s.GetZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zone, ok := ZoneFor(zones, country)
+	if !ok {
+		return nil, UnknownDestinationError
+	}
+	rates, err := s.repo.QueryRate(ctx, map[string]interface{}{"zone_id": zone.ID}, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying shipping rates: %v", err)
+		return nil, InternalError
+	}
+	quotes := Quote(zone, rates, ParcelWeight(items), subtotal)
+	if len(quotes) == 0 {
+		return nil, NotShippableError
+	}
+	return quotes, nil
+}
+
+func (s service) Required(ctx context.Context) (bool, error) {
+	zones, err := s.repo.QueryZone(ctx, map[string]interface{}{}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying shipping zones: %v", err)
+		return false, InternalError
+	}
+	return len(zones) > 0, nil
+}
+
+func (s service) CreateZone(ctx context.Context, zone *entity.ShippingZone) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", InvalidZoneError, fmt.Sprintf(format, args...))
+	}
+	zone.Name = strings.ToLower(strings.TrimSpace(zone.Name))
+	if !nameRegex.MatchString(zone.Name) {
+		return invalid("the name must be 1 to 32 letters, digits, dashes or underscores")
+	}
+	var countries []string
+	for _, country := range strings.Split(zone.Countries, ",") {
+		country = NormalizeCountry(country)
+		if country != "*" && !countryRegex.MatchString(country) {
+			return invalid("%q is not a two-letter country code or *", country)
+		}
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+
+	zones, err := s.GetZones(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range zones {
+		if other.Name == zone.Name {
+			return invalid("a zone named %s already exists", zone.Name)
+		}
+		for _, country := range strings.Split(other.Countries, ",") {
+			for _, c := range countries {
+				if c == country {
+					return invalid("%s is already in zone %s", c, other.Name)
+				}
+			}
+		}
+	}
+	zone.Countries = strings.Join(countries, ",")
+	if len(zone.Countries) > 255 {
+		return invalid("too many countries")
+	}
+	if err := s.repo.CreateZone(ctx, zone); err != nil {
+		s.logger.With(ctx).Errorf("error creating shipping zone: %v", err)
+		return InternalError
+	}
+	return nil
+}
+
+func (s service) GetZones(ctx context.Context) ([]entity.ShippingZone, error) {
+	zones, err := s.repo.QueryZone(ctx, map[string]interface{}{}, "name asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying shipping zones: %v", err)
+		return nil, InternalError
+	}
+	return zones, nil
+}
+
+func (s service) CreateRate(ctx context.Context, rate *entity.ShippingRate) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", InvalidRateError, fmt.Sprintf(format, args...))
+	}
+	rate.Method = strings.ToLower(strings.TrimSpace(rate.Method))
+	if !nameRegex.MatchString(rate.Method) {
+		return invalid("the method must be 1 to 32 letters, digits, dashes or underscores")
+	}
+	if rate.Name = strings.TrimSpace(rate.Name); rate.Name == "" {
+		rate.Name = rate.Method
+	}
+	if rate.Price.Amount < 0 || rate.Price.Currency == "" {
+		return invalid("the price cannot be negative")
+	}
+	switch rate.Kind {
+	case entity.ShippingFlat:
+	case entity.ShippingWeight:
+		if rate.MaxWeight <= 0 {
+			return invalid("a weight tier needs a maximum weight")
+		}
+	case entity.ShippingFreeOver:
+		if rate.Threshold.Amount <= 0 || rate.Threshold.Currency != rate.Price.Currency {
+			return invalid("the threshold must be positive and in the currency of the price")
+		}
+	default:
+		return invalid("unknown kind %q", rate.Kind)
+	}
+
+	zones, err := s.repo.QueryZone(ctx, map[string]interface{}{"id": rate.ZoneID}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying shipping zone: %v", err)
+		return InternalError
+	}
+	if len(zones) == 0 {
+		return invalid("unknown zone")
+	}
+	others, err := s.repo.QueryRate(ctx, map[string]interface{}{"zone_id": rate.ZoneID, "method": rate.Method}, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying shipping rates: %v", err)
+		return InternalError
+	}
+	for _, other := range others {
+		if rate.Kind != entity.ShippingWeight || other.Kind != entity.ShippingWeight {
+			return invalid("method %s already has a rate in zone %s; only weight tiers can be added", rate.Method, zones[0].Name)
+		}
+		if other.MaxWeight == rate.MaxWeight {
+			return invalid("method %s already has a tier up to %d g", rate.Method, rate.MaxWeight)
+		}
+	}
+	if err := s.repo.CreateRate(ctx, rate); err != nil {
+		s.logger.With(ctx).Errorf("error creating shipping rate: %v", err)
+		return InternalError
+	}
+	return nil
+}
+
+func (s service) GetRates(ctx context.Context) ([]entity.ShippingRate, error) {
+	rates, err := s.repo.QueryRate(ctx, map[string]interface{}{}, "zone_id asc, method asc, max_weight asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying shipping rates: %v", err)
+		return nil, InternalError
+	}
+	return rates, nil
+}
@@ -0,0 +1,104 @@
+package shipping
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) Service {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "shipping_rates", "shipping_zones")
+	return NewService(NewRepository(dbc, logger), logger)
+}
+
+func TestService_CreateZone(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	required, err := s.Required(ctx)
+	require.Nil(t, err)
+	assert.False(t, required)
+
+	zone := entity.ShippingZone{Name: " EU ", Countries: "fr, de ,at"}
+	require.Nil(t, s.CreateZone(ctx, &zone))
+	assert.Equal(t, "eu", zone.Name)
+	assert.Equal(t, "AT,DE,FR", zone.Countries)
+	require.Nil(t, s.CreateZone(ctx, &entity.ShippingZone{Name: "world", Countries: "*"}))
+
+	invalid := []entity.ShippingZone{
+		{Name: "eu", Countries: "IT"},
+		{Name: "dach", Countries: "CH,DE"},
+		{Name: "rest", Countries: "*"},
+		{Name: "us", Countries: "USA"},
+		{Name: "two words", Countries: "US"},
+	}
+	for _, z := range invalid {
+		assert.ErrorIs(t, s.CreateZone(ctx, &z), InvalidZoneError, "%+v", z)
+	}
+
+	zones, err := s.GetZones(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(zones))
+	required, err = s.Required(ctx)
+	require.Nil(t, err)
+	assert.True(t, required)
+}
+
+func TestService_CreateRate(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	zone := entity.ShippingZone{Name: "eu", Countries: "DE"}
+	require.Nil(t, s.CreateZone(ctx, &zone))
+
+	require.Nil(t, s.CreateRate(ctx, &entity.ShippingRate{ZoneID: zone.ID, Method: "Parcel", Kind: entity.ShippingWeight, Price: usd(900), MaxWeight: 1000}))
+	require.Nil(t, s.CreateRate(ctx, &entity.ShippingRate{ZoneID: zone.ID, Method: "parcel", Kind: entity.ShippingWeight, Price: usd(1500), MaxWeight: 5000}))
+	require.Nil(t, s.CreateRate(ctx, &entity.ShippingRate{ZoneID: zone.ID, Method: "standard", Name: "Standard", Kind: entity.ShippingFreeOver, Price: usd(1200), Threshold: usd(10000)}))
+
+	invalid := []entity.ShippingRate{
+		{ZoneID: zone.ID + 1, Method: "express", Kind: entity.ShippingFlat, Price: usd(2500)},
+		{ZoneID: zone.ID, Method: "parcel", Kind: entity.ShippingWeight, Price: usd(1000), MaxWeight: 1000},
+		{ZoneID: zone.ID, Method: "parcel", Kind: entity.ShippingFlat, Price: usd(1000)},
+		{ZoneID: zone.ID, Method: "standard", Kind: entity.ShippingFlat, Price: usd(1000)},
+		{ZoneID: zone.ID, Method: "express", Kind: entity.ShippingWeight, Price: usd(1000)},
+		{ZoneID: zone.ID, Method: "express", Kind: entity.ShippingFreeOver, Price: usd(1000)},
+		{ZoneID: zone.ID, Method: "express", Kind: entity.ShippingFlat, Price: usd(-1)},
+		{ZoneID: zone.ID, Method: "express", Kind: "pigeon", Price: usd(1000)},
+	}
+	for _, r := range invalid {
+		assert.ErrorIs(t, s.CreateRate(ctx, &r), InvalidRateError, "%+v", r)
+	}
+
+	rates, err := s.GetRates(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(rates))
+	assert.Equal(t, "parcel", rates[0].Method)
+	assert.Equal(t, "parcel", rates[0].Name)
+	assert.Equal(t, 1000, rates[0].MaxWeight)
+}
+
+func TestService_Quotes(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	zone := entity.ShippingZone{Name: "eu", Countries: "DE"}
+	require.Nil(t, s.CreateZone(ctx, &zone))
+	require.Nil(t, s.CreateRate(ctx, &entity.ShippingRate{ZoneID: zone.ID, Method: "parcel", Kind: entity.ShippingWeight, Price: usd(900), MaxWeight: 1000}))
+	items := []Item{{Quantity: 2, Weight: 400}}
+
+	quotes, err := s.Quotes(ctx, "de", items, usd(5000))
+	require.Nil(t, err)
+	assert.Equal(t, []ShippingQuote{{Zone: "eu", Method: "parcel", Name: "parcel", Weight: 800, Price: usd(900)}}, quotes)
+
+	_, err = s.Quotes(ctx, "DE", []Item{{Quantity: 3, Weight: 400}}, usd(5000))
+	assert.Equal(t, NotShippableError, err)
+	_, err = s.Quotes(ctx, "US", items, usd(5000))
+	assert.Equal(t, UnknownDestinationError, err)
+	_, err = s.Quotes(ctx, "Germany", items, usd(5000))
+	assert.Equal(t, UnknownDestinationError, err)
+}
@@ -0,0 +1,56 @@
+package tax
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+// Repository stores tax rates.
+type Repository interface {
+	QueryTaxRate(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.TaxRate, error)
+	CreateTaxRate(ctx context.Context, rate *entity.TaxRate) error
+	UpdateTaxRate(ctx context.Context, rate *entity.TaxRate) error
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryTaxRate(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.TaxRate, error) {
+	var rates []entity.TaxRate
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&rates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return rates, nil
+}
+
+func (r repository) CreateTaxRate(ctx context.Context, rate *entity.TaxRate) error {
+	db := r.db.With(ctx)
+	result := db.Create(rate)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) UpdateTaxRate(ctx context.Context, rate *entity.TaxRate) error {
+	db := r.db.With(ctx)
+	result := db.Save(rate)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
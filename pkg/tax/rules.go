@@ -0,0 +1,152 @@
+package tax
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"interview/pkg/entity"
+	"interview/pkg/money"
+)
+
+// Rounding tells at which point the tax amounts are rounded to the minor unit of the currency.
+type Rounding string
+
+const (
+	// RoundPerLine rounds the tax of every cart line, and the tax lines are the sums of the rounded amounts.
+	RoundPerLine Rounding = "line"
+	// RoundPerTotal rounds the tax once per tax line, on the total of its cart lines.
+	RoundPerTotal Rounding = "total"
+)
+
+// Settings are the tax rules of the shop.
+type Settings struct {
+	// Region is the region of carts for which the customer has not chosen one.
+	Region string
+	// Inclusive tells whether product prices include tax.
+	Inclusive bool
+	Rounding  Rounding
+}
+
+// Line is an amount charged for products of a tax category, e.g. a cart item less its share of the discount.
+type Line struct {
+	Category string
+	Amount   money.Money
+}
+
+// TaxLine is the tax charged in one tax category.
+type TaxLine struct {
+	Name     string
+	Category string
+	// Rate is in basis points.
+	Rate int
+	// Taxable is the net amount the rate applies to.
+	Taxable money.Money
+	Tax     money.Money
+}
+
+// Percent returns the rate as a percentage, e.g. "8.25".
+func (l TaxLine) Percent() string {
+	return FormatRate(l.Rate)
+}
+
+// Breakdown splits the amount charged for the lines into net and tax.
+type Breakdown struct {
+	Region    string
+	Inclusive bool
+	Net       money.Money
+	Tax       money.Money
+	Gross     money.Money
+	Lines     []TaxLine
+}
+
+// Calculate returns the tax on the lines at the rates of a region. Lines of a category without a rate are not
+// taxed, and lines without a category are in entity.DefaultTaxCategory. Tax is rounded half up, and the tax lines
+// are ordered by category.
+func Calculate(lines []Line, rates []entity.TaxRate, settings Settings) Breakdown {
+	breakdown := Breakdown{Region: settings.Region, Inclusive: settings.Inclusive}
+	byCategory := map[string]entity.TaxRate{}
+	for _, rate := range rates {
+		byCategory[rate.Category] = rate
+	}
+
+	taxLines := map[string]*TaxLine{}
+	for _, line := range lines {
+		if settings.Inclusive {
+			breakdown.Gross = breakdown.Gross.Add(line.Amount)
+		} else {
+			breakdown.Net = breakdown.Net.Add(line.Amount)
+		}
+		category := line.Category
+		if category == "" {
+			category = entity.DefaultTaxCategory
+		}
+		rate, ok := byCategory[category]
+		if !ok || rate.Rate == 0 {
+			if settings.Inclusive {
+				breakdown.Net = breakdown.Net.Add(line.Amount)
+			} else {
+				breakdown.Gross = breakdown.Gross.Add(line.Amount)
+			}
+			continue
+		}
+		taxLine, ok := taxLines[category]
+		if !ok {
+			taxLine = &TaxLine{Name: rate.Name, Category: category, Rate: rate.Rate}
+			taxLines[category] = taxLine
+		}
+		// Taxable holds the amounts as charged until the tax is known.
+		taxLine.Taxable = taxLine.Taxable.Add(line.Amount)
+		if settings.Rounding != RoundPerTotal {
+			taxLine.Tax = taxLine.Tax.Add(tax(line.Amount, rate.Rate, settings.Inclusive))
+		}
+	}
+
+	for _, taxLine := range taxLines {
+		if settings.Rounding == RoundPerTotal {
+			taxLine.Tax = tax(taxLine.Taxable, taxLine.Rate, settings.Inclusive)
+		}
+		if settings.Inclusive {
+			taxLine.Taxable = taxLine.Taxable.Sub(taxLine.Tax)
+			breakdown.Net = breakdown.Net.Add(taxLine.Taxable)
+		} else {
+			breakdown.Gross = breakdown.Gross.Add(taxLine.Taxable).Add(taxLine.Tax)
+		}
+		breakdown.Tax = breakdown.Tax.Add(taxLine.Tax)
+		breakdown.Lines = append(breakdown.Lines, *taxLine)
+	}
+	sort.Slice(breakdown.Lines, func(i, j int) bool { return breakdown.Lines[i].Category < breakdown.Lines[j].Category })
+	if breakdown.Tax.Currency == "" {
+		breakdown.Tax.Currency = breakdown.Gross.Currency
+	}
+	return breakdown
+}
+
+// tax returns the tax on an amount at a rate in basis points, rounded half up. If the amount includes the tax,
+// the tax is the part of it that the rate adds to the net amount.
+func tax(amount money.Money, rate int, inclusive bool) money.Money {
+	numerator, denominator := amount.Amount*int64(rate), int64(10000)
+	if inclusive {
+		denominator += int64(rate)
+	}
+	return money.Money{Amount: (2*numerator + denominator) / (2 * denominator), Currency: amount.Currency}
+}
+
+// FormatRate returns a rate in basis points as a percentage without trailing zeros, e.g. "8.25" for 825.
+func FormatRate(rate int) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%d.%02d", rate/100, rate%100), "0"), ".")
+}
+
+// ParseRate parses a percentage with up to two decimals, e.g. "8.25", as basis points.
+func ParseRate(s string) (int, error) {
+	whole, fraction, _ := strings.Cut(strings.TrimSuffix(strings.TrimSpace(s), "%"), ".")
+	if len(fraction) > 2 {
+		return 0, fmt.Errorf("%w: %q has more than two decimals", InvalidRateError, s)
+	}
+	rate, err := strconv.Atoi(whole + (fraction + "00")[:2])
+	if err != nil || whole == "" || strings.HasPrefix(whole, "-") || strings.HasPrefix(whole, "+") {
+		return 0, fmt.Errorf("%w: %q is not a percentage", InvalidRateError, s)
+	}
+	return rate, nil
+}
@@ -0,0 +1,126 @@
+package tax
+
+import (
+	"interview/pkg/entity"
+	"interview/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func usd(cents int64) money.Money {
+	return money.New(cents, "USD")
+}
+
+func TestCalculate(t *testing.T) {
+	rates := []entity.TaxRate{
+		{Region: "ny", Category: "standard", Rate: 825, Name: "Sales tax"},
+		{Region: "ny", Category: "food", Rate: 0, Name: "Sales tax"},
+		{Region: "ny", Category: "reduced", Rate: 500, Name: "Reduced tax"},
+	}
+	lines := []Line{
+		{Category: "", Amount: usd(999)},
+		{Category: "standard", Amount: usd(1999)},
+		{Category: "reduced", Amount: usd(1001)},
+		{Category: "food", Amount: usd(500)},
+		{Category: "books", Amount: usd(300)},
+	}
+	tests := []struct {
+		name     string
+		settings Settings
+		net      money.Money
+		tax      money.Money
+		gross    money.Money
+		lines    []TaxLine
+	}{
+		{
+			name:     "exclusive prices rounded per line",
+			settings: Settings{Region: "ny", Rounding: RoundPerLine},
+			// 999 * 8.25% = 82.42, 1999 * 8.25% = 164.92, 1001 * 5% = 50.05
+			net:   usd(4799),
+			tax:   usd(82 + 165 + 50),
+			gross: usd(4799 + 297),
+			lines: []TaxLine{
+				{Name: "Reduced tax", Category: "reduced", Rate: 500, Taxable: usd(1001), Tax: usd(50)},
+				{Name: "Sales tax", Category: "standard", Rate: 825, Taxable: usd(2998), Tax: usd(247)},
+			},
+		},
+		{
+			name:     "exclusive prices rounded per total",
+			settings: Settings{Region: "ny", Rounding: RoundPerTotal},
+			// 2998 * 8.25% = 247.335
+			net:   usd(4799),
+			tax:   usd(247 + 50),
+			gross: usd(4799 + 297),
+			lines: []TaxLine{
+				{Name: "Reduced tax", Category: "reduced", Rate: 500, Taxable: usd(1001), Tax: usd(50)},
+				{Name: "Sales tax", Category: "standard", Rate: 825, Taxable: usd(2998), Tax: usd(247)},
+			},
+		},
+		{
+			name:     "inclusive prices rounded per line",
+			settings: Settings{Region: "ny", Inclusive: true, Rounding: RoundPerLine},
+			// 999 / 1.0825 * 0.0825 = 76.14, 1999 / 1.0825 * 0.0825 = 152.35, 1001 / 1.05 * 0.05 = 47.67
+			net:   usd(4799 - 76 - 152 - 48),
+			tax:   usd(76 + 152 + 48),
+			gross: usd(4799),
+			lines: []TaxLine{
+				{Name: "Reduced tax", Category: "reduced", Rate: 500, Taxable: usd(1001 - 48), Tax: usd(48)},
+				{Name: "Sales tax", Category: "standard", Rate: 825, Taxable: usd(2998 - 228), Tax: usd(228)},
+			},
+		},
+		{
+			name:     "inclusive prices rounded per total",
+			settings: Settings{Region: "ny", Inclusive: true, Rounding: RoundPerTotal},
+			// 2998 / 1.0825 * 0.0825 = 228.48
+			net:   usd(4799 - 228 - 48),
+			tax:   usd(228 + 48),
+			gross: usd(4799),
+			lines: []TaxLine{
+				{Name: "Reduced tax", Category: "reduced", Rate: 500, Taxable: usd(1001 - 48), Tax: usd(48)},
+				{Name: "Sales tax", Category: "standard", Rate: 825, Taxable: usd(2998 - 228), Tax: usd(228)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := Calculate(lines, rates, tt.settings)
+			assert.Equal(t, tt.net, breakdown.Net)
+			assert.Equal(t, tt.tax, breakdown.Tax)
+			assert.Equal(t, tt.gross, breakdown.Gross)
+			assert.Equal(t, tt.lines, breakdown.Lines)
+			assert.Equal(t, breakdown.Gross, breakdown.Net.Add(breakdown.Tax))
+		})
+	}
+}
+
+func TestCalculate_Rounding(t *testing.T) {
+	rates := []entity.TaxRate{{Category: "standard", Rate: 1000}}
+	lines := []Line{{Amount: usd(5)}, {Amount: usd(5)}, {Amount: usd(5)}}
+	// Every line has a tax of 0.5 cents, which rounds up.
+	assert.Equal(t, usd(3), Calculate(lines, rates, Settings{Rounding: RoundPerLine}).Tax)
+	// The total of 15 cents has a tax of 1.5 cents.
+	assert.Equal(t, usd(2), Calculate(lines, rates, Settings{Rounding: RoundPerTotal}).Tax)
+}
+
+func TestCalculate_NoRates(t *testing.T) {
+	breakdown := Calculate([]Line{{Amount: usd(1000)}}, nil, Settings{Rounding: RoundPerLine})
+	assert.Equal(t, Breakdown{Net: usd(1000), Tax: usd(0), Gross: usd(1000)}, breakdown)
+}
+
+func TestParseRate(t *testing.T) {
+	for s, rate := range map[string]int{"8.25": 825, "19": 1900, "7.5%": 750, "0": 0, "100": 10000, " 0.05 ": 5} {
+		got, err := ParseRate(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, rate, got, s)
+		if s == "8.25" || s == "19" {
+			assert.Equal(t, s, FormatRate(rate))
+		}
+	}
+	assert.Equal(t, "7.5", FormatRate(750))
+	assert.Equal(t, "0.05", FormatRate(5))
+	for _, s := range []string{"", "abc", "8.255", "-1", ".5", "1.x"} {
+		_, err := ParseRate(s)
+		assert.ErrorIs(t, err, InvalidRateError, s)
+	}
+}
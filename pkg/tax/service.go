@@ -0,0 +1,129 @@
+// Package tax keeps the tax rates of the regions the shop sells to and calculates the tax on carts and orders.
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"interview/pkg/entity"
+	"interview/pkg/log"
+)
+
+// Service manages tax rates and calculates taxes.
+type Service interface {
+	// Calculate returns the tax on the lines in a region. An empty region is the default region of the settings.
+	Calculate(ctx context.Context, region string, lines []Line) (Breakdown, error)
+	// Regions returns the regions that have tax rates, in order.
+	Regions(ctx context.Context) ([]string, error)
+	// GetRates returns all tax rates ordered by region and category.
+	GetRates(ctx context.Context) ([]entity.TaxRate, error)
+	// SetRate stores the rate of a category in a region, replacing the rate stored before.
+	SetRate(ctx context.Context, rate *entity.TaxRate) error
+}
+
+type service struct {
+	repo     Repository
+	settings Settings
+	logger   log.Logger
+}
+
+var UnknownRegionError = errors.New("unknown tax region")
+var InvalidRateError = errors.New("invalid tax rate")
+var InternalError = errors.New("internal error")
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+func NewService(repo Repository, settings Settings, logger log.Logger) Service {
+	settings.Region = NormalizeName(settings.Region)
+	return service{repo, settings, logger}
+}
+
+// NormalizeName returns the form in which regions and categories are stored: trimmed and lower case.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func (s service) Calculate(ctx context.Context, region string, lines []Line) (Breakdown, error) {
+	settings := s.settings
+	if region = NormalizeName(region); region != "" {
+		settings.Region = region
+	}
+	rates, err := s.repo.QueryTaxRate(ctx, map[string]interface{}{"region": settings.Region}, "id asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying tax rates: %v", err)
+		return Breakdown{}, InternalError
+	}
+	// The default region needs no rates: a shop that charges no tax has none.
+	if len(rates) == 0 && settings.Region != s.settings.Region {
+		return Breakdown{}, UnknownRegionError
+	}
+	return Calculate(lines, rates, settings), nil
+}
+
+func (s service) Regions(ctx context.Context) ([]string, error) {
+	rates, err := s.GetRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var regions []string
+	seen := map[string]bool{}
+	for _, region := range append([]string{s.settings.Region}, regionsOf(rates)...) {
+		if region != "" && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+func regionsOf(rates []entity.TaxRate) []string {
+	regions := make([]string, len(rates))
+	for i, rate := range rates {
+		regions[i] = rate.Region
+	}
+	return regions
+}
+
+func (s service) GetRates(ctx context.Context) ([]entity.TaxRate, error) {
+	rates, err := s.repo.QueryTaxRate(ctx, map[string]interface{}{}, "region asc, category asc", -1, -1)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying tax rates: %v", err)
+		return nil, InternalError
+	}
+	return rates, nil
+}
+
+func (s service) SetRate(ctx context.Context, rate *entity.TaxRate) error {
+	rate.Region = NormalizeName(rate.Region)
+	rate.Category = NormalizeName(rate.Category)
+	if rate.Category == "" {
+		rate.Category = entity.DefaultTaxCategory
+	}
+	if !nameRegex.MatchString(rate.Region) || !nameRegex.MatchString(rate.Category) {
+		return fmt.Errorf("%w: the region and category must be 1 to 32 letters, digits, dashes or underscores", InvalidRateError)
+	}
+	if rate.Rate < 0 || rate.Rate > 10000 {
+		return fmt.Errorf("%w: the rate must be between 0 and 100 percent", InvalidRateError)
+	}
+	rates, err := s.repo.QueryTaxRate(ctx, map[string]interface{}{"region": rate.Region, "category": rate.Category}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying tax rate: %v", err)
+		return InternalError
+	}
+	if len(rates) > 0 {
+		rate.Model = rates[0].Model
+		err = s.repo.UpdateTaxRate(ctx, rate)
+	} else {
+		err = s.repo.CreateTaxRate(ctx, rate)
+	}
+	if err != nil {
+		s.logger.With(ctx).Errorf("error storing tax rate: %v", err)
+		return InternalError
+	}
+	return nil
+}
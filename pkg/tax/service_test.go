@@ -0,0 +1,69 @@
+package tax
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, settings Settings) Service {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "tax_rates")
+	return NewService(NewRepository(dbc, logger), settings, logger)
+}
+
+func TestService_SetRate(t *testing.T) {
+	s := newTestService(t, Settings{Region: "US-NY", Rounding: RoundPerLine})
+	ctx := context.Background()
+
+	require.Nil(t, s.SetRate(ctx, &entity.TaxRate{Region: " US-NY ", Rate: 800, Name: "Sales tax"}))
+	require.Nil(t, s.SetRate(ctx, &entity.TaxRate{Region: "us-ny", Category: "standard", Rate: 825, Name: "Sales tax"}))
+	require.Nil(t, s.SetRate(ctx, &entity.TaxRate{Region: "de", Category: "Reduced", Rate: 700, Name: "VAT"}))
+	assert.ErrorIs(t, s.SetRate(ctx, &entity.TaxRate{Region: "", Rate: 800}), InvalidRateError)
+	assert.ErrorIs(t, s.SetRate(ctx, &entity.TaxRate{Region: "two words", Rate: 800}), InvalidRateError)
+	assert.ErrorIs(t, s.SetRate(ctx, &entity.TaxRate{Region: "de", Rate: 10001}), InvalidRateError)
+
+	rates, err := s.GetRates(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(rates))
+	assert.Equal(t, "de", rates[0].Region)
+	assert.Equal(t, "reduced", rates[0].Category)
+	assert.Equal(t, "us-ny", rates[1].Region)
+	assert.Equal(t, "standard", rates[1].Category)
+	assert.Equal(t, 825, rates[1].Rate)
+
+	regions, err := s.Regions(ctx)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"de", "us-ny"}, regions)
+}
+
+func TestService_Calculate(t *testing.T) {
+	s := newTestService(t, Settings{Region: "us-ny", Rounding: RoundPerLine})
+	ctx := context.Background()
+	lines := []Line{{Category: "standard", Amount: usd(10000)}}
+
+	// The default region charges no tax until it has rates.
+	breakdown, err := s.Calculate(ctx, "", lines)
+	require.Nil(t, err)
+	assert.Equal(t, usd(0), breakdown.Tax)
+
+	require.Nil(t, s.SetRate(ctx, &entity.TaxRate{Region: "us-ny", Rate: 825, Name: "Sales tax"}))
+	require.Nil(t, s.SetRate(ctx, &entity.TaxRate{Region: "de", Rate: 1900, Name: "VAT"}))
+	breakdown, err = s.Calculate(ctx, "", lines)
+	require.Nil(t, err)
+	assert.Equal(t, "us-ny", breakdown.Region)
+	assert.Equal(t, usd(825), breakdown.Tax)
+	breakdown, err = s.Calculate(ctx, "DE", lines)
+	require.Nil(t, err)
+	assert.Equal(t, "de", breakdown.Region)
+	assert.Equal(t, usd(1900), breakdown.Tax)
+
+	_, err = s.Calculate(ctx, "fr", lines)
+	assert.Equal(t, UnknownRegionError, err)
+}
@@ -0,0 +1,114 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	apierrors "interview/internal/errors"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+
+	"github.com/gin-gonic/gin"
+)
+
+const APIPath = "/api/v1/account"
+
+func RegisterAPIHandlers(r *gin.RouterGroup, service Service, logger log.Logger) {
+	res := apiResource{service, logger}
+
+	r.GET("", res.getUser())
+	r.POST("/signup", res.signup())
+	r.POST("/login", res.login())
+	r.POST("/logout", res.logout())
+}
+
+type apiResource struct {
+	service Service
+	logger  log.Logger
+}
+
+type credentialsRequest struct {
+	Email    string `json:"email"    binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type userResponse struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
+func newUserResponse(userEntity entity.User) userResponse {
+	return userResponse{ID: userEntity.ID, Email: userEntity.Email}
+}
+
+func (r *apiResource) getUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEntity, err := r.service.CurrentUser(c.Request.Context())
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newUserResponse(userEntity))
+	}
+}
+
+func (r *apiResource) signup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req credentialsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with an email and a password"))
+			return
+		}
+		userEntity, err := r.service.Signup(c.Request.Context(), req.Email, req.Password)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, newUserResponse(userEntity))
+	}
+}
+
+func (r *apiResource) login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req credentialsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			r.respondError(c, apierrors.BadRequest("request body must be a JSON object with an email and a password"))
+			return
+		}
+		userEntity, err := r.service.Login(c.Request.Context(), req.Email, req.Password)
+		if err != nil {
+			r.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newUserResponse(userEntity))
+	}
+}
+
+func (r *apiResource) logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r.service.Logout(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// respondError translates a service error into an API error response.
+// The error is also recorded on the gin context so that the request transaction is rolled back.
+func (r *apiResource) respondError(c *gin.Context, err error) {
+	var res apierrors.ErrorResponse
+	switch {
+	case errors.As(err, &res):
+	case errors.Is(err, InvalidEmailError), errors.Is(err, PasswordLengthError):
+		res = apierrors.BadRequest(err.Error())
+	case errors.Is(err, InvalidCredentialsError), errors.Is(err, NotLoggedInError):
+		res = apierrors.Unauthorized(err.Error())
+	case errors.Is(err, EmailTakenError):
+		res = apierrors.Conflict(err.Error())
+	case errors.Is(err, InternalError):
+		res = apierrors.InternalServerError("")
+	default:
+		r.logger.With(c.Request.Context()).Errorf("unexpected error in account API: %v", err)
+		res = apierrors.InternalServerError("")
+	}
+	_ = c.Error(err)
+	c.AbortWithStatusJSON(res.StatusCode(), res.Envelope())
+}
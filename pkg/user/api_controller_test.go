@@ -0,0 +1,67 @@
+package user
+
+import (
+	"encoding/json"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPITestEngine(s Service, sess *session.Session) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger, _ := log.NewForTest()
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		ctx := session.WithSession(c.Request.Context(), sess)
+		c.Request = c.Request.WithContext(ctx)
+	})
+	RegisterAPIHandlers(engine.Group(APIPath), s, logger)
+	return engine
+}
+
+func serveAPI(engine *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPI_Account(t *testing.T) {
+	s := newTestService(&mockUserRepo{}, &mockCartMerger{})
+	sess := &session.Session{ID: "anonymous"}
+	engine := newAPITestEngine(s, sess)
+
+	w := serveAPI(engine, "GET", APIPath, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serveAPI(engine, "POST", APIPath+"/signup", `{"email":"jane@example.com","password":"short"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAPI(engine, "POST", APIPath+"/signup", `{"email":"jane@example.com","password":"correct horse"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var res userResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, userResponse{ID: 1, Email: "jane@example.com"}, res)
+	w = serveAPI(engine, "POST", APIPath+"/signup", `{"email":"jane@example.com","password":"correct horse"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = serveAPI(engine, "GET", APIPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serveAPI(engine, "POST", APIPath+"/logout", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, uint(0), sess.UserID)
+
+	w = serveAPI(engine, "POST", APIPath+"/login", `{"email":"jane@example.com","password":"wrong horse"}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":{"status":401,"code":"unauthorized","message":"email address or password is wrong"}}`, w.Body.String())
+	w = serveAPI(engine, "POST", APIPath+"/login", `{"email":"jane@example.com","password":"correct horse"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), sess.UserID)
+}
@@ -0,0 +1,94 @@
+package user
+
+import (
+	"interview/internal/utils"
+	"interview/pkg/log"
+	"interview/pkg/session"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// cartPath is where users are sent after logging in or out.
+const cartPath = "/cart"
+
+func RegisterHandlers(r *gin.RouterGroup, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.GET("/signup", res.showForm("Sign up", "signup"))
+	r.POST("/signup", res.signup())
+	r.GET("/login", res.showForm("Log in", "login"))
+	r.POST("/login", res.login())
+	r.POST("/logout", res.logout())
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+type credentialsForm struct {
+	Email    string `form:"email"    binding:"required"`
+	Password string `form:"password" binding:"required"`
+}
+
+func (r *resource) showForm(title string, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data := map[string]interface{}{
+			"Errors": session.FromContext(c.Request.Context()).PopFlashes(),
+			"Title":  title,
+			"Action": action,
+		}
+		html, err := utils.RenderTemplate(c.Request.Context(), data, "account_form.html")
+		if err != nil {
+			r.logger.With(c.Request.Context()).Errorf("Failed to render account template: %s", err)
+			c.AbortWithStatus(500)
+			return
+		}
+		c.Header("Content-Type", "text/html")
+		c.String(200, html)
+	}
+}
+
+func (r *resource) signup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form := &credentialsForm{}
+		if err := binding.FormPost.Bind(c.Request, form); err != nil {
+			r.redirectWithError(c, "signup", "email address and password are required")
+			return
+		}
+		if _, err := r.service.Signup(c.Request.Context(), form.Email, form.Password); err != nil {
+			r.redirectWithError(c, "signup", err.Error())
+			return
+		}
+		c.Redirect(302, cartPath)
+	}
+}
+
+func (r *resource) login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form := &credentialsForm{}
+		if err := binding.FormPost.Bind(c.Request, form); err != nil {
+			r.redirectWithError(c, "login", "email address and password are required")
+			return
+		}
+		if _, err := r.service.Login(c.Request.Context(), form.Email, form.Password); err != nil {
+			r.redirectWithError(c, "login", err.Error())
+			return
+		}
+		c.Redirect(302, cartPath)
+	}
+}
+
+func (r *resource) logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r.service.Logout(c.Request.Context())
+		c.Redirect(302, cartPath)
+	}
+}
+
+// redirectWithError sends the user back to the form, which shows the error as a flash message.
+func (r *resource) redirectWithError(c *gin.Context, action string, msg string) {
+	session.FromContext(c.Request.Context()).AddFlash(msg)
+	c.Redirect(302, AccountPath+"/"+action)
+}
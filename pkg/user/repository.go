@@ -0,0 +1,60 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"interview/pkg/db"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	QueryUser(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.User, error)
+	// CreateUser creates the user, or returns EmailTakenError if a user with the same email address exists.
+	CreateUser(ctx context.Context, user *entity.User) error
+	Transactional(ctx context.Context, f func(ctx context.Context) error) error
+}
+
+type repository struct {
+	db     *db.DB
+	logger log.Logger
+}
+
+func NewRepository(db *db.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) QueryUser(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.User, error) {
+	var users []entity.User
+	db := r.db.With(ctx)
+	result := db.Where(conditions).
+		Order(order).
+		Limit(limit).
+		Offset(offset).
+		Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return users, nil
+}
+
+func (r repository) CreateUser(ctx context.Context, user *entity.User) error {
+	db := r.db.With(ctx)
+	result := db.Create(user)
+	if result.Error != nil {
+		// the unique index on the email address tells a taken address apart from other errors, in the terms of
+		// the database driver
+		if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok &&
+			errors.Is(translator.Translate(result.Error), gorm.ErrDuplicatedKey) {
+			return EmailTakenError
+		}
+		return result.Error
+	}
+	return nil
+}
+
+func (r repository) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	return r.db.Transactional(ctx, f)
+}
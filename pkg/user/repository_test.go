@@ -0,0 +1,35 @@
+package user
+
+import (
+	"context"
+	"interview/pkg/db"
+	"interview/pkg/db/migrations"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) Repository {
+	logger, _ := log.NewForTest()
+	dbc := db.MigratedForTest(t, migrations.All(), "users")
+	return NewRepository(dbc, logger)
+}
+
+func TestRepository_CreateUser(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	first := entity.User{Email: "jane@example.com", PasswordHash: "hash"}
+	require.Nil(t, repo.CreateUser(ctx, &first))
+	users, err := repo.QueryUser(ctx, map[string]interface{}{"email": "jane@example.com"}, "id asc", 1, 0)
+	require.Nil(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, first.ID, users[0].ID)
+
+	second := entity.User{Email: "jane@example.com", PasswordHash: "other"}
+	err = repo.CreateUser(ctx, &second)
+	assert.Equal(t, EmailTakenError, err)
+}
@@ -0,0 +1,168 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Service interface {
+	Signup(ctx context.Context, email string, password string) (entity.User, error)
+	Login(ctx context.Context, email string, password string) (entity.User, error)
+	Logout(ctx context.Context)
+	CurrentUser(ctx context.Context) (entity.User, error)
+}
+
+// CartMerger takes over the cart of an anonymous session when a user logs in. It is implemented by cart.Service.
+type CartMerger interface {
+	MergeCart(ctx context.Context, userID uint) error
+}
+
+type service struct {
+	repo   Repository
+	carts  CartMerger
+	logger log.Logger
+	cost   int
+}
+
+var InternalError = errors.New("internal error")
+var InvalidEmailError = errors.New("email address is not valid")
+var PasswordLengthError = errors.New("password must be between 8 and 72 characters")
+var EmailTakenError = errors.New("an account with this email address already exists")
+var InvalidCredentialsError = errors.New("email address or password is wrong")
+var NotLoggedInError = errors.New("not logged in")
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after the 72nd byte of a password
+	maxPasswordLength = 72
+)
+
+// dummyHash is compared with the password of an unknown user, so that a failed login takes as long for an unknown
+// email address as for a wrong password and does not reveal which addresses have accounts.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+func NewService(repo Repository, carts CartMerger, logger log.Logger) Service {
+	return service{repo, carts, logger, bcrypt.DefaultCost}
+}
+
+const AccountPath = "/account"
+
+// Signup creates a user account and logs the session in to it.
+func (s service) Signup(ctx context.Context, email string, password string) (entity.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return entity.User{}, err
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return entity.User{}, PasswordLengthError
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error hashing password: %v", err)
+		return entity.User{}, InternalError
+	}
+	userEntity := entity.User{Email: email, PasswordHash: string(hash)}
+	err = s.repo.Transactional(ctx, func(ctx context.Context) error {
+		users, err := s.repo.QueryUser(ctx, map[string]interface{}{"email": email}, "id asc", 1, 0)
+		if err != nil {
+			s.logger.With(ctx).Errorf("error querying user: %v", err)
+			return InternalError
+		}
+		if len(users) > 0 {
+			return EmailTakenError
+		}
+		if err := s.repo.CreateUser(ctx, &userEntity); err != nil {
+			// a concurrent signup with the same email address may have created its account since the query
+			if errors.Is(err, EmailTakenError) {
+				return EmailTakenError
+			}
+			s.logger.With(ctx).Errorf("error creating user: %v", err)
+			return InternalError
+		}
+		return s.logIn(ctx, userEntity)
+	})
+	if err != nil {
+		return entity.User{}, err
+	}
+	return userEntity, nil
+}
+
+// Login checks the credentials of a user and logs the session in to the user's account.
+// The cart of the anonymous session is merged into the user's cart.
+func (s service) Login(ctx context.Context, email string, password string) (entity.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return entity.User{}, InvalidCredentialsError
+	}
+	users, err := s.repo.QueryUser(ctx, map[string]interface{}{"email": email}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying user: %v", err)
+		return entity.User{}, InternalError
+	}
+	if len(users) == 0 {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return entity.User{}, InvalidCredentialsError
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(users[0].PasswordHash), []byte(password)); err != nil {
+		return entity.User{}, InvalidCredentialsError
+	}
+	if err := s.logIn(ctx, users[0]); err != nil {
+		return entity.User{}, err
+	}
+	return users[0], nil
+}
+
+// Logout ends the login of the session. The session gets a new ID and starts without a cart.
+func (s service) Logout(ctx context.Context) {
+	sess := session.FromContext(ctx)
+	sess.UserID = 0
+	sess.CartID = 0
+	sess.Renew()
+}
+
+// CurrentUser returns the user logged in to the session.
+func (s service) CurrentUser(ctx context.Context) (entity.User, error) {
+	userID := session.FromContext(ctx).UserID
+	if userID == 0 {
+		return entity.User{}, NotLoggedInError
+	}
+	users, err := s.repo.QueryUser(ctx, map[string]interface{}{"id": userID}, "id asc", 1, 0)
+	if err != nil {
+		s.logger.With(ctx).Errorf("error querying user: %v", err)
+		return entity.User{}, InternalError
+	}
+	if len(users) == 0 {
+		return entity.User{}, NotLoggedInError
+	}
+	return users[0], nil
+}
+
+// logIn binds the session to the user after merging the cart of the session into the user's cart.
+// The session is renewed so that its ID from before the login cannot be used to act as the user.
+func (s service) logIn(ctx context.Context, userEntity entity.User) error {
+	if err := s.carts.MergeCart(ctx, userEntity.ID); err != nil {
+		s.logger.With(ctx).Errorf("error merging cart of user %d: %v", userEntity.ID, err)
+		return InternalError
+	}
+	sess := session.FromContext(ctx)
+	sess.UserID = userEntity.ID
+	sess.Renew()
+	return nil
+}
+
+// normalizeEmail returns the email address in lower case, or InvalidEmailError if it is not a plain address.
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return "", InvalidEmailError
+	}
+	return email, nil
+}
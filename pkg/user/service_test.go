@@ -0,0 +1,147 @@
+package user
+
+import (
+	"context"
+	"interview/pkg/entity"
+	"interview/pkg/log"
+	"interview/pkg/session"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockUserRepo struct {
+	users []entity.User
+}
+
+type mockCartMerger struct {
+	merged []uint
+}
+
+func newTestService(repo *mockUserRepo, carts *mockCartMerger) service {
+	logger, _ := log.NewForTest()
+	return service{repo, carts, logger, bcrypt.MinCost}
+}
+
+func Test_service_Signup(t *testing.T) {
+	repo := &mockUserRepo{}
+	carts := &mockCartMerger{}
+	s := newTestService(repo, carts)
+	sess := &session.Session{ID: "anonymous"}
+	ctx := session.WithSession(context.Background(), sess)
+
+	userEntity, err := s.Signup(ctx, " Jane@Example.com ", "correct horse")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), userEntity.ID)
+	assert.Equal(t, "jane@example.com", userEntity.Email)
+	assert.NotEqual(t, "correct horse", repo.users[0].PasswordHash)
+	assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(repo.users[0].PasswordHash), []byte("correct horse")))
+	assert.Equal(t, uint(1), sess.UserID)
+	assert.NotEqual(t, "anonymous", sess.ID)
+	assert.Equal(t, []uint{1}, carts.merged)
+
+	ctx = session.WithSession(context.Background(), &session.Session{ID: "other"})
+	_, err = s.Signup(ctx, "jane@example.com", "another password")
+	assert.Equal(t, EmailTakenError, err)
+	_, err = s.Signup(ctx, "not an address", "correct horse")
+	assert.Equal(t, InvalidEmailError, err)
+	_, err = s.Signup(ctx, "joe@example.com", "short")
+	assert.Equal(t, PasswordLengthError, err)
+	assert.Equal(t, 1, len(repo.users))
+}
+
+// racingUserRepo misses the accounts created by concurrent signups when it is queried.
+type racingUserRepo struct {
+	Repository
+}
+
+func (racingUserRepo) QueryUser(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.User, error) {
+	return nil, nil
+}
+
+func Test_service_Signup_Concurrent(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := newTestRepository(t)
+	s := service{racingUserRepo{repo}, &mockCartMerger{}, logger, bcrypt.MinCost}
+	ctx := session.WithSession(context.Background(), &session.Session{ID: "first"})
+	_, err := s.Signup(ctx, "jane@example.com", "correct horse")
+	assert.Nil(t, err)
+
+	ctx = session.WithSession(context.Background(), &session.Session{ID: "second"})
+	_, err = s.Signup(ctx, "jane@example.com", "another password")
+	assert.Equal(t, EmailTakenError, err)
+}
+
+func Test_service_Login(t *testing.T) {
+	repo := &mockUserRepo{}
+	carts := &mockCartMerger{}
+	s := newTestService(repo, carts)
+	ctx := session.WithSession(context.Background(), &session.Session{ID: "signup"})
+	_, err := s.Signup(ctx, "jane@example.com", "correct horse")
+	assert.Nil(t, err)
+
+	sess := &session.Session{ID: "anonymous"}
+	ctx = session.WithSession(context.Background(), sess)
+	_, err = s.Login(ctx, "jane@example.com", "wrong horse")
+	assert.Equal(t, InvalidCredentialsError, err)
+	_, err = s.Login(ctx, "joe@example.com", "correct horse")
+	assert.Equal(t, InvalidCredentialsError, err)
+	assert.Equal(t, uint(0), sess.UserID)
+	assert.Equal(t, "anonymous", sess.ID)
+
+	userEntity, err := s.Login(ctx, "JANE@example.com", "correct horse")
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), userEntity.ID)
+	assert.Equal(t, uint(1), sess.UserID)
+	assert.NotEqual(t, "anonymous", sess.ID)
+	assert.Equal(t, []uint{1, 1}, carts.merged)
+
+	current, err := s.CurrentUser(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "jane@example.com", current.Email)
+
+	loggedInID := sess.ID
+	sess.CartID = 3
+	s.Logout(ctx)
+	assert.Equal(t, uint(0), sess.UserID)
+	assert.Equal(t, uint(0), sess.CartID)
+	assert.NotEqual(t, loggedInID, sess.ID)
+	_, err = s.CurrentUser(ctx)
+	assert.Equal(t, NotLoggedInError, err)
+}
+
+func (m *mockUserRepo) QueryUser(ctx context.Context, conditions map[string]interface{}, order string, limit int, offset int) ([]entity.User, error) {
+	var users []entity.User
+	for _, u := range m.users {
+		matched := true
+		for k, v := range conditions {
+			if k == "id" && u.ID == v.(uint) {
+				continue
+			}
+			if k == "email" && u.Email == v.(string) {
+				continue
+			}
+			matched = false
+		}
+		if matched {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (m *mockUserRepo) CreateUser(ctx context.Context, user *entity.User) error {
+	user.ID = uint(len(m.users) + 1)
+	m.users = append(m.users, *user)
+	return nil
+}
+
+func (m *mockUserRepo) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	return f(ctx)
+}
+
+func (m *mockCartMerger) MergeCart(ctx context.Context, userID uint) error {
+	m.merged = append(m.merged, userID)
+	return nil
+}